@@ -27,22 +27,28 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/cellery-io/cellery-hub/components/docker-auth/pkg/auth"
+	"github.com/cellery-io/cellery-hub/components/docker-auth/pkg/db"
 	"github.com/cellery-io/cellery-hub/components/docker-auth/pkg/extension"
 )
 
 var logger *zap.SugaredLogger
 
 type PluginAuthn struct {
+	dbConnectionPool db.SharedConnectionPool
 }
 
-func (*PluginAuthn) Authenticate(user string, password api.PasswordString) (bool, api.Labels, error) {
+func (c *PluginAuthn) Authenticate(user string, password api.PasswordString) (bool, api.Labels, error) {
 	if logger == nil {
 		logger = extension.NewLogger()
 	}
-	return doAuthentication(user, string(password), logger)
+	return doAuthentication(&c.dbConnectionPool, user, string(password), logger)
 }
 
-func (*PluginAuthn) Stop() {
+func (c *PluginAuthn) Stop() {
+	if logger == nil {
+		logger = extension.NewLogger()
+	}
+	c.dbConnectionPool.Close(logger)
 }
 
 func (*PluginAuthn) Name() string {
@@ -51,7 +57,8 @@ func (*PluginAuthn) Name() string {
 
 var Authn PluginAuthn
 
-func doAuthentication(user, incomingToken string, logger *zap.SugaredLogger) (bool, api.Labels, error) {
+func doAuthentication(dbConnectionPool *db.SharedConnectionPool, user, incomingToken string,
+	logger *zap.SugaredLogger) (bool, api.Labels, error) {
 	execId, err := extension.GetExecID(logger)
 	if err != nil {
 		return false, nil, fmt.Errorf("error in generating the execId : %s", err)
@@ -71,8 +78,17 @@ func doAuthentication(user, incomingToken string, logger *zap.SugaredLogger) (bo
 	// present, IDP is called. If credentials are not present, through authorization logic image visibility
 	// will be evaluated.
 	var isAuthenticated bool
+	var robotAccount *auth.RobotAccount
+	var tokenInfo *auth.TokenInfo
 	if user != "" && token != "" {
-		isAuthenticated, err = auth.Authenticate(user, token, logger, execId)
+		if auth.IsRobotUsername(user) {
+			// Robot accounts are validated against the database without calling the IDP
+			robotAccount, err = authenticateRobot(dbConnectionPool, user, token, logger, execId)
+			isAuthenticated = robotAccount != nil
+		} else {
+			tokenInfo, err = auth.Authenticate(dbConnectionPool, user, token, logger, execId)
+			isAuthenticated = tokenInfo != nil
+		}
 		if err != nil {
 			return false, nil, fmt.Errorf("error while authenticating %v", err)
 		}
@@ -88,13 +104,42 @@ func doAuthentication(user, incomingToken string, logger *zap.SugaredLogger) (bo
 		}
 	} else {
 		logger.Debugf("[%s] User successfully authenticated by validating token", execId)
-		return true, makeAuthenticationLabel(true), nil
+		authLabels := makeAuthenticationLabel(true)
+		if robotAccount != nil {
+			authLabels[extension.RobotOrganizationLabel] = []string{robotAccount.Organization}
+			authLabels[extension.RobotActionsLabel] = robotAccount.Actions
+		}
+		if tokenInfo != nil && tokenInfo.Scopes != nil {
+			// Scoped tokens such as personal access tokens only allow a subset of the actions of the user
+			authLabels[extension.TokenScopesLabel] = tokenInfo.Scopes
+			if len(tokenInfo.Organization) > 0 {
+				authLabels[extension.TokenOrganizationLabel] = []string{tokenInfo.Organization}
+			}
+		}
+		return true, authLabels, nil
+	}
+}
+
+func authenticateRobot(dbConnectionPool *db.SharedConnectionPool, user, secret string, logger *zap.SugaredLogger,
+	execId string) (*auth.RobotAccount, error) {
+	dbConnection, err := dbConnectionPool.Get(logger)
+	if err != nil {
+		return nil, fmt.Errorf("error while establishing database connection pool: %v", err)
+	}
+	robotAccount, err := auth.AuthenticateRobot(dbConnection, user, secret, logger, execId)
+	if err != nil {
+		// Invalid hashes do not indicate an unavailable endpoint
+		if extension.IsStoreError(err) {
+			dbConnectionPool.ResetIfUnhealthy(logger)
+		}
+		return nil, err
 	}
+	return robotAccount, nil
 }
 
 func makeAuthenticationLabel(isAuthenticated bool) api.Labels {
 	authResultString := strconv.FormatBool(isAuthenticated)
 	authLabels := api.Labels{}
-	authLabels["isAuthSuccess"] = []string{authResultString}
+	authLabels[extension.AuthSuccessLabel] = []string{authResultString}
 	return authLabels
 }
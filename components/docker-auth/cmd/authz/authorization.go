@@ -19,7 +19,10 @@
 package main
 
 import (
+	"database/sql"
 	"fmt"
+	"os"
+	"sync"
 
 	"github.com/cesanta/docker_auth/auth_server/api"
 	"go.uber.org/zap"
@@ -27,14 +30,42 @@ import (
 	"github.com/cellery-io/cellery-hub/components/docker-auth/pkg/auth"
 	"github.com/cellery-io/cellery-hub/components/docker-auth/pkg/db"
 	"github.com/cellery-io/cellery-hub/components/docker-auth/pkg/extension"
+	"github.com/cellery-io/cellery-hub/components/docker-auth/pkg/migrations"
 )
 
 var logger *zap.SugaredLogger
 
+// closableAclStore is an ACL store which holds its own database
+type closableAclStore interface {
+	extension.ACLStore
+	Close() error
+}
+
+// openSqliteStore opens the embedded SQLite ACL store. It is only set when the plugin is built with the sqlite build
+// tag, hence the SQLite driver is not linked into the plugin by default.
+var openSqliteStore func(file string, logger *zap.SugaredLogger) (closableAclStore, error)
+
 type PluginAuthz struct {
+	dbConnectionPool db.SharedConnectionPool
+	sqliteStoreMutex sync.Mutex
+	sqliteStore      closableAclStore
+	schemaMutex      sync.Mutex
+	isSchemaVerified bool
 }
 
-func (*PluginAuthz) Stop() {
+func (c *PluginAuthz) Stop() {
+	if logger == nil {
+		logger = extension.NewLogger()
+	}
+	c.dbConnectionPool.Close(logger)
+	c.sqliteStoreMutex.Lock()
+	defer c.sqliteStoreMutex.Unlock()
+	if c.sqliteStore != nil {
+		if err := c.sqliteStore.Close(); err != nil {
+			logger.Errorf("Error while closing the SQLite ACL store : %v", err)
+		}
+		c.sqliteStore = nil
+	}
 }
 
 func (*PluginAuthz) Name() string {
@@ -45,30 +76,114 @@ func (c *PluginAuthz) Authorize(ai *api.AuthRequestInfo) ([]string, error) {
 	if logger == nil {
 		logger = extension.NewLogger()
 	}
-	return doAuthorize(ai, logger)
+	return doAuthorize(c, ai, logger)
 }
 
 var Authz PluginAuthz
 
-func doAuthorize(ai *api.AuthRequestInfo, logger *zap.SugaredLogger) ([]string, error) {
+// getAclStore returns the ACL store selected through the environment along with the endpoint serving it. The Cellery
+// Hub database is used unless the embedded SQLite database is selected.
+func (c *PluginAuthz) getAclStore(logger *zap.SugaredLogger) (extension.ACLStore, string, error) {
+	aclStore := os.Getenv(extension.AclStoreEnvVar)
+	switch aclStore {
+	case extension.SqliteAclStore:
+		file := os.Getenv(extension.SqliteFileEnvVar)
+		store, err := c.getSqliteStore(file, logger)
+		if err != nil {
+			return nil, "", err
+		}
+		return store, "SQLite ACL store " + file, nil
+	case "", extension.DatabaseAclStore:
+		endpoint, err := c.dbConnectionPool.GetReader(logger)
+		if err != nil {
+			return nil, "", &extension.StoreError{
+				Err: fmt.Errorf("error while establishing database connection pool: %v", err),
+			}
+		}
+		served := endpoint.Role + " db endpoint " + endpoint.Name
+		driver, _ := extension.ResolveDbDriver()
+		err = c.verifySchemaVersion(endpoint.Db, driver, logger)
+		if err != nil {
+			return nil, served, err
+		}
+		if driver == extension.PostgresDriver {
+			return extension.NewPostgresStore(endpoint.Db), served, nil
+		}
+		return extension.NewMySQLStore(endpoint.Db), served, nil
+	default:
+		return nil, "", fmt.Errorf("unsupported ACL store %s", aclStore)
+	}
+}
+
+// getSqliteStore opens the SQLite ACL store once it is first used. Opening the store is retried by the later requests
+// if it fails.
+func (c *PluginAuthz) getSqliteStore(file string, logger *zap.SugaredLogger) (closableAclStore, error) {
+	if openSqliteStore == nil {
+		return nil, fmt.Errorf("the SQLite ACL store is not supported, since the plugin is not built with the " +
+			"sqlite build tag")
+	}
+	c.sqliteStoreMutex.Lock()
+	defer c.sqliteStoreMutex.Unlock()
+	if c.sqliteStore == nil {
+		store, err := openSqliteStore(file, logger)
+		if err != nil {
+			return nil, fmt.Errorf("error while opening the SQLite ACL store: %v", err)
+		}
+		c.sqliteStore = store
+	}
+	return c.sqliteStore, nil
+}
+
+// verifySchemaVersion refuses to authorize against a database which is not at the schema version the plugin is built
+// for. The check is repeated until it succeeds, since the database can be migrated while the plugin is running.
+func (c *PluginAuthz) verifySchemaVersion(dbConnection *sql.DB, driver string, logger *zap.SugaredLogger) error {
+	c.schemaMutex.Lock()
+	defer c.schemaMutex.Unlock()
+	if c.isSchemaVerified {
+		return nil
+	}
+	err := migrations.CheckSchemaVersion(dbConnection, driver, logger)
+	if err != nil {
+		return &extension.StoreError{
+			Err: fmt.Errorf("error while verifying the schema version of the database: %v", err),
+		}
+	}
+	c.isSchemaVerified = true
+	return nil
+}
+
+// resetIfStoreFailed checks the health of the db endpoints only if the database failed, since the invalid requests and
+// configurations do not indicate an unavailable endpoint
+func (c *PluginAuthz) resetIfStoreFailed(err error, logger *zap.SugaredLogger) {
+	if extension.IsStoreError(err) {
+		c.dbConnectionPool.ResetIfUnhealthy(logger)
+	}
+}
+
+func doAuthorize(plugin *PluginAuthz, ai *api.AuthRequestInfo, logger *zap.SugaredLogger) ([]string, error) {
 	execId, err := extension.GetExecID(logger)
 	if err != nil {
 		return nil, fmt.Errorf("error in generating the execId : %s", err)
 	}
 	logger.Debugf("Authorization logic reached. User will be authorized")
-	dbConnectionPool, err := db.GetDbConnectionPool(logger)
+	aclStore, served, err := plugin.getAclStore(logger)
 	if err != nil {
-		return nil, fmt.Errorf("error while establishing database connection pool: %v", err)
+		plugin.resetIfStoreFailed(err, logger)
+		return nil, err
 	}
-	authorized, err := auth.Authorize(dbConnectionPool, ai, logger, execId)
+	decision, err := auth.Authorize(aclStore, ai, logger, execId)
 	if err != nil {
+		logger.Debugf("[%s] Authorization failed on the %s", execId, served)
+		plugin.resetIfStoreFailed(err, logger)
 		return nil, fmt.Errorf("error while executing authorization logic: %v", err)
 	}
-	if !authorized {
+	logger.Infof("[%s] Authorization decision served by the %s : %s", execId, served, decision)
+	if len(decision.GrantedActions) == 0 {
 		logger.Debugf("[%s] User : %s is unauthorized for %s actions", execId, ai.Account, ai.Actions)
 		return nil, nil
 	} else {
-		logger.Debugf("[%s] User : %s is authorized for %s actions", execId, ai.Account, ai.Actions)
-		return ai.Actions, nil
+		logger.Debugf("[%s] User : %s is authorized for %s actions out of the requested %s actions", execId,
+			ai.Account, decision.GrantedActions, ai.Actions)
+		return decision.GrantedActions, nil
 	}
 }
@@ -0,0 +1,38 @@
+//go:build sqlite
+// +build sqlite
+
+/*
+ * Copyright (c) 2019 WSO2 Inc. (http:www.wso2.org) All Rights Reserved.
+ *
+ * WSO2 Inc. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http:www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package main
+
+import (
+	"go.uber.org/zap"
+
+	"github.com/cellery-io/cellery-hub/components/docker-auth/pkg/sqlite"
+)
+
+func init() {
+	openSqliteStore = func(file string, logger *zap.SugaredLogger) (closableAclStore, error) {
+		store, err := sqlite.NewStore(file, logger)
+		if err != nil {
+			return nil, err
+		}
+		return store, nil
+	}
+}
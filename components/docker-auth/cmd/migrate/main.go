@@ -0,0 +1,125 @@
+/*
+ * Copyright (c) 2019 WSO2 Inc. (http:www.wso2.org) All Rights Reserved.
+ *
+ * WSO2 Inc. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http:www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"go.uber.org/zap"
+
+	"github.com/cellery-io/cellery-hub/components/docker-auth/pkg/db"
+	"github.com/cellery-io/cellery-hub/components/docker-auth/pkg/extension"
+	"github.com/cellery-io/cellery-hub/components/docker-auth/pkg/migrations"
+)
+
+const usage = `Usage: migrate <command>
+
+Applies the schema migrations of the Cellery Hub database selected through the environment.
+
+Commands:
+  up        Apply the pending migrations
+  status    Show the state of the migrations
+  baseline  Mark a database created before the migrations were introduced as the first schema version. The
+            pending migrations should be applied afterwards with the up command.
+`
+
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	logger := extension.NewLogger()
+	var err error
+	switch os.Args[1] {
+	case "up":
+		err = migrateUp(logger)
+	case "status":
+		err = printStatus(logger)
+	case "baseline":
+		err = baselineSchema(logger)
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+func migrateUp(logger *zap.SugaredLogger) error {
+	dbConnection, dbDriver, err := openDatabase(logger)
+	if err != nil {
+		return err
+	}
+	defer dbConnection.Close()
+	count, err := migrations.Up(dbConnection, dbDriver, logger)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Applied %d migrations, the schema is at version %d\n", count, migrations.LatestVersion())
+	return nil
+}
+
+func baselineSchema(logger *zap.SugaredLogger) error {
+	dbConnection, dbDriver, err := openDatabase(logger)
+	if err != nil {
+		return err
+	}
+	defer dbConnection.Close()
+	err = migrations.Baseline(dbConnection, dbDriver, logger)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Baselined the database at schema version 1, the pending migrations can be applied with the up command")
+	return nil
+}
+
+func printStatus(logger *zap.SugaredLogger) error {
+	dbConnection, dbDriver, err := openDatabase(logger)
+	if err != nil {
+		return err
+	}
+	defer dbConnection.Close()
+	statuses, err := migrations.Status(dbConnection, dbDriver, logger)
+	if err != nil {
+		return err
+	}
+	writer := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(writer, "VERSION\tSTATE\tDESCRIPTION")
+	for _, status := range statuses {
+		fmt.Fprintf(writer, "%d\t%s\t%s\n", status.Version, status.State, status.Description)
+	}
+	return writer.Flush()
+}
+
+func openDatabase(logger *zap.SugaredLogger) (*sql.DB, string, error) {
+	dbDriver, err := extension.ResolveDbDriver()
+	if err != nil {
+		return nil, "", err
+	}
+	dbConnection, err := db.GetDbConnection(logger)
+	if err != nil {
+		return nil, "", err
+	}
+	return dbConnection, dbDriver, nil
+}
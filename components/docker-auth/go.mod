@@ -14,6 +14,8 @@ require (
 	github.com/facebookgo/httpdown v0.0.0-20180706035922-5979d39b15c2
 	github.com/go-ldap/ldap v3.0.3+incompatible
 	github.com/go-sql-driver/mysql v1.4.1
+	github.com/lib/pq v1.2.0
+	github.com/mattn/go-sqlite3 v1.11.0
 	github.com/schwarmco/go-cartesian-product v0.0.0-20180515110546-d5ee747a6dc9
 	github.com/syndtr/goleveldb v1.0.0
 	go.uber.org/atomic v1.4.0 // indirect
@@ -19,124 +19,87 @@
 package auth
 
 import (
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
-	"github.com/cellery-io/cellery-hub/components/docker-auth/pkg/extension"
-
 	"go.uber.org/zap"
+
+	"github.com/cellery-io/cellery-hub/components/docker-auth/pkg/db"
 )
 
-func Authenticate(uName string, token string, logger *zap.SugaredLogger, execId string) (bool, error) {
+// Authenticate validates the access token of the user and returns the token information if the user is
+// successfully authenticated. Nil is returned if the user failed to authenticate.
+func Authenticate(dbConnectionPool *db.SharedConnectionPool, uName string, token string, logger *zap.SugaredLogger,
+	execId string) (*TokenInfo, error) {
 	logger.Debugf("[%s] Authentication logic handler reached and token will be validated. "+
 		"Performing authentication by using access token", execId)
-	accessTokenValidity, err := validateAccessToken(token, uName, logger, execId)
+	tokenValidators, err := getTokenValidators(dbConnectionPool, logger)
 	if err != nil {
-		return false, fmt.Errorf("error occured while validating access token : %s", err)
-	}
-	if accessTokenValidity {
-		logger.Debugf("[%s] User successfully authenticated", execId)
-		return true, nil
-	} else {
-		logger.Debugf("[%s] User failed to authenticate", execId)
-		return false, nil
+		return nil, fmt.Errorf("error occured while resolving the token validators : %s", err)
 	}
-}
-
-// validateAccessToken is used to introspect the access token
-func validateAccessToken(token string, providedUsername string, logger *zap.SugaredLogger,
-	execId string) (bool, error) {
-	introspectionUrl, err := resolveIntrospectionUrl(logger, execId)
+	tokenInfo, err := validateAccessToken(tokenValidators, token, uName, logger, execId)
 	if err != nil {
-		return false, fmt.Errorf("error occured while resolving introspection url: %v", err)
+		return nil, fmt.Errorf("error occured while validating access token : %s", err)
 	}
-	payload := strings.NewReader("token=" + token)
-	req, err := http.NewRequest("POST", introspectionUrl, payload)
-	if err != nil {
-		return false, fmt.Errorf("error creating new request to the introspection endpoint : %s", err)
-	}
-	username, password, err := resolveCredentials(logger, execId)
-	if err != nil {
-		return false, err
-	}
-	req.SetBasicAuth(username, password)
-	client := &http.Client{}
-	res, err := client.Do(req)
-	if err != nil {
-		return false, fmt.Errorf("error sending the request to the introspection endpoint : %s", err)
-	}
-
-	if res.StatusCode == http.StatusBadRequest {
-		return false, fmt.Errorf("[%s] %d status code returned from IDP probably due to empty token", execId,
-			res.StatusCode)
-	} else if res.StatusCode != http.StatusOK {
-		return false, fmt.Errorf("[%s] Error while calling IDP, status code :%d. Exiting without "+
-			"authorization\n", execId, res.StatusCode)
-	}
-
-	defer res.Body.Close()
-	body, err := ioutil.ReadAll(res.Body)
-	if err != nil {
-		return false, fmt.Errorf("error reading the response from introspection endpoint. Returing without "+
-			"authorization : %s", err)
+	if tokenInfo != nil {
+		logger.Debugf("[%s] User successfully authenticated", execId)
+		return tokenInfo, nil
 	} else {
-		logger.Debugf("[%s] Response received from introspection endpoint : %s", execId, body)
-	}
-
-	type Response struct {
-		Active   bool   `json:"active"`
-		Username string `json:"username"`
-		Exp      int64  `json:"exp"`
-	}
-	var response Response
-	err = json.Unmarshal(body, &response)
-	if err != nil {
-		return false, fmt.Errorf("error unmarshalling the json. This may be due to a invalid token : %s", err)
-	}
-	logger.Debugf("[%s] Resolved access token validity", execId)
-	isExpired, err := isExpired(response.Exp, logger, execId)
-	if err != nil {
-		return false, err
-	}
-	isValidUser, err := isValidUser(response.Username, providedUsername, logger, execId)
-	if err != nil {
-		return false, err
+		logger.Debugf("[%s] User failed to authenticate", execId)
+		return nil, nil
 	}
-	return isExpired && response.Active && isValidUser, nil
 }
 
-// resolves the IS host and port from the environment variables.
-// If the environment is not set the port and host will be resolved through the config file.
-func resolveIntrospectionUrl(logger *zap.SugaredLogger, execId string) (string, error) {
-	idpEndPoint := os.Getenv("IDP_END_POINT")
-	introspectionEP := os.Getenv("INTROSPECTION_END_POINT")
-	if len(introspectionEP) == 0 {
-		return "", fmt.Errorf("'INTROSPECTION_END_POINT' environment variable is empty")
-	}
-	if len(idpEndPoint) == 0 {
-		return "", fmt.Errorf("'IDP_END_POINT' environment variable is empty")
+// validateAccessToken passes the access token through the chain of validators until one of them is able to
+// validate it, and returns the token information if the token is valid and belongs to the provided user
+func validateAccessToken(tokenValidators []TokenValidator, token string, providedUsername string,
+	logger *zap.SugaredLogger, execId string) (*TokenInfo, error) {
+	for _, tokenValidator := range tokenValidators {
+		tokenInfo, err := tokenValidator.Validate(token, logger, execId)
+		if err == ErrTokenNotSupported {
+			logger.Debugf("[%s] Token is not supported by the %s validator", execId, tokenValidator.Name())
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error occured in the %s validator : %v", tokenValidator.Name(), err)
+		}
+		logger.Debugf("[%s] Resolved access token validity using the %s validator", execId, tokenValidator.Name())
+		if !tokenInfo.Active {
+			logger.Debugf("[%s] Token received is not active", execId)
+			return nil, nil
+		}
+		isExpired, err := isExpired(tokenInfo.Exp, logger, execId)
+		if err != nil {
+			return nil, err
+		}
+		isValidUser, err := isValidUser(tokenInfo.Username, providedUsername, logger, execId)
+		if err != nil {
+			return nil, err
+		}
+		if isExpired && isValidUser {
+			return tokenInfo, nil
+		}
+		return nil, nil
 	}
-	logger.Debugf("[%s] Successfully resolved introspection url", execId)
-	return idpEndPoint + introspectionEP, nil
+	logger.Debugf("[%s] None of the token validators were able to validate the token", execId)
+	return nil, nil
 }
 
-// resolveCredentials resolves the user credentials of the user that is used to communicate to introspection endpoint
-func resolveCredentials(logger *zap.SugaredLogger, execId string) (string, string, error) {
-	username := os.Getenv(extension.IdpUsernameEnvVar)
-	if len(username) == 0 {
-		return "", "", fmt.Errorf("'USERNAME' environment variable is empty")
+// resolveIntEnvVar reads an integer from the environment and falls back to the default value if it is not set
+func resolveIntEnvVar(envVar string, defaultValue int) (int, error) {
+	value := os.Getenv(envVar)
+	if len(value) == 0 {
+		return defaultValue, nil
 	}
-	password := os.Getenv(extension.IdppasswordEnvVar)
-	if len(password) == 0 {
-		return "", "", fmt.Errorf("'PASSWORD' environment variable is empty")
+	intValue, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("error occurred while converting '%s' environment variable into integer : %v",
+			envVar, err)
 	}
-	logger.Debugf("[%s] Successfully resolved credentials", execId)
-	return username, password, nil
+	return intValue, nil
 }
 
 // isValidUser checks whether the provided username matches with the username in the token
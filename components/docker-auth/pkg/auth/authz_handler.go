@@ -20,26 +20,110 @@ package auth
 
 import (
 	"fmt"
-
-	"database/sql"
+	"os"
+	"sync"
 
 	"github.com/cesanta/docker_auth/auth_server/api"
 	"go.uber.org/zap"
 
 	"github.com/cellery-io/cellery-hub/components/docker-auth/pkg/extension"
+	"github.com/cellery-io/cellery-hub/components/docker-auth/pkg/policy"
 )
 
-func Authorize(dbConn *sql.DB, ai *api.AuthRequestInfo, logger *zap.SugaredLogger, execId string) (bool, error) {
+var accessPolicy *policy.Policy
+var accessPolicyMutex sync.Mutex
+
+// getAccessPolicy returns the policy loaded from the policy file configured through the environment. Nil is
+// returned if a policy file is not configured. The policy is loaded on the first successful call, hence a policy
+// file which cannot be read is retried by the next call.
+func getAccessPolicy(logger *zap.SugaredLogger) (*policy.Policy, error) {
+	accessPolicyMutex.Lock()
+	defer accessPolicyMutex.Unlock()
+	file := os.Getenv(extension.PolicyFileEnvVar)
+	if accessPolicy == nil && len(file) > 0 {
+		loadedPolicy, err := policy.NewPolicy(file, logger)
+		if err != nil {
+			return nil, err
+		}
+		accessPolicy = loadedPolicy
+	}
+	return accessPolicy, nil
+}
+
+// Authorize returns the decision which holds the subset of the requested actions the user is allowed to perform
+// along with the reasons for the denied actions
+func Authorize(store extension.ACLStore, ai *api.AuthRequestInfo, logger *zap.SugaredLogger, execId string) (
+	*extension.Decision, error) {
 	logger.Debugf("[%s] Authorization logic handler reached and access will be validated", execId)
-	isValid, err := extension.IsUserAuthorized(dbConn, ai.Actions, ai.Account, ai.Name, ai.Labels, logger, execId)
+	accessPolicy, err := getAccessPolicy(logger)
 	if err != nil {
-		return false, fmt.Errorf("[%s] Error occurred while validating the user :%s", execId, err)
+		return nil, fmt.Errorf("[%s] Error occurred while loading the policy file :%s", execId, err)
 	}
-	if isValid {
-		logger.Debugf("[%s] Authorized user. Access granted by authz handler", execId)
-		return true, nil
+	var decision *extension.Decision
+	if accessPolicy == nil {
+		decision, err = extension.IsUserAuthorized(store, ai.Actions, ai.Account, ai.Name, ai.Labels, logger,
+			execId)
 	} else {
-		logger.Debugf("[%s] User access denied by authz handler", execId)
-		return false, nil
+		decision, err = authorizeWithPolicy(store, accessPolicy, ai, logger, execId)
+	}
+	if err != nil {
+		validationErr := fmt.Errorf("[%s] Error occurred while validating the user :%s", execId, err)
+		if extension.IsStoreError(err) {
+			return nil, &extension.StoreError{Err: validationErr}
+		}
+		return nil, validationErr
+	}
+	return decision, nil
+}
+
+// authorizeWithPolicy evaluates the rules of the before phase prior to the database ACL and only the actions which
+// are not decided by these rules are validated against the ACL store. The rules of the after phase then override
+// the resulting decisions.
+func authorizeWithPolicy(store extension.ACLStore, accessPolicy *policy.Policy, ai *api.AuthRequestInfo,
+	logger *zap.SugaredLogger, execId string) (*extension.Decision, error) {
+	organization, image, err := extension.GetOrganizationAndImage(ai.Name, logger, execId)
+	if err != nil {
+		return nil, err
+	}
+	authSuccessLabel := ai.Labels[extension.AuthSuccessLabel]
+	request := &policy.Request{
+		Account:         ai.Account,
+		Organization:    organization,
+		Image:           image,
+		IP:              ai.IP,
+		IsAuthenticated: len(authSuccessLabel) > 0 && authSuccessLabel[0] == "true",
+	}
+	requiredActions, _ := extension.ResolveRequiredActions(ai.Actions)
+	outcomes := make(map[string]extension.ActionOutcome)
+	var undecidedActions []string
+	for _, action := range requiredActions {
+		if rule := accessPolicy.Evaluate(policy.BeforePhase, request, action, logger, execId); rule != nil {
+			outcomes[action] = newPolicyOutcome(rule)
+		} else {
+			undecidedActions = append(undecidedActions, action)
+		}
+	}
+	if len(undecidedActions) > 0 {
+		aclDecision, err := extension.IsUserAuthorized(store, undecidedActions, ai.Account, ai.Name, ai.Labels,
+			logger, execId)
+		if err != nil {
+			return nil, err
+		}
+		for action, outcome := range aclDecision.Outcomes() {
+			outcomes[action] = outcome
+		}
+	}
+	for _, action := range requiredActions {
+		if rule := accessPolicy.Evaluate(policy.AfterPhase, request, action, logger, execId); rule != nil {
+			outcomes[action] = newPolicyOutcome(rule)
+		}
+	}
+	return extension.NewDecision(ai.Account, ai.Name, ai.Actions, outcomes), nil
+}
+
+func newPolicyOutcome(rule *policy.Rule) extension.ActionOutcome {
+	if rule.Effect == policy.AllowEffect {
+		return extension.ActionOutcome{IsGranted: true, Rule: rule.Name}
 	}
+	return extension.ActionOutcome{Reason: extension.ReasonPolicyDenied, Rule: rule.Name}
 }
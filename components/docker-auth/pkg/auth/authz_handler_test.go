@@ -0,0 +1,254 @@
+/*
+ * Copyright (c) 2019 WSO2 Inc. (http:www.wso2.org) All Rights Reserved.
+ *
+ * WSO2 Inc. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http:www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package auth
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cesanta/docker_auth/auth_server/api"
+	"go.uber.org/zap"
+
+	"github.com/cellery-io/cellery-hub/components/docker-auth/pkg/extension"
+	"github.com/cellery-io/cellery-hub/components/docker-auth/pkg/policy"
+)
+
+const testAccessPolicy = `
+rules:
+  - name: office-pull
+    effect: allow
+    match:
+      organizations: ["cellery"]
+      actions: ["pull"]
+      ips: ["10.0.0.0/8"]
+  - name: restrict-push
+    effect: deny
+    match:
+      organizations: ["cellery"]
+      actions: ["push", "delete"]
+  - name: anonymous-pull
+    effect: deny
+    phase: after
+    match:
+      actions: ["pull"]
+      authenticated: false
+`
+
+// The requests are fully decided by the policy, hence the database ACL is not consulted
+func TestAuthorizeWithPolicy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "policy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "policy.yaml")
+	err = ioutil.WriteFile(file, []byte(testAccessPolicy), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := zap.NewExample().Sugar()
+	accessPolicy, err := policy.NewPolicy(file, logger)
+	if err != nil {
+		t.Fatal("Error while loading the policy :", err)
+	}
+
+	authLabels := api.Labels{"isAuthSuccess": []string{"true"}}
+	values := []struct {
+		actions        []string
+		labels         api.Labels
+		grantedActions []string
+		decision       string
+	}{
+		{[]string{"pull", "push"}, authLabels, []string{"pull"}, `account="alice" repository="cellery/hello" ` +
+			`requested=[pull,push] granted=[pull] denied=[push:POLICY_DENIED:restrict-push] ` +
+			`rules=[office-pull,restrict-push]`},
+		{[]string{"*"}, authLabels, nil, `account="alice" repository="cellery/hello" requested=[*] granted=[] ` +
+			`denied=[push:POLICY_DENIED:restrict-push,delete:POLICY_DENIED:restrict-push] ` +
+			`rules=[office-pull,restrict-push]`},
+		{[]string{"pull", "delete", "tag"}, authLabels, []string{"pull"}, `account="alice" ` +
+			`repository="cellery/hello" requested=[pull,delete,tag] granted=[pull] ` +
+			`denied=[delete:POLICY_DENIED:restrict-push,tag:UNSUPPORTED_ACTION] rules=[office-pull,restrict-push]`},
+		{[]string{"pull"}, api.Labels{"isAuthSuccess": []string{"false"}}, nil, `account="alice" ` +
+			`repository="cellery/hello" requested=[pull] granted=[] denied=[pull:POLICY_DENIED:anonymous-pull] ` +
+			`rules=[anonymous-pull]`},
+	}
+	for _, value := range values {
+		ai := &api.AuthRequestInfo{
+			Account: "alice",
+			Type:    "repository",
+			Name:    "cellery/hello",
+			IP:      net.ParseIP("10.1.2.3"),
+			Actions: value.actions,
+			Labels:  value.labels,
+		}
+		decision, err := authorizeWithPolicy(nil, accessPolicy, ai, logger, testExecId)
+		if err != nil {
+			t.Error("Error while authorizing :", err)
+			continue
+		}
+		if strings.Join(decision.GrantedActions, ",") != strings.Join(value.grantedActions, ",") {
+			t.Error("Expected granted actions", value.grantedActions, "but found", decision.GrantedActions, "for",
+				value.actions)
+		}
+		if decision.String() != value.decision {
+			t.Error("Expected the decision", value.decision, "but found", decision.String())
+		}
+	}
+}
+
+// The account of an unauthenticated request is only the user name claimed by the client, hence the rules matching
+// the account must not grant any actions to it
+func TestAuthorizeWithPolicyUnauthenticatedAccount(t *testing.T) {
+	dir, err := ioutil.TempDir("", "policy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "policy.yaml")
+	err = ioutil.WriteFile(file, []byte(testAccessPolicy+`
+  - name: release-push
+    effect: allow
+    priority: 200
+    match:
+      accounts: ["alice"]
+      organizations: ["cellery"]
+      actions: ["push"]
+`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := zap.NewExample().Sugar()
+	accessPolicy, err := policy.NewPolicy(file, logger)
+	if err != nil {
+		t.Fatal("Error while loading the policy :", err)
+	}
+
+	values := []struct {
+		isAuthSuccess  string
+		grantedActions []string
+	}{
+		{"true", []string{"push"}},
+		{"false", nil},
+	}
+	for _, value := range values {
+		ai := &api.AuthRequestInfo{
+			Account: "alice",
+			Type:    "repository",
+			Name:    "cellery/hello",
+			Actions: []string{"push"},
+			Labels:  api.Labels{"isAuthSuccess": []string{value.isAuthSuccess}},
+		}
+		decision, err := authorizeWithPolicy(nil, accessPolicy, ai, logger, testExecId)
+		if err != nil {
+			t.Error("Error while authorizing :", err)
+			continue
+		}
+		if strings.Join(decision.GrantedActions, ",") != strings.Join(value.grantedActions, ",") {
+			t.Error("Expected granted actions", value.grantedActions, "but found", decision.GrantedActions,
+				"when the authentication success is", value.isAuthSuccess)
+		}
+	}
+}
+
+func TestGetAccessPolicyRetry(t *testing.T) {
+	dir, err := ioutil.TempDir("", "policy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "policy.yaml")
+	err = os.Setenv(extension.PolicyFileEnvVar, file)
+	if err != nil {
+		t.Fatal("Error setting up the environment :", err)
+	}
+	defer os.Unsetenv(extension.PolicyFileEnvVar)
+	defer func() {
+		accessPolicy = nil
+	}()
+	logger := zap.NewExample().Sugar()
+	_, err = getAccessPolicy(logger)
+	if err == nil {
+		t.Fatal("Expected an error since the policy file does not exist")
+	}
+	err = ioutil.WriteFile(file, []byte(testAccessPolicy), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loadedPolicy, err := getAccessPolicy(logger)
+	if err != nil || loadedPolicy == nil {
+		t.Error("Expected the policy to be loaded once the file is available but found error :", err)
+	}
+}
+
+// unavailableStore fails as a database which cannot be reached
+type unavailableStore struct{}
+
+func (unavailableStore) GetACLRecord(user string, organization string, image string, logger *zap.SugaredLogger,
+	execId string) (*extension.ACLRecord, error) {
+	return nil, errors.New("connection refused")
+}
+
+func (unavailableStore) GetRolePermissions(logger *zap.SugaredLogger, execId string) (map[string][]string, error) {
+	return nil, errors.New("connection refused")
+}
+
+func (unavailableStore) GetOrganizationQuota(organization string, logger *zap.SugaredLogger,
+	execId string) (*extension.OrganizationQuota, error) {
+	return nil, errors.New("connection refused")
+}
+
+func (unavailableStore) GetVersion(logger *zap.SugaredLogger, execId string) (int64, error) {
+	return 0, errors.New("connection refused")
+}
+
+// Only the failures of the store are reported as store errors, hence the invalid requests do not cause the health
+// of the db endpoints to be checked
+func TestAuthorizeStoreError(t *testing.T) {
+	values := []struct {
+		repository   string
+		isStoreError bool
+	}{
+		{"cellery/unavailable", true},
+		{"unavailable", false},
+	}
+	logger := zap.NewExample().Sugar()
+	for _, value := range values {
+		ai := &api.AuthRequestInfo{
+			Account: "alice",
+			Type:    "repository",
+			Name:    value.repository,
+			Actions: []string{"pull"},
+			Labels:  api.Labels{"isAuthSuccess": []string{"true"}},
+		}
+		_, err := Authorize(unavailableStore{}, ai, logger, testExecId)
+		if err == nil {
+			t.Error("Expected an error while authorizing for the repository", value.repository)
+			continue
+		}
+		if extension.IsStoreError(err) != value.isStoreError {
+			t.Error("Expected the error to be a store error :", value.isStoreError, "but found", err,
+				"for the repository", value.repository)
+		}
+	}
+}
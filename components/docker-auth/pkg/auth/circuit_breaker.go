@@ -0,0 +1,136 @@
+/*
+ * Copyright (c) 2019 WSO2 Inc. (http:www.wso2.org) All Rights Reserved.
+ *
+ * WSO2 Inc. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http:www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package auth
+
+import (
+	"errors"
+	"expvar"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// errCircuitOpen is returned without calling the IDP while the circuit breaker is open
+var errCircuitOpen = errors.New("circuit breaker is open since the IDP is failing")
+
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+func (s circuitState) String() string {
+	switch s {
+	case circuitOpen:
+		return "open"
+	case circuitHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// circuitBreaker stops calling the IDP after a number of consecutive transient failures. Once the open duration
+// has passed a single probe request is let through, and the circuit is closed again if the probe succeeds.
+type circuitBreaker struct {
+	mutex               sync.Mutex
+	name                string
+	failureThreshold    int
+	openDuration        time.Duration
+	state               circuitState
+	consecutiveFailures int
+	openedAt            time.Time
+	isProbing           bool
+	metrics             *expvar.Map
+	now                 func() time.Time
+}
+
+func newCircuitBreaker(name string, failureThreshold int, openDuration time.Duration,
+	metrics *expvar.Map) *circuitBreaker {
+	breaker := &circuitBreaker{
+		name:             name,
+		failureThreshold: failureThreshold,
+		openDuration:     openDuration,
+		metrics:          metrics,
+		now:              time.Now,
+	}
+	breaker.publishState()
+	return breaker
+}
+
+// allow returns errCircuitOpen if the call should not be made
+func (b *circuitBreaker) allow(logger *zap.SugaredLogger, execId string) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.state == circuitOpen && b.now().Sub(b.openedAt) >= b.openDuration {
+		b.setState(circuitHalfOpen, logger, execId)
+	}
+	if b.state == circuitOpen || (b.state == circuitHalfOpen && b.isProbing) {
+		b.metrics.Add("circuitRejections", 1)
+		return errCircuitOpen
+	}
+	if b.state == circuitHalfOpen {
+		b.isProbing = true
+	}
+	return nil
+}
+
+// recordSuccess closes the circuit. Any response from the IDP which is not a transient failure is a success.
+func (b *circuitBreaker) recordSuccess(logger *zap.SugaredLogger, execId string) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.consecutiveFailures = 0
+	b.isProbing = false
+	if b.state != circuitClosed {
+		b.setState(circuitClosed, logger, execId)
+	}
+}
+
+// recordFailure opens the circuit if the failure threshold is reached or the half open probe failed
+func (b *circuitBreaker) recordFailure(logger *zap.SugaredLogger, execId string) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.metrics.Add("failures", 1)
+	b.consecutiveFailures++
+	b.isProbing = false
+	if b.state == circuitHalfOpen || (b.state == circuitClosed && b.consecutiveFailures >= b.failureThreshold) {
+		b.openedAt = b.now()
+		b.setState(circuitOpen, logger, execId)
+	}
+}
+
+func (b *circuitBreaker) setState(state circuitState, logger *zap.SugaredLogger, execId string) {
+	if state == circuitOpen {
+		logger.Errorf("[%s] Circuit breaker of %s changed from %s to %s after %d consecutive failures", execId,
+			b.name, b.state, state, b.consecutiveFailures)
+	} else {
+		logger.Infof("[%s] Circuit breaker of %s changed from %s to %s", execId, b.name, b.state, state)
+	}
+	b.state = state
+	b.publishState()
+}
+
+func (b *circuitBreaker) publishState() {
+	state := new(expvar.String)
+	state.Set(b.state.String())
+	b.metrics.Set("circuitState", state)
+}
@@ -0,0 +1,120 @@
+/*
+ * Copyright (c) 2019 WSO2 Inc. (http:www.wso2.org) All Rights Reserved.
+ *
+ * WSO2 Inc. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http:www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package auth
+
+import (
+	"expvar"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Now()
+	metrics := new(expvar.Map).Init()
+	breaker := newCircuitBreaker("test", 2, 30*time.Second, metrics)
+	breaker.now = func() time.Time {
+		return now
+	}
+	logger := zap.NewExample().Sugar()
+
+	breaker.recordFailure(logger, testExecId)
+	if err := breaker.allow(logger, testExecId); err != nil {
+		t.Error("Circuit is expected to be closed before reaching the failure threshold")
+	}
+	breaker.recordFailure(logger, testExecId)
+	if err := breaker.allow(logger, testExecId); err != errCircuitOpen {
+		t.Error("Circuit is expected to be open after reaching the failure threshold")
+	}
+	if metrics.Get("circuitState").String() != `"open"` {
+		t.Error("Circuit state metric is expected to be open but found", metrics.Get("circuitState"))
+	}
+
+	now = now.Add(30 * time.Second)
+	if err := breaker.allow(logger, testExecId); err != nil {
+		t.Error("A probe request is expected to be allowed after the open duration")
+	}
+	if err := breaker.allow(logger, testExecId); err != errCircuitOpen {
+		t.Error("Only a single probe request is expected to be allowed while half open")
+	}
+	breaker.recordFailure(logger, testExecId)
+	if err := breaker.allow(logger, testExecId); err != errCircuitOpen {
+		t.Error("Circuit is expected to be opened again when the probe request fails")
+	}
+
+	now = now.Add(30 * time.Second)
+	if err := breaker.allow(logger, testExecId); err != nil {
+		t.Error("A probe request is expected to be allowed after the open duration")
+	}
+	breaker.recordSuccess(logger, testExecId)
+	if err := breaker.allow(logger, testExecId); err != nil {
+		t.Error("Circuit is expected to be closed when the probe request succeeds")
+	}
+	if metrics.Get("circuitState").String() != `"closed"` {
+		t.Error("Circuit state metric is expected to be closed but found", metrics.Get("circuitState"))
+	}
+}
+
+func TestIntrospectionRetries(t *testing.T) {
+	requestCount := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestCount++
+		if requestCount <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = fmt.Fprintf(w, `{"active":true,"username":"admin@cellery.io","exp":%d}`,
+			time.Now().Add(time.Hour).Unix())
+	}))
+	defer server.Close()
+
+	newValidator := func(maxRetries int) *introspectionValidator {
+		return &introspectionValidator{
+			introspectionUrl:    server.URL,
+			cache:               newTokenCache(0, 0, 0),
+			client:              server.Client(),
+			maxRetries:          maxRetries,
+			initialRetryBackoff: time.Millisecond,
+			maxRetryBackoff:     5 * time.Millisecond,
+			circuitBreaker:      newCircuitBreaker("test", 10, time.Minute, new(expvar.Map).Init()),
+		}
+	}
+	logger := zap.NewExample().Sugar()
+
+	_, err := newValidator(1).Validate("token", logger, testExecId)
+	if err == nil {
+		t.Error("Introspection is expected to fail when the retries are exhausted")
+	}
+	if requestCount != 2 {
+		t.Error("Introspection endpoint is expected to be called 2 times but called", requestCount, "times")
+	}
+
+	requestCount = 0
+	tokenInfo, err := newValidator(2).Validate("token", logger, testExecId)
+	if err != nil {
+		t.Fatal("Introspection is expected to succeed after retrying :", err)
+	}
+	if !tokenInfo.Active || tokenInfo.Username != "admin@cellery.io" {
+		t.Error("Unexpected token information", tokenInfo)
+	}
+}
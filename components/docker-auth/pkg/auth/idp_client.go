@@ -0,0 +1,151 @@
+/*
+ * Copyright (c) 2019 WSO2 Inc. (http:www.wso2.org) All Rights Reserved.
+ *
+ * WSO2 Inc. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http:www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package auth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/cellery-io/cellery-hub/components/docker-auth/pkg/extension"
+)
+
+var idpHttpClient *http.Client
+var idpHttpClientMutex sync.Mutex
+
+// getIdpHttpClient returns the HTTP client used for all the calls to the IDP. The client is created on the first
+// successful call and reused so that the connections to the IDP are kept alive across requests. A client which
+// cannot be created, such as when the CA certificate file is not mounted yet, is retried by the next call.
+func getIdpHttpClient(logger *zap.SugaredLogger) (*http.Client, error) {
+	idpHttpClientMutex.Lock()
+	defer idpHttpClientMutex.Unlock()
+	if idpHttpClient == nil {
+		client, err := newIdpHttpClient(logger)
+		if err != nil {
+			return nil, err
+		}
+		idpHttpClient = client
+	}
+	return idpHttpClient, nil
+}
+
+// newIdpHttpClient creates an HTTP client with the timeouts, trusted CAs, client certificate and proxy configured
+// through the environment
+func newIdpHttpClient(logger *zap.SugaredLogger) (*http.Client, error) {
+	connectTimeout, err := resolveIntEnvVar(extension.IdpConnectTimeoutEnvVar, extension.DefaultIdpConnectTimeout)
+	if err != nil {
+		return nil, err
+	}
+	readTimeout, err := resolveIntEnvVar(extension.IdpReadTimeoutEnvVar, extension.DefaultIdpReadTimeout)
+	if err != nil {
+		return nil, err
+	}
+	maxIdleConnections, err := resolveIntEnvVar(extension.IdpMaxIdleConnectionsEnvVar,
+		extension.DefaultIdpMaxIdleConnections)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig, err := resolveIdpTlsConfig(logger)
+	if err != nil {
+		return nil, err
+	}
+	proxy, err := resolveIdpProxy(logger)
+	if err != nil {
+		return nil, err
+	}
+	dialer := &net.Dialer{
+		Timeout:   time.Duration(connectTimeout) * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	transport := &http.Transport{
+		Proxy:                 proxy,
+		DialContext:           dialer.DialContext,
+		TLSClientConfig:       tlsConfig,
+		TLSHandshakeTimeout:   time.Duration(connectTimeout) * time.Second,
+		ResponseHeaderTimeout: time.Duration(readTimeout) * time.Second,
+		MaxIdleConns:          maxIdleConnections,
+		MaxIdleConnsPerHost:   maxIdleConnections,
+		IdleConnTimeout:       90 * time.Second,
+	}
+	logger.Debugf("IDP HTTP client configurations. ConnectTimeout = %d, ReadTimeout = %d, MaxIdleConns = %d",
+		connectTimeout, readTimeout, maxIdleConnections)
+	return &http.Client{
+		Transport: transport,
+		Timeout:   time.Duration(connectTimeout+readTimeout) * time.Second,
+	}, nil
+}
+
+// resolveIdpTlsConfig adds the custom CA bundle to the trusted certificates and loads the client certificate
+// used for mutual TLS with the IDP
+func resolveIdpTlsConfig(logger *zap.SugaredLogger) (*tls.Config, error) {
+	tlsConfig := &tls.Config{}
+	caCertFile := os.Getenv(extension.IdpCaCertFileEnvVar)
+	if len(caCertFile) > 0 {
+		caCerts, err := ioutil.ReadFile(caCertFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading the IDP CA certificate file : %v", err)
+		}
+		rootCAs, err := x509.SystemCertPool()
+		if err != nil {
+			logger.Debugf("System certificate pool is not available, hence only trusting the IDP CA "+
+				"certificates : %v", err)
+			rootCAs = x509.NewCertPool()
+		}
+		if !rootCAs.AppendCertsFromPEM(caCerts) {
+			return nil, fmt.Errorf("no certificates found in the IDP CA certificate file %s", caCertFile)
+		}
+		tlsConfig.RootCAs = rootCAs
+		logger.Debugf("Trusting the IDP CA certificates in %s", caCertFile)
+	}
+	clientCertFile := os.Getenv(extension.IdpClientCertFileEnvVar)
+	clientKeyFile := os.Getenv(extension.IdpClientKeyFileEnvVar)
+	if len(clientCertFile) > 0 || len(clientKeyFile) > 0 {
+		clientCert, err := tls.LoadX509KeyPair(clientCertFile, clientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("error loading the client certificate for the IDP : %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{clientCert}
+		logger.Debugf("Using the client certificate %s for mutual TLS with the IDP", clientCertFile)
+	}
+	return tlsConfig, nil
+}
+
+// resolveIdpProxy uses the proxy configured for the IDP if available and falls back to the standard
+// HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables
+func resolveIdpProxy(logger *zap.SugaredLogger) (func(*http.Request) (*url.URL, error), error) {
+	proxyUrl := os.Getenv(extension.IdpProxyUrlEnvVar)
+	if len(proxyUrl) == 0 {
+		return http.ProxyFromEnvironment, nil
+	}
+	parsedProxyUrl, err := url.Parse(proxyUrl)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing the IDP proxy url : %v", err)
+	}
+	logger.Debugf("Using the proxy %s for the calls to the IDP", parsedProxyUrl.Host)
+	return http.ProxyURL(parsedProxyUrl), nil
+}
@@ -0,0 +1,124 @@
+/*
+ * Copyright (c) 2019 WSO2 Inc. (http:www.wso2.org) All Rights Reserved.
+ *
+ * WSO2 Inc. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http:www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package auth
+
+import (
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/cellery-io/cellery-hub/components/docker-auth/pkg/extension"
+)
+
+func TestIdpHttpClient(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(2 * time.Second)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	caCertFile, err := ioutil.TempFile("", "idp-ca")
+	if err != nil {
+		t.Fatal("Error while creating the CA certificate file :", err)
+	}
+	defer os.Remove(caCertFile.Name())
+	err = pem.Encode(caCertFile, &pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err != nil {
+		t.Fatal("Error while writing the CA certificate file :", err)
+	}
+	caCertFile.Close()
+
+	logger := zap.NewExample().Sugar()
+	client, err := newIdpHttpClient(logger)
+	if err != nil {
+		t.Fatal("Error while creating the IDP http client :", err)
+	}
+	_, err = client.Get(server.URL)
+	if err == nil {
+		t.Error("Request to the IDP is expected to fail since the CA certificate is not trusted")
+	}
+
+	envVars := map[string]string{
+		extension.IdpCaCertFileEnvVar:  caCertFile.Name(),
+		extension.IdpReadTimeoutEnvVar: "1",
+	}
+	for key, value := range envVars {
+		err := os.Setenv(key, value)
+		if err != nil {
+			t.Fatal("Error setting up the environment", key, ":", err)
+		}
+		defer os.Unsetenv(key)
+	}
+	client, err = newIdpHttpClient(logger)
+	if err != nil {
+		t.Fatal("Error while creating the IDP http client :", err)
+	}
+	res, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal("Error while calling the IDP with the trusted CA certificate :", err)
+	}
+	res.Body.Close()
+	_, err = client.Get(server.URL + "/slow")
+	if err == nil {
+		t.Error("Request to the IDP is expected to time out")
+	}
+}
+
+func TestGetIdpHttpClientRetry(t *testing.T) {
+	dir, err := ioutil.TempDir("", "idp-ca")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	caCertFile := filepath.Join(dir, "ca.crt")
+	err = os.Setenv(extension.IdpCaCertFileEnvVar, caCertFile)
+	if err != nil {
+		t.Fatal("Error setting up the environment :", err)
+	}
+	defer os.Unsetenv(extension.IdpCaCertFileEnvVar)
+	defer func() {
+		idpHttpClient = nil
+	}()
+	logger := zap.NewExample().Sugar()
+	_, err = getIdpHttpClient(logger)
+	if err == nil {
+		t.Fatal("Expected an error since the CA certificate file does not exist")
+	}
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	err = ioutil.WriteFile(caCertFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE",
+		Bytes: server.Certificate().Raw}), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := getIdpHttpClient(logger)
+	if err != nil || client == nil {
+		t.Error("Expected the client to be created once the CA certificate file is available but found error :",
+			err)
+	}
+}
@@ -0,0 +1,263 @@
+/*
+ * Copyright (c) 2019 WSO2 Inc. (http:www.wso2.org) All Rights Reserved.
+ *
+ * WSO2 Inc. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http:www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package auth
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/cellery-io/cellery-hub/components/docker-auth/pkg/extension"
+)
+
+// introspectionValidator validates opaque tokens by calling an RFC 7662 token introspection endpoint using basic
+// authentication. The introspection results are cached until the tokens expire.
+type introspectionValidator struct {
+	introspectionUrl string
+	username         string
+	password         string
+	cache            *tokenCache
+	client           *http.Client
+
+	maxRetries          int
+	initialRetryBackoff time.Duration
+	maxRetryBackoff     time.Duration
+	circuitBreaker      *circuitBreaker
+}
+
+// introspectionMetrics exposes the request, retry and failure counts and the circuit breaker state through expvar
+var introspectionMetrics = expvar.NewMap("idpIntrospection")
+
+func newIntrospectionValidator(logger *zap.SugaredLogger) (*introspectionValidator, error) {
+	introspectionUrl, err := resolveIntrospectionUrl(logger)
+	if err != nil {
+		return nil, fmt.Errorf("error occured while resolving introspection url: %v", err)
+	}
+	username, password, err := resolveCredentials(logger)
+	if err != nil {
+		return nil, err
+	}
+	cache, err := newTokenCacheFromEnv(logger)
+	if err != nil {
+		return nil, err
+	}
+	client, err := getIdpHttpClient(logger)
+	if err != nil {
+		return nil, fmt.Errorf("error occured while creating the IDP http client: %v", err)
+	}
+	validator := &introspectionValidator{
+		introspectionUrl: introspectionUrl,
+		username:         username,
+		password:         password,
+		cache:            cache,
+		client:           client,
+	}
+	err = validator.resolveResilienceConfigurations(logger)
+	if err != nil {
+		return nil, err
+	}
+	return validator, nil
+}
+
+func (v *introspectionValidator) Name() string {
+	return extension.IntrospectionTokenValidator
+}
+
+// Validate is used to introspect the access token. Transient failures of the IDP are retried with a jittered
+// exponential backoff and the IDP is not called while the circuit breaker is open.
+func (v *introspectionValidator) Validate(token string, logger *zap.SugaredLogger, execId string) (*TokenInfo,
+	error) {
+	if response, isCached := v.cache.get(token); isCached {
+		logger.Debugf("[%s] Resolved access token validity from the cache", execId)
+		return &response, nil
+	}
+	introspectionMetrics.Add("requests", 1)
+	for attempt := 0; ; attempt++ {
+		err := v.circuitBreaker.allow(logger, execId)
+		if err != nil {
+			return nil, err
+		}
+		response, isTransient, err := v.introspect(token, logger, execId)
+		if err == nil || !isTransient {
+			v.circuitBreaker.recordSuccess(logger, execId)
+		} else {
+			v.circuitBreaker.recordFailure(logger, execId)
+		}
+		if err == nil {
+			v.cache.put(token, *response)
+			return response, nil
+		}
+		if !isTransient || attempt >= v.maxRetries {
+			return nil, err
+		}
+		backoff := v.retryBackoff(attempt)
+		logger.Debugf("[%s] Retrying the introspection request in %s after a transient failure : %v", execId,
+			backoff, err)
+		introspectionMetrics.Add("retries", 1)
+		time.Sleep(backoff)
+	}
+}
+
+// retryBackoff returns a random duration up to the exponentially increasing backoff of the attempt
+func (v *introspectionValidator) retryBackoff(attempt int) time.Duration {
+	backoff := v.maxRetryBackoff
+	if attempt < 30 && v.initialRetryBackoff<<uint(attempt) < v.maxRetryBackoff {
+		backoff = v.initialRetryBackoff << uint(attempt)
+	}
+	if backoff <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(backoff)) + 1)
+}
+
+// introspect calls the introspection endpoint once. Network failures and the 429 and 5xx status codes are
+// reported as transient failures which can be retried.
+func (v *introspectionValidator) introspect(token string, logger *zap.SugaredLogger, execId string) (*TokenInfo,
+	bool, error) {
+	payload := strings.NewReader("token=" + token)
+	req, err := http.NewRequest("POST", v.introspectionUrl, payload)
+	if err != nil {
+		return nil, false, fmt.Errorf("error creating new request to the introspection endpoint : %s", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(v.username, v.password)
+	res, err := v.client.Do(req)
+	if err != nil {
+		return nil, true, fmt.Errorf("error sending the request to the introspection endpoint : %s", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusBadRequest {
+		return nil, false, fmt.Errorf("[%s] %d status code returned from IDP probably due to empty token",
+			execId, res.StatusCode)
+	} else if res.StatusCode != http.StatusOK {
+		isTransient := res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= http.StatusInternalServerError
+		return nil, isTransient, fmt.Errorf("[%s] Error while calling IDP, status code :%d. Exiting without "+
+			"authorization\n", execId, res.StatusCode)
+	}
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, true, fmt.Errorf("error reading the response from introspection endpoint. Returing "+
+			"without authorization : %s", err)
+	} else {
+		logger.Debugf("[%s] Response received from introspection endpoint : %s", execId, body)
+	}
+
+	var response TokenInfo
+	err = json.Unmarshal(body, &response)
+	if err != nil {
+		return nil, false, fmt.Errorf("error unmarshalling the json. This may be due to a invalid token : %s",
+			err)
+	}
+	return &response, false, nil
+}
+
+// resolveResilienceConfigurations resolves the retry and circuit breaker configurations from the environment
+func (v *introspectionValidator) resolveResilienceConfigurations(logger *zap.SugaredLogger) error {
+	maxRetries, err := resolveIntEnvVar(extension.IdpMaxRetriesEnvVar, extension.DefaultIdpMaxRetries)
+	if err != nil {
+		return err
+	}
+	initialRetryBackoff, err := resolveIntEnvVar(extension.IdpRetryBackoffEnvVar, extension.DefaultIdpRetryBackoff)
+	if err != nil {
+		return err
+	}
+	maxRetryBackoff, err := resolveIntEnvVar(extension.IdpMaxRetryBackoffEnvVar,
+		extension.DefaultIdpMaxRetryBackoff)
+	if err != nil {
+		return err
+	}
+	failureThreshold, err := resolveIntEnvVar(extension.IdpCircuitBreakerThresholdEnvVar,
+		extension.DefaultIdpCircuitBreakerThreshold)
+	if err != nil {
+		return err
+	}
+	openDuration, err := resolveIntEnvVar(extension.IdpCircuitBreakerOpenDurationEnvVar,
+		extension.DefaultIdpCircuitBreakerOpenDuration)
+	if err != nil {
+		return err
+	}
+	logger.Debugf("IDP resilience configurations. MaxRetries = %d, RetryBackoff = %dms, MaxRetryBackoff = %dms, "+
+		"CircuitBreakerThreshold = %d, CircuitBreakerOpenDuration = %ds", maxRetries, initialRetryBackoff,
+		maxRetryBackoff, failureThreshold, openDuration)
+	v.maxRetries = maxRetries
+	v.initialRetryBackoff = time.Duration(initialRetryBackoff) * time.Millisecond
+	v.maxRetryBackoff = time.Duration(maxRetryBackoff) * time.Millisecond
+	v.circuitBreaker = newCircuitBreaker("IDP introspection", failureThreshold,
+		time.Duration(openDuration)*time.Second, introspectionMetrics)
+	return nil
+}
+
+// newTokenCacheFromEnv creates the introspection result cache using the configurations in the environment
+func newTokenCacheFromEnv(logger *zap.SugaredLogger) (*tokenCache, error) {
+	maxSize, err := resolveIntEnvVar(extension.TokenCacheMaxSizeEnvVar, extension.DefaultTokenCacheMaxSize)
+	if err != nil {
+		return nil, err
+	}
+	ttl, err := resolveIntEnvVar(extension.TokenCacheTtlEnvVar, extension.DefaultTokenCacheTtl)
+	if err != nil {
+		return nil, err
+	}
+	negativeTtl, err := resolveIntEnvVar(extension.TokenCacheNegativeTtlEnvVar,
+		extension.DefaultTokenCacheNegativeTtl)
+	if err != nil {
+		return nil, err
+	}
+	logger.Debugf("Token cache configurations. MaxSize = %d, Ttl = %d, NegativeTtl = %d", maxSize, ttl,
+		negativeTtl)
+	return newTokenCache(maxSize, time.Duration(ttl)*time.Second, time.Duration(negativeTtl)*time.Second), nil
+}
+
+// resolves the IS host and port from the environment variables.
+// If the environment is not set the port and host will be resolved through the config file.
+func resolveIntrospectionUrl(logger *zap.SugaredLogger) (string, error) {
+	idpEndPoint := os.Getenv("IDP_END_POINT")
+	introspectionEP := os.Getenv("INTROSPECTION_END_POINT")
+	if len(introspectionEP) == 0 {
+		return "", fmt.Errorf("'INTROSPECTION_END_POINT' environment variable is empty")
+	}
+	if len(idpEndPoint) == 0 {
+		return "", fmt.Errorf("'IDP_END_POINT' environment variable is empty")
+	}
+	logger.Debugf("Successfully resolved introspection url")
+	return idpEndPoint + introspectionEP, nil
+}
+
+// resolveCredentials resolves the user credentials of the user that is used to communicate to introspection endpoint
+func resolveCredentials(logger *zap.SugaredLogger) (string, string, error) {
+	username := os.Getenv(extension.IdpUsernameEnvVar)
+	if len(username) == 0 {
+		return "", "", fmt.Errorf("'USERNAME' environment variable is empty")
+	}
+	password := os.Getenv(extension.IdppasswordEnvVar)
+	if len(password) == 0 {
+		return "", "", fmt.Errorf("'PASSWORD' environment variable is empty")
+	}
+	logger.Debugf("Successfully resolved credentials")
+	return username, password, nil
+}
@@ -0,0 +1,188 @@
+/*
+ * Copyright (c) 2019 WSO2 Inc. (http:www.wso2.org) All Rights Reserved.
+ *
+ * WSO2 Inc. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http:www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// minJwksRefreshInterval limits how often an unknown key id can trigger a refresh of the key set
+const minJwksRefreshInterval = time.Minute
+
+// jwksKeySet holds the public keys of the IDP loaded from a JWKS document. The document is either fetched from
+// a URL and refreshed periodically or loaded from a file. Previously loaded keys are kept if a refresh fails,
+// so that tokens can still be validated during short IDP outages.
+type jwksKeySet struct {
+	mutex           sync.Mutex
+	url             string
+	file            string
+	refreshInterval time.Duration
+	client          *http.Client
+	keys            map[string]interface{}
+	lastRefresh     time.Time
+}
+
+type jwksDocument struct {
+	Keys []jwk `json:"keys"`
+}
+
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// getKey returns the public key with the given key id, refreshing the key set if required
+func (s *jwksKeySet) getKey(kid string, logger *zap.SugaredLogger, execId string) (interface{}, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	sinceRefresh := time.Since(s.lastRefresh)
+	_, isKnownKey := s.keys[kid]
+	if s.keys == nil || sinceRefresh > s.refreshInterval || (!isKnownKey && sinceRefresh > minJwksRefreshInterval) {
+		err := s.refresh(logger, execId)
+		if err != nil {
+			if s.keys == nil {
+				return nil, err
+			}
+			logger.Errorf("[%s] Using previously loaded keys since refreshing the JWKS failed : %v", execId, err)
+		}
+	}
+	key, ok := s.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("key with id %q not found in the JWKS", kid)
+	}
+	return key, nil
+}
+
+func (s *jwksKeySet) refresh(logger *zap.SugaredLogger, execId string) error {
+	// The refresh time is updated even on failure so that an unavailable IDP is not called on every request
+	s.lastRefresh = time.Now()
+	var content []byte
+	var err error
+	if len(s.file) > 0 {
+		logger.Debugf("[%s] Loading JWKS from the file %s", execId, s.file)
+		content, err = ioutil.ReadFile(s.file)
+		if err != nil {
+			return fmt.Errorf("error reading the JWKS file : %v", err)
+		}
+	} else {
+		logger.Debugf("[%s] Fetching JWKS from %s", execId, s.url)
+		content, err = fetchJwks(s.client, s.url)
+		if err != nil {
+			return err
+		}
+	}
+	keys, err := parseJwks(content)
+	if err != nil {
+		return err
+	}
+	s.keys = keys
+	logger.Debugf("[%s] Loaded %d keys from the JWKS", execId, len(keys))
+	return nil
+}
+
+func fetchJwks(client *http.Client, url string) ([]byte, error) {
+	res, err := client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("error sending the request to the JWKS endpoint : %v", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error while fetching the JWKS, status code :%d", res.StatusCode)
+	}
+	content, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the response from the JWKS endpoint : %v", err)
+	}
+	return content, nil
+}
+
+// parseJwks parses the RSA and EC signing keys of a JWKS document
+func parseJwks(content []byte) (map[string]interface{}, error) {
+	var document jwksDocument
+	err := json.Unmarshal(content, &document)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling the JWKS : %v", err)
+	}
+	keys := make(map[string]interface{})
+	for _, key := range document.Keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+		switch key.Kty {
+		case "RSA":
+			n, err := decodeBigInt(key.N)
+			if err != nil {
+				return nil, fmt.Errorf("error decoding the modulus of the key %q : %v", key.Kid, err)
+			}
+			e, err := decodeBigInt(key.E)
+			if err != nil {
+				return nil, fmt.Errorf("error decoding the exponent of the key %q : %v", key.Kid, err)
+			}
+			keys[key.Kid] = &rsa.PublicKey{N: n, E: int(e.Int64())}
+		case "EC":
+			var curve elliptic.Curve
+			switch key.Crv {
+			case "P-256":
+				curve = elliptic.P256()
+			case "P-384":
+				curve = elliptic.P384()
+			case "P-521":
+				curve = elliptic.P521()
+			default:
+				return nil, fmt.Errorf("unsupported curve %q in the key %q", key.Crv, key.Kid)
+			}
+			x, err := decodeBigInt(key.X)
+			if err != nil {
+				return nil, fmt.Errorf("error decoding the x coordinate of the key %q : %v", key.Kid, err)
+			}
+			y, err := decodeBigInt(key.Y)
+			if err != nil {
+				return nil, fmt.Errorf("error decoding the y coordinate of the key %q : %v", key.Kid, err)
+			}
+			keys[key.Kid] = &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+		}
+	}
+	return keys, nil
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	bytes, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(bytes), nil
+}
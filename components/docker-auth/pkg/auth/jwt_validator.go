@@ -0,0 +1,162 @@
+/*
+ * Copyright (c) 2019 WSO2 Inc. (http:www.wso2.org) All Rights Reserved.
+ *
+ * WSO2 Inc. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http:www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/subtle"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"go.uber.org/zap"
+
+	"github.com/cellery-io/cellery-hub/components/docker-auth/pkg/extension"
+)
+
+// jwtValidator validates JWT access tokens offline by verifying their signatures against the JWKS of the IDP and
+// checking their claims. Opaque tokens are left to the next validator in the chain.
+type jwtValidator struct {
+	issuer        string
+	audience      string
+	usernameClaim string
+	keySet        *jwksKeySet
+}
+
+func newJwtValidator(logger *zap.SugaredLogger) (*jwtValidator, error) {
+	jwksUrl := os.Getenv(extension.JwksUrlEnvVar)
+	jwksFile := os.Getenv(extension.JwksFileEnvVar)
+	if len(jwksUrl) == 0 && len(jwksFile) == 0 {
+		return nil, fmt.Errorf("either '%s' or '%s' environment variable is required for JWT validation",
+			extension.JwksUrlEnvVar, extension.JwksFileEnvVar)
+	}
+	refreshInterval, err := resolveIntEnvVar(extension.JwksRefreshIntervalEnvVar,
+		extension.DefaultJwksRefreshInterval)
+	if err != nil {
+		return nil, err
+	}
+	usernameClaim := os.Getenv(extension.JwtUsernameClaimEnvVar)
+	if len(usernameClaim) == 0 {
+		usernameClaim = extension.DefaultJwtUsernameClaim
+	}
+	client, err := getIdpHttpClient(logger)
+	if err != nil {
+		return nil, fmt.Errorf("error occured while creating the IDP http client: %v", err)
+	}
+	validator := &jwtValidator{
+		issuer:        os.Getenv(extension.JwtIssuerEnvVar),
+		audience:      os.Getenv(extension.JwtAudienceEnvVar),
+		usernameClaim: usernameClaim,
+		keySet: &jwksKeySet{
+			url:             jwksUrl,
+			file:            jwksFile,
+			refreshInterval: time.Duration(refreshInterval) * time.Second,
+			client:          client,
+		},
+	}
+	logger.Debugf("JWT validation configurations. Issuer = %s, Audience = %s, UsernameClaim = %s, "+
+		"RefreshInterval = %d", validator.issuer, validator.audience, usernameClaim, refreshInterval)
+	return validator, nil
+}
+
+func (v *jwtValidator) Name() string {
+	return extension.JwtTokenValidator
+}
+
+// Validate verifies the signature of the JWT against the JWKS and checks its claims
+func (v *jwtValidator) Validate(token string, logger *zap.SugaredLogger, execId string) (*TokenInfo, error) {
+	if strings.Count(token, ".") != 2 {
+		return nil, ErrTokenNotSupported
+	}
+	parser := &jwt.Parser{}
+	claims := jwt.MapClaims{}
+	_, err := parser.ParseWithClaims(token, claims, func(parsedToken *jwt.Token) (interface{}, error) {
+		kid, _ := parsedToken.Header["kid"].(string)
+		key, err := v.keySet.getKey(kid, logger, execId)
+		if err != nil {
+			return nil, err
+		}
+		switch parsedToken.Method.(type) {
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+			if _, ok := key.(*rsa.PublicKey); ok {
+				return key, nil
+			}
+		case *jwt.SigningMethodECDSA:
+			if _, ok := key.(*ecdsa.PublicKey); ok {
+				return key, nil
+			}
+		}
+		return nil, fmt.Errorf("signing method %s does not match the key %q", parsedToken.Method.Alg(), kid)
+	})
+	inactiveToken := &TokenInfo{Active: false}
+	if err != nil {
+		validationErr, ok := err.(*jwt.ValidationError)
+		if ok && validationErr.Errors&jwt.ValidationErrorMalformed != 0 {
+			logger.Debugf("[%s] Access token could not be parsed as a JWT : %v", execId, err)
+			return nil, ErrTokenNotSupported
+		}
+		logger.Debugf("[%s] JWT access token validation failed : %v", execId, err)
+		return inactiveToken, nil
+	}
+	logger.Debugf("[%s] Signature of the JWT access token is valid", execId)
+
+	now := time.Now().Unix()
+	if !claims.VerifyExpiresAt(now, true) {
+		logger.Debugf("[%s] JWT access token is expired or does not contain an expiry time", execId)
+		return inactiveToken, nil
+	}
+	if !claims.VerifyNotBefore(now, false) {
+		logger.Debugf("[%s] JWT access token is not valid yet", execId)
+		return inactiveToken, nil
+	}
+	if len(v.issuer) > 0 && !claims.VerifyIssuer(v.issuer, true) {
+		logger.Debugf("[%s] Issuer of the JWT access token does not match %s", execId, v.issuer)
+		return inactiveToken, nil
+	}
+	if len(v.audience) > 0 && !verifyAudience(claims["aud"], v.audience) {
+		logger.Debugf("[%s] Audience of the JWT access token does not contain %s", execId, v.audience)
+		return inactiveToken, nil
+	}
+	username, ok := claims[v.usernameClaim].(string)
+	if !ok {
+		logger.Debugf("[%s] JWT access token does not contain the username claim %s", execId, v.usernameClaim)
+		return inactiveToken, nil
+	}
+	exp, _ := claims["exp"].(float64)
+	return &TokenInfo{Active: true, Username: username, Exp: int64(exp)}, nil
+}
+
+// verifyAudience checks the audience claim which can either be a single string or an array of strings
+func verifyAudience(audClaim interface{}, audience string) bool {
+	switch aud := audClaim.(type) {
+	case string:
+		return subtle.ConstantTimeCompare([]byte(aud), []byte(audience)) == 1
+	case []interface{}:
+		for _, value := range aud {
+			if audString, ok := value.(string); ok &&
+				subtle.ConstantTimeCompare([]byte(audString), []byte(audience)) == 1 {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,161 @@
+/*
+ * Copyright (c) 2019 WSO2 Inc. (http:www.wso2.org) All Rights Reserved.
+ *
+ * WSO2 Inc. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http:www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"go.uber.org/zap"
+)
+
+const testExecId = "testExecId"
+const testKeyId = "testKey"
+const testIssuer = "https://localhost:9443/oauth2/token"
+const testAudience = "cellery-hub"
+
+func createJwtValidator(t *testing.T, key *rsa.PrivateKey) *jwtValidator {
+	document := jwksDocument{
+		Keys: []jwk{{
+			Kid: testKeyId,
+			Kty: "RSA",
+			Use: "sig",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}},
+	}
+	content, err := json.Marshal(document)
+	if err != nil {
+		t.Fatal("Error while marshalling the JWKS :", err)
+	}
+	dir, err := ioutil.TempDir("", "jwks")
+	if err != nil {
+		t.Fatal("Error while creating the temp dir :", err)
+	}
+	jwksFile := filepath.Join(dir, "jwks.json")
+	err = ioutil.WriteFile(jwksFile, content, 0600)
+	if err != nil {
+		t.Fatal("Error while writing the JWKS file :", err)
+	}
+	return &jwtValidator{
+		issuer:        testIssuer,
+		audience:      testAudience,
+		usernameClaim: "sub",
+		keySet: &jwksKeySet{
+			file:            jwksFile,
+			refreshInterval: time.Hour,
+		},
+	}
+}
+
+func signToken(t *testing.T, key *rsa.PrivateKey, kid string, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	signedToken, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal("Error while signing the token :", err)
+	}
+	return signedToken
+}
+
+func TestValidateJwt(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal("Error while generating the key :", err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal("Error while generating the key :", err)
+	}
+	validator := createJwtValidator(t, key)
+	defer os.RemoveAll(filepath.Dir(validator.keySet.file))
+
+	now := time.Now()
+	validClaims := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"sub": "admin@cellery.io",
+			"iss": testIssuer,
+			"aud": []interface{}{"other", testAudience},
+			"exp": now.Add(time.Hour).Unix(),
+			"nbf": now.Add(-time.Minute).Unix(),
+		}
+	}
+	withClaim := func(name string, value interface{}) jwt.MapClaims {
+		claims := validClaims()
+		if value == nil {
+			delete(claims, name)
+		} else {
+			claims[name] = value
+		}
+		return claims
+	}
+
+	values := []struct {
+		name     string
+		token    string
+		username string
+		isValid  bool
+	}{
+		{"valid token", signToken(t, key, testKeyId, validClaims()), "admin", true},
+		{"string audience", signToken(t, key, testKeyId, withClaim("aud", testAudience)), "admin", true},
+		{"other user", signToken(t, key, testKeyId, validClaims()), "other", false},
+		{"expired", signToken(t, key, testKeyId, withClaim("exp", now.Add(-time.Minute).Unix())), "admin", false},
+		{"without expiry", signToken(t, key, testKeyId, withClaim("exp", nil)), "admin", false},
+		{"not yet valid", signToken(t, key, testKeyId, withClaim("nbf", now.Add(time.Hour).Unix())), "admin",
+			false},
+		{"wrong issuer", signToken(t, key, testKeyId, withClaim("iss", "https://other")), "admin", false},
+		{"wrong audience", signToken(t, key, testKeyId, withClaim("aud", "other")), "admin", false},
+		{"unknown key", signToken(t, key, "unknownKey", validClaims()), "admin", false},
+		{"wrong signature", signToken(t, otherKey, testKeyId, validClaims()), "admin", false},
+	}
+	logger := zap.NewExample().Sugar()
+	for _, value := range values {
+		tokenInfo, err := validateAccessToken([]TokenValidator{validator}, value.token, value.username, logger,
+			testExecId)
+		if err != nil {
+			t.Error("Error while validating the token for the case", value.name, ":", err)
+		}
+		if isValid := tokenInfo != nil; isValid != value.isValid {
+			t.Error("Expected validity", value.isValid, "but found", isValid, "for the case", value.name)
+		}
+	}
+}
+
+func TestValidateOpaqueToken(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal("Error while generating the key :", err)
+	}
+	validator := createJwtValidator(t, key)
+	defer os.RemoveAll(filepath.Dir(validator.keySet.file))
+	logger := zap.NewExample().Sugar()
+	_, err = validator.Validate("3a6fc1bd-5b9b-3a0f-a6d3-a6a8fd1a7b1e", logger, testExecId)
+	if err != ErrTokenNotSupported {
+		t.Error("Opaque token is expected to be left to the next validator but found :", err)
+	}
+}
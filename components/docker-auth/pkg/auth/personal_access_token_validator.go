@@ -0,0 +1,130 @@
+/*
+ * Copyright (c) 2019 WSO2 Inc. (http:www.wso2.org) All Rights Reserved.
+ *
+ * WSO2 Inc. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http:www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package auth
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/cellery-io/cellery-hub/components/docker-auth/pkg/db"
+	"github.com/cellery-io/cellery-hub/components/docker-auth/pkg/extension"
+)
+
+// personalAccessTokenValidator validates long lived personal access tokens which are stored as SHA-256 hashes in
+// the database. A personal access token is limited to a set of scopes (pull, push, delete) and optionally to a
+// single organization. Tokens without the personal access token prefix are left to the next validator. The results
+// are cached until the expiry of the token if it is earlier than the time to live of the cache.
+type personalAccessTokenValidator struct {
+	dbConnectionPool *db.SharedConnectionPool
+	cache            *tokenCache
+}
+
+// unexpiringTokenExpiry is used as the expiry of the personal access tokens which do not expire
+var unexpiringTokenExpiry = time.Date(9999, time.December, 31, 0, 0, 0, 0, time.UTC)
+
+func newPersonalAccessTokenValidator(dbConnectionPool *db.SharedConnectionPool,
+	logger *zap.SugaredLogger) (*personalAccessTokenValidator, error) {
+	cache, err := newTokenCacheFromEnv(logger)
+	if err != nil {
+		return nil, err
+	}
+	return &personalAccessTokenValidator{dbConnectionPool: dbConnectionPool, cache: cache}, nil
+}
+
+func (v *personalAccessTokenValidator) Name() string {
+	return extension.PersonalAccessTokenValidator
+}
+
+// Validate looks up the hash of the personal access token in the database
+func (v *personalAccessTokenValidator) Validate(token string, logger *zap.SugaredLogger, execId string) (*TokenInfo,
+	error) {
+	if !strings.HasPrefix(token, extension.PersonalAccessTokenPrefix) {
+		return nil, ErrTokenNotSupported
+	}
+	if tokenInfo, isCached := v.cache.get(token); isCached {
+		logger.Debugf("[%s] Resolved personal access token validity from the cache", execId)
+		return &tokenInfo, nil
+	}
+	dbConnection, err := v.dbConnectionPool.Get(logger)
+	if err != nil {
+		return nil, fmt.Errorf("error while establishing database connection pool: %v", err)
+	}
+	// The driver is valid since the connection pool has been created with it
+	driver, _ := extension.ResolveDbDriver()
+	results, err := dbConnection.Query(extension.RebindQuery(driver, extension.GetPersonalAccessTokenQuery),
+		hashToken(token))
+	defer func() {
+		if results != nil {
+			err := results.Close()
+			if err != nil {
+				logger.Errorf("[%s] error while closing result set in personal access token validation : %v",
+					execId, err)
+			}
+		}
+	}()
+	if err != nil {
+		v.dbConnectionPool.ResetIfUnhealthy(logger)
+		return nil, fmt.Errorf("error while executing the query getPersonalAccessTokenQuery :%s", err)
+	}
+	if !results.Next() {
+		logger.Debugf("[%s] Personal access token does not exist or has expired", execId)
+		tokenInfo := &TokenInfo{Active: false}
+		v.cache.put(token, *tokenInfo)
+		return tokenInfo, nil
+	}
+	var username string
+	var scopes string
+	var organization string
+	var expiresAt *time.Time
+	err = results.Scan(&username, &scopes, &organization, &expiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("[%s] Error in retrieving the personal access token from the database :%s",
+			execId, err)
+	}
+	tokenInfo := newPersonalAccessTokenInfo(username, scopes, organization, expiresAt)
+	v.cache.put(token, *tokenInfo)
+	logger.Debugf("[%s] Personal access token of the user %s has the scopes %s and expires at %s", execId,
+		username, tokenInfo.Scopes, time.Unix(tokenInfo.Exp, 0))
+	return tokenInfo, nil
+}
+
+// newPersonalAccessTokenInfo creates the information of an active personal access token. The expiry stored with the
+// token is used, and tokens without an expiry are considered valid until they are deleted.
+func newPersonalAccessTokenInfo(username string, scopes string, organization string,
+	expiresAt *time.Time) *TokenInfo {
+	tokenInfo := &TokenInfo{
+		Active:       true,
+		Username:     username,
+		Organization: organization,
+		Scopes:       []string{},
+		Exp:          unexpiringTokenExpiry.Unix(),
+	}
+	if expiresAt != nil {
+		tokenInfo.Exp = expiresAt.Unix()
+	}
+	for _, scope := range strings.Split(scopes, ",") {
+		if scope = strings.TrimSpace(scope); len(scope) > 0 {
+			tokenInfo.Scopes = append(tokenInfo.Scopes, scope)
+		}
+	}
+	return tokenInfo
+}
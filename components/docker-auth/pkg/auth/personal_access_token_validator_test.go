@@ -0,0 +1,66 @@
+/*
+ * Copyright (c) 2019 WSO2 Inc. (http:www.wso2.org) All Rights Reserved.
+ *
+ * WSO2 Inc. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http:www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/cellery-io/cellery-hub/components/docker-auth/pkg/extension"
+)
+
+func TestPersonalAccessTokenExpiry(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	tokenInfo := newPersonalAccessTokenInfo("alice", "pull, push", "cellery", &expiresAt)
+	if tokenInfo.Exp != expiresAt.Unix() {
+		t.Error("Expected the stored expiry", expiresAt.Unix(), "but found", tokenInfo.Exp)
+	}
+	if strings.Join(tokenInfo.Scopes, ",") != "pull,push" {
+		t.Error("Expected the scopes pull and push but found", tokenInfo.Scopes)
+	}
+	tokenInfo = newPersonalAccessTokenInfo("alice", "pull", "", nil)
+	if tokenInfo.Exp != unexpiringTokenExpiry.Unix() {
+		t.Error("Expected a token without an expiry not to expire but found", time.Unix(tokenInfo.Exp, 0))
+	}
+}
+
+// The cached results are used without querying the database until the token expires
+func TestPersonalAccessTokenCache(t *testing.T) {
+	logger := zap.NewExample().Sugar()
+	validator, err := newPersonalAccessTokenValidator(nil, logger)
+	if err != nil {
+		t.Fatal("Error while creating the validator :", err)
+	}
+	token := extension.PersonalAccessTokenPrefix + "token"
+	expiresAt := time.Now().Add(time.Minute)
+	validator.cache.put(token, *newPersonalAccessTokenInfo("alice", "pull", "", &expiresAt))
+	tokenInfo, err := validator.Validate(token, logger, testExecId)
+	if err != nil || !tokenInfo.Active || tokenInfo.Username != "alice" {
+		t.Error("Expected the cached personal access token but found", tokenInfo, err)
+	}
+	validator.cache.now = func() time.Time {
+		return expiresAt
+	}
+	if _, isCached := validator.cache.get(token); isCached {
+		t.Error("Expected the personal access token not to be cached beyond its expiry")
+	}
+}
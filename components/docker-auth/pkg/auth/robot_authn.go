@@ -0,0 +1,163 @@
+/*
+ * Copyright (c) 2019 WSO2 Inc. (http:www.wso2.org) All Rights Reserved.
+ *
+ * WSO2 Inc. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http:www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package auth
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/cellery-io/cellery-hub/components/docker-auth/pkg/extension"
+)
+
+// RobotAccount is an organization scoped account used by CI pipelines to pull and push images
+type RobotAccount struct {
+	Organization string
+	Name         string
+	Actions      []string
+}
+
+// IsRobotUsername checks whether the username is of the form robot$<organization>+<name>
+func IsRobotUsername(username string) bool {
+	return strings.HasPrefix(username, extension.RobotUsernamePrefix)
+}
+
+// AuthenticateRobot validates the secret of a robot account against the hashed secret stored in the database.
+// The robot account is returned only if the secret is valid and the account has not expired. The failures of the
+// database are returned as store errors.
+func AuthenticateRobot(dbConn *sql.DB, username string, secret string, logger *zap.SugaredLogger,
+	execId string) (*RobotAccount, error) {
+	logger.Debugf("[%s] Authenticating robot account %s", execId, username)
+	organization, robotName, err := parseRobotUsername(username)
+	if err != nil {
+		logger.Debugf("[%s] Robot account is not authenticated : %v", execId, err)
+		return nil, nil
+	}
+	driver, err := extension.ResolveDbDriver()
+	if err != nil {
+		return nil, err
+	}
+	results, err := dbConn.Query(extension.RebindQuery(driver, extension.GetRobotAccountQuery), organization,
+		robotName)
+	defer func() {
+		if results != nil {
+			err := results.Close()
+			if err != nil {
+				logger.Errorf("[%s] error while closing result set in AuthenticateRobot : %v", execId, err)
+			}
+		}
+	}()
+	if err != nil {
+		return nil, &extension.StoreError{
+			Err: fmt.Errorf("error while executing the query getRobotAccountQuery :%s", err),
+		}
+	}
+	if !results.Next() {
+		logger.Debugf("[%s] Robot account %s does not exist or has expired", execId, username)
+		return nil, nil
+	}
+	var secretHash string
+	var actions string
+	err = results.Scan(&secretHash, &actions)
+	if err != nil {
+		return nil, &extension.StoreError{
+			Err: fmt.Errorf("[%s] Error in retrieving the robot account from the database :%s", execId, err),
+		}
+	}
+	isValidSecret, err := verifySecret(secret, secretHash)
+	if err != nil {
+		return nil, fmt.Errorf("[%s] Error while verifying the secret of the robot account %s :%s", execId,
+			username, err)
+	}
+	if !isValidSecret {
+		logger.Debugf("[%s] Secret of the robot account %s is invalid", execId, username)
+		return nil, nil
+	}
+	var allowedActions []string
+	for _, action := range strings.Split(actions, ",") {
+		if action = strings.TrimSpace(action); len(action) > 0 {
+			allowedActions = append(allowedActions, action)
+		}
+	}
+	logger.Debugf("[%s] Robot account %s authenticated with actions %s", execId, username, allowedActions)
+	return &RobotAccount{Organization: organization, Name: robotName, Actions: allowedActions}, nil
+}
+
+// parseRobotUsername splits a username of the form robot$<organization>+<name>
+func parseRobotUsername(username string) (string, string, error) {
+	tokens := strings.SplitN(strings.TrimPrefix(username, extension.RobotUsernamePrefix), "+", 2)
+	if len(tokens) != 2 || len(tokens[0]) == 0 || len(tokens[1]) == 0 {
+		return "", "", fmt.Errorf("robot username %q is not of the form %s<organization>+<name>", username,
+			extension.RobotUsernamePrefix)
+	}
+	return tokens[0], tokens[1], nil
+}
+
+// verifySecret compares a secret with a bcrypt hash or an argon2id hash in the PHC string format
+// ($argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>)
+func verifySecret(secret string, secretHash string) (bool, error) {
+	if strings.HasPrefix(secretHash, "$argon2id$") {
+		return verifyArgon2idSecret(secret, secretHash)
+	}
+	err := bcrypt.CompareHashAndPassword([]byte(secretHash), []byte(secret))
+	if err == bcrypt.ErrMismatchedHashAndPassword {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func verifyArgon2idSecret(secret string, secretHash string) (bool, error) {
+	tokens := strings.Split(secretHash, "$")
+	if len(tokens) != 6 {
+		return false, fmt.Errorf("invalid argon2id hash format")
+	}
+	var version int
+	_, err := fmt.Sscanf(tokens[2], "v=%d", &version)
+	if err != nil {
+		return false, fmt.Errorf("invalid argon2id version : %v", err)
+	}
+	if version != argon2.Version {
+		return false, fmt.Errorf("unsupported argon2id version %d", version)
+	}
+	var memory, iterations uint32
+	var parallelism uint8
+	_, err = fmt.Sscanf(tokens[3], "m=%d,t=%d,p=%d", &memory, &iterations, &parallelism)
+	if err != nil {
+		return false, fmt.Errorf("invalid argon2id parameters : %v", err)
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(tokens[4])
+	if err != nil {
+		return false, fmt.Errorf("invalid argon2id salt : %v", err)
+	}
+	hash, err := base64.RawStdEncoding.DecodeString(tokens[5])
+	if err != nil {
+		return false, fmt.Errorf("invalid argon2id hash : %v", err)
+	}
+	secretKey := argon2.IDKey([]byte(secret), salt, iterations, memory, parallelism, uint32(len(hash)))
+	return subtle.ConstantTimeCompare(secretKey, hash) == 1, nil
+}
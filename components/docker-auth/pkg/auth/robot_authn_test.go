@@ -0,0 +1,87 @@
+/*
+ * Copyright (c) 2019 WSO2 Inc. (http:www.wso2.org) All Rights Reserved.
+ *
+ * WSO2 Inc. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http:www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package auth
+
+import (
+	"database/sql"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/cellery-io/cellery-hub/components/docker-auth/pkg/extension"
+)
+
+func TestVerifySecret(t *testing.T) {
+	values := []struct {
+		secret     string
+		secretHash string
+		isValid    bool
+	}{
+		{"robotSecret", "$2a$10$MVum9v2XjQoiz4f4BbLs4.5Vz8P8H2agMeLpBJCOKSFt/YVKEnYRO", true},
+		{"otherSecret", "$2a$10$MVum9v2XjQoiz4f4BbLs4.5Vz8P8H2agMeLpBJCOKSFt/YVKEnYRO", false},
+		{"robotSecret", "$argon2id$v=19$m=65536,t=3,p=2$zQQitDxYlsPeGe1L9NpYaQ$" +
+			"8fymmEpXEaTfZGYT2CfnFLbdhHLshs13U/ZasRU7YiY", true},
+		{"otherSecret", "$argon2id$v=19$m=65536,t=3,p=2$zQQitDxYlsPeGe1L9NpYaQ$" +
+			"8fymmEpXEaTfZGYT2CfnFLbdhHLshs13U/ZasRU7YiY", false},
+	}
+	for _, value := range values {
+		isValid, err := verifySecret(value.secret, value.secretHash)
+		if err != nil {
+			t.Error("Error while verifying the secret", value.secret, ":", err)
+		}
+		if isValid != value.isValid {
+			t.Error("Expected validity", value.isValid, "but found", isValid, "for the secret", value.secret,
+				"against the hash", value.secretHash)
+		}
+	}
+}
+
+func TestParseRobotUsername(t *testing.T) {
+	organization, robotName, err := parseRobotUsername("robot$cellery+ci-pipeline")
+	if err != nil {
+		t.Error("Error while parsing the robot username :", err)
+	}
+	if organization != "cellery" || robotName != "ci-pipeline" {
+		t.Error("Unexpected organization", organization, "and robot name", robotName)
+	}
+	for _, username := range []string{"robot$cellery", "robot$+ci", "robot$cellery+"} {
+		_, _, err := parseRobotUsername(username)
+		if err == nil {
+			t.Error("Invalid robot username", username, "is expected to be rejected")
+		}
+	}
+}
+
+func TestAuthenticateRobotErrors(t *testing.T) {
+	// The database is not reachable, hence only the valid robot usernames cause store errors
+	dbConnection, err := sql.Open(extension.MysqlDriver, "celleryhub:secret@tcp(127.0.0.1:1)/CELLERY_HUB")
+	if err != nil {
+		t.Fatal("Error while opening the database :", err)
+	}
+	defer dbConnection.Close()
+	logger := zap.NewExample().Sugar()
+	robotAccount, err := AuthenticateRobot(dbConnection, "robot$cellery", "secret", logger, testExecId)
+	if err != nil || robotAccount != nil {
+		t.Error("Expected a malformed robot username not to be authenticated but found", robotAccount, err)
+	}
+	_, err = AuthenticateRobot(dbConnection, "robot$cellery+ci-pipeline", "secret", logger, testExecId)
+	if !extension.IsStoreError(err) {
+		t.Error("Expected a store error when the database is not reachable but found", err)
+	}
+}
@@ -0,0 +1,138 @@
+/*
+ * Copyright (c) 2019 WSO2 Inc. (http:www.wso2.org) All Rights Reserved.
+ *
+ * WSO2 Inc. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http:www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package auth
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+
+	"github.com/cellery-io/cellery-hub/components/docker-auth/pkg/extension"
+)
+
+// staticTokenValidator validates tokens against a YAML file which maps tokens to usernames. The file is reloaded
+// when it is modified. Tokens which are not in the file are left to the next validator in the chain.
+//
+//	tokens:
+//	  - tokenSha256: 9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08
+//	    username: ci-bot
+//	    expiresAt: 2020-01-01T00:00:00Z
+type staticTokenValidator struct {
+	mutex   sync.Mutex
+	file    string
+	modTime time.Time
+	tokens  map[string]staticToken
+}
+
+type staticTokenFile struct {
+	Tokens []staticToken `yaml:"tokens"`
+}
+
+type staticToken struct {
+	Token       string    `yaml:"token"`
+	TokenSha256 string    `yaml:"tokenSha256"`
+	Username    string    `yaml:"username"`
+	ExpiresAt   time.Time `yaml:"expiresAt"`
+}
+
+func newStaticTokenValidator(logger *zap.SugaredLogger) (*staticTokenValidator, error) {
+	file := os.Getenv(extension.StaticTokenFileEnvVar)
+	if len(file) == 0 {
+		return nil, fmt.Errorf("'%s' environment variable is required for static token validation",
+			extension.StaticTokenFileEnvVar)
+	}
+	validator := &staticTokenValidator{file: file}
+	err := validator.reloadIfModified(logger)
+	if err != nil {
+		return nil, err
+	}
+	return validator, nil
+}
+
+func (v *staticTokenValidator) Name() string {
+	return extension.StaticTokenValidator
+}
+
+// Validate looks up the hash of the token in the static token file
+func (v *staticTokenValidator) Validate(token string, logger *zap.SugaredLogger, execId string) (*TokenInfo,
+	error) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	err := v.reloadIfModified(logger)
+	if err != nil {
+		logger.Errorf("[%s] Using previously loaded static tokens since reloading failed : %v", execId, err)
+	}
+	tokenHash := hashToken(token)
+	for hash, staticToken := range v.tokens {
+		if subtle.ConstantTimeCompare([]byte(hash), []byte(tokenHash)) == 1 {
+			logger.Debugf("[%s] Token found in the static token file", execId)
+			// Tokens without an expiry time never expire
+			exp := time.Now().Add(time.Hour).Unix()
+			if !staticToken.ExpiresAt.IsZero() {
+				exp = staticToken.ExpiresAt.Unix()
+			}
+			return &TokenInfo{Active: true, Username: staticToken.Username, Exp: exp}, nil
+		}
+	}
+	return nil, ErrTokenNotSupported
+}
+
+func (v *staticTokenValidator) reloadIfModified(logger *zap.SugaredLogger) error {
+	fileInfo, err := os.Stat(v.file)
+	if err != nil {
+		return fmt.Errorf("error reading the static token file : %v", err)
+	}
+	if v.tokens != nil && fileInfo.ModTime().Equal(v.modTime) {
+		return nil
+	}
+	content, err := ioutil.ReadFile(v.file)
+	if err != nil {
+		return fmt.Errorf("error reading the static token file : %v", err)
+	}
+	var tokenFile staticTokenFile
+	err = yaml.Unmarshal(content, &tokenFile)
+	if err != nil {
+		return fmt.Errorf("error unmarshalling the static token file : %v", err)
+	}
+	tokens := make(map[string]staticToken)
+	for _, staticToken := range tokenFile.Tokens {
+		if len(staticToken.Username) == 0 {
+			return fmt.Errorf("username is not defined for a token in the static token file")
+		}
+		tokenHash := staticToken.TokenSha256
+		if len(staticToken.Token) > 0 {
+			tokenHash = hashToken(staticToken.Token)
+		}
+		if len(tokenHash) == 0 {
+			return fmt.Errorf("token is not defined for the user %s in the static token file",
+				staticToken.Username)
+		}
+		tokens[tokenHash] = staticToken
+	}
+	v.tokens = tokens
+	v.modTime = fileInfo.ModTime()
+	logger.Debugf("Loaded %d tokens from the static token file %s", len(tokens), v.file)
+	return nil
+}
@@ -0,0 +1,125 @@
+/*
+ * Copyright (c) 2019 WSO2 Inc. (http:www.wso2.org) All Rights Reserved.
+ *
+ * WSO2 Inc. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http:www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package auth
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// tokenCache is an in-memory LRU cache of introspection results keyed by a hash of the access token.
+// Active tokens are never kept beyond their expiry time and inactive tokens are kept for a shorter period.
+type tokenCache struct {
+	mutex        sync.Mutex
+	maxSize      int
+	ttl          time.Duration
+	negativeTtl  time.Duration
+	entries      map[string]*list.Element
+	evictionList *list.List
+	now          func() time.Time
+}
+
+type tokenCacheEntry struct {
+	key    string
+	info   TokenInfo
+	expiry time.Time
+}
+
+func newTokenCache(maxSize int, ttl time.Duration, negativeTtl time.Duration) *tokenCache {
+	return &tokenCache{
+		maxSize:      maxSize,
+		ttl:          ttl,
+		negativeTtl:  negativeTtl,
+		entries:      make(map[string]*list.Element),
+		evictionList: list.New(),
+		now:          time.Now,
+	}
+}
+
+// get returns the cached introspection result of the token if it has not expired yet
+func (c *tokenCache) get(token string) (TokenInfo, bool) {
+	key := hashToken(token)
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	element, ok := c.entries[key]
+	if !ok {
+		return TokenInfo{}, false
+	}
+	entry := element.Value.(*tokenCacheEntry)
+	if !c.now().Before(entry.expiry) {
+		c.removeElement(element)
+		return TokenInfo{}, false
+	}
+	c.evictionList.MoveToFront(element)
+	return entry.info, true
+}
+
+// put caches the introspection result of the token. The entry of an active token expires at the token expiry
+// time if it is earlier than the configured time to live.
+func (c *tokenCache) put(token string, info TokenInfo) {
+	now := c.now()
+	var expiry time.Time
+	if info.Active {
+		if c.ttl <= 0 {
+			return
+		}
+		expiry = now.Add(c.ttl)
+		tokenExpiry := time.Unix(info.Exp, 0)
+		if tokenExpiry.Before(expiry) {
+			expiry = tokenExpiry
+		}
+	} else {
+		if c.negativeTtl <= 0 {
+			return
+		}
+		expiry = now.Add(c.negativeTtl)
+	}
+	if !now.Before(expiry) || c.maxSize <= 0 {
+		return
+	}
+
+	key := hashToken(token)
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if element, ok := c.entries[key]; ok {
+		entry := element.Value.(*tokenCacheEntry)
+		entry.info = info
+		entry.expiry = expiry
+		c.evictionList.MoveToFront(element)
+		return
+	}
+	for c.evictionList.Len() >= c.maxSize {
+		c.removeElement(c.evictionList.Back())
+	}
+	c.entries[key] = c.evictionList.PushFront(&tokenCacheEntry{key: key, info: info, expiry: expiry})
+}
+
+func (c *tokenCache) removeElement(element *list.Element) {
+	c.evictionList.Remove(element)
+	delete(c.entries, element.Value.(*tokenCacheEntry).key)
+}
+
+// hashToken is used as the cache key so that raw access tokens are not kept in memory
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
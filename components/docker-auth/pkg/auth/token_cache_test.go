@@ -0,0 +1,85 @@
+/*
+ * Copyright (c) 2019 WSO2 Inc. (http:www.wso2.org) All Rights Reserved.
+ *
+ * WSO2 Inc. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http:www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTokenCacheExpiry(t *testing.T) {
+	now := time.Now()
+	cache := newTokenCache(10, 5*time.Minute, 30*time.Second)
+	cache.now = func() time.Time {
+		return now
+	}
+	cache.put("longLivedToken", TokenInfo{Active: true, Username: "admin", Exp: now.Add(time.Hour).Unix()})
+	cache.put("shortLivedToken", TokenInfo{Active: true, Username: "admin", Exp: now.Add(time.Minute).Unix()})
+	cache.put("inactiveToken", TokenInfo{Active: false})
+	cache.put("expiredToken", TokenInfo{Active: true, Username: "admin", Exp: now.Add(-time.Minute).Unix()})
+
+	values := []struct {
+		token    string
+		elapsed  time.Duration
+		isCached bool
+	}{
+		{"longLivedToken", 4 * time.Minute, true},
+		{"shortLivedToken", 59 * time.Second, true},
+		{"inactiveToken", 29 * time.Second, true},
+		{"expiredToken", 0, false},
+		{"unknownToken", 0, false},
+		// inactive tokens are cached for a shorter period
+		{"inactiveToken", 30 * time.Second, false},
+		// active tokens are not cached beyond the token expiry time
+		{"shortLivedToken", 2 * time.Minute, false},
+		{"longLivedToken", 5 * time.Minute, false},
+	}
+	for _, value := range values {
+		cache.now = func() time.Time {
+			return now.Add(value.elapsed)
+		}
+		_, isCached := cache.get(value.token)
+		if isCached != value.isCached {
+			t.Error("Unexpected cache state for token", value.token, "after", value.elapsed, ": expected",
+				value.isCached, "but found", isCached)
+		}
+	}
+}
+
+func TestTokenCacheEviction(t *testing.T) {
+	exp := time.Now().Add(time.Hour).Unix()
+	cache := newTokenCache(2, 5*time.Minute, 30*time.Second)
+	cache.put("token1", TokenInfo{Active: true, Username: "user1", Exp: exp})
+	cache.put("token2", TokenInfo{Active: true, Username: "user2", Exp: exp})
+	// token1 becomes the most recently used entry
+	if _, isCached := cache.get("token1"); !isCached {
+		t.Error("Token token1 is expected to be cached")
+	}
+	cache.put("token3", TokenInfo{Active: true, Username: "user3", Exp: exp})
+	if _, isCached := cache.get("token2"); isCached {
+		t.Error("Least recently used token token2 is expected to be evicted")
+	}
+	info, isCached := cache.get("token1")
+	if !isCached || info.Username != "user1" {
+		t.Error("Token token1 is expected to be cached with username user1")
+	}
+	if len(cache.entries) != 2 || cache.evictionList.Len() != 2 {
+		t.Error("Cache is expected to hold 2 entries but found", len(cache.entries))
+	}
+}
@@ -0,0 +1,108 @@
+/*
+ * Copyright (c) 2019 WSO2 Inc. (http:www.wso2.org) All Rights Reserved.
+ *
+ * WSO2 Inc. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http:www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"github.com/cellery-io/cellery-hub/components/docker-auth/pkg/db"
+	"github.com/cellery-io/cellery-hub/components/docker-auth/pkg/extension"
+)
+
+// ErrTokenNotSupported is returned by a token validator which is not able to decide on the validity of a token,
+// so that the token is passed to the next validator in the chain
+var ErrTokenNotSupported = errors.New("token is not supported by the validator")
+
+// TokenValidator validates the access tokens presented by the docker clients.
+// Implementations must be goroutine-safe.
+type TokenValidator interface {
+	// Validate resolves the information of the token. An inactive token is reported through TokenInfo and
+	// errors are only returned if the token could not be validated.
+	Validate(token string, logger *zap.SugaredLogger, execId string) (*TokenInfo, error)
+
+	// Name of the token validator
+	Name() string
+}
+
+// TokenInfo holds the attributes of a validated token which are used to authenticate the user. Scopes and
+// Organization are only set for tokens which are restricted to a subset of the actions of the user.
+type TokenInfo struct {
+	Active       bool     `json:"active"`
+	Username     string   `json:"username"`
+	Exp          int64    `json:"exp"`
+	Scopes       []string `json:"-"`
+	Organization string   `json:"-"`
+}
+
+var tokenValidators []TokenValidator
+var tokenValidatorsMutex sync.Mutex
+
+// getTokenValidators returns the chain of token validators configured through the environment, in the order
+// they should be tried. The chain is created on the first successful call, hence a failure such as an unreadable
+// token file is retried by the next call.
+func getTokenValidators(dbConnectionPool *db.SharedConnectionPool, logger *zap.SugaredLogger) ([]TokenValidator,
+	error) {
+	tokenValidatorsMutex.Lock()
+	defer tokenValidatorsMutex.Unlock()
+	if tokenValidators == nil {
+		validators, err := newTokenValidators(os.Getenv(extension.TokenValidatorsEnvVar), dbConnectionPool, logger)
+		if err != nil {
+			return nil, err
+		}
+		tokenValidators = validators
+	}
+	return tokenValidators, nil
+}
+
+// newTokenValidators creates the token validators from a comma separated list of validator names
+func newTokenValidators(validatorNames string, dbConnectionPool *db.SharedConnectionPool,
+	logger *zap.SugaredLogger) ([]TokenValidator, error) {
+	if len(strings.TrimSpace(validatorNames)) == 0 {
+		validatorNames = extension.DefaultTokenValidators
+	}
+	var validators []TokenValidator
+	for _, validatorName := range strings.Split(validatorNames, ",") {
+		var validator TokenValidator
+		var err error
+		switch strings.TrimSpace(validatorName) {
+		case extension.IntrospectionTokenValidator:
+			validator, err = newIntrospectionValidator(logger)
+		case extension.JwtTokenValidator:
+			validator, err = newJwtValidator(logger)
+		case extension.StaticTokenValidator:
+			validator, err = newStaticTokenValidator(logger)
+		case extension.PersonalAccessTokenValidator:
+			validator, err = newPersonalAccessTokenValidator(dbConnectionPool, logger)
+		default:
+			err = fmt.Errorf("unknown token validator %q", validatorName)
+		}
+		if err != nil {
+			return nil, err
+		}
+		validators = append(validators, validator)
+	}
+	logger.Debugf("Token validators are configured in the order %s", validatorNames)
+	return validators, nil
+}
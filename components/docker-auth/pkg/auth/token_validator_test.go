@@ -0,0 +1,130 @@
+/*
+ * Copyright (c) 2019 WSO2 Inc. (http:www.wso2.org) All Rights Reserved.
+ *
+ * WSO2 Inc. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http:www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package auth
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/cellery-io/cellery-hub/components/docker-auth/pkg/extension"
+)
+
+const staticTokens = `
+tokens:
+  - token: ci-token
+    username: ci-bot
+  - tokenSha256: 2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae
+    username: admin@cellery.io
+  - token: expired-token
+    username: admin
+    expiresAt: 2019-01-01T00:00:00Z
+`
+
+func TestValidateAccessTokenWithValidatorChain(t *testing.T) {
+	file, err := ioutil.TempFile("", "static-tokens")
+	if err != nil {
+		t.Fatal("Error while creating the static token file :", err)
+	}
+	defer os.Remove(file.Name())
+	_, err = file.WriteString(staticTokens)
+	if err != nil {
+		t.Fatal("Error while writing the static token file :", err)
+	}
+	file.Close()
+	err = os.Setenv(extension.StaticTokenFileEnvVar, file.Name())
+	if err != nil {
+		t.Fatal("Error setting up the environment", extension.StaticTokenFileEnvVar, ":", err)
+	}
+
+	logger := zap.NewExample().Sugar()
+	validators, err := newTokenValidators(" static ", nil, logger)
+	if err != nil {
+		t.Fatal("Error while creating the token validators :", err)
+	}
+	values := []struct {
+		token    string
+		username string
+		isValid  bool
+	}{
+		{"ci-token", "ci-bot", true},
+		// sha256 of "foo"
+		{"foo", "admin", true},
+		{"ci-token", "admin", false},
+		{"expired-token", "admin", false},
+		// tokens which are not supported by any of the validators fail authentication
+		{"unknown-token", "admin", false},
+	}
+	for _, value := range values {
+		tokenInfo, err := validateAccessToken(validators, value.token, value.username, logger, testExecId)
+		if err != nil {
+			t.Error("Error while validating the token", value.token, ":", err)
+		}
+		if isValid := tokenInfo != nil; isValid != value.isValid {
+			t.Error("Expected validity", value.isValid, "but found", isValid, "for the token", value.token)
+		}
+	}
+
+	_, err = newTokenValidators("static,unknown", nil, logger)
+	if err == nil {
+		t.Error("Unknown token validator is expected to be rejected")
+	}
+}
+
+func TestGetTokenValidatorsRetry(t *testing.T) {
+	err := os.Setenv(extension.TokenValidatorsEnvVar, extension.StaticTokenValidator)
+	if err != nil {
+		t.Fatal("Error setting up the environment", extension.TokenValidatorsEnvVar, ":", err)
+	}
+	defer os.Unsetenv(extension.TokenValidatorsEnvVar)
+	err = os.Setenv(extension.StaticTokenFileEnvVar, "/nonexistent/static-tokens.yaml")
+	if err != nil {
+		t.Fatal("Error setting up the environment", extension.StaticTokenFileEnvVar, ":", err)
+	}
+	defer os.Unsetenv(extension.StaticTokenFileEnvVar)
+	defer func() {
+		tokenValidators = nil
+	}()
+	logger := zap.NewExample().Sugar()
+	_, err = getTokenValidators(nil, logger)
+	if err == nil {
+		t.Fatal("Expected an error since the static token file does not exist")
+	}
+
+	file, err := ioutil.TempFile("", "static-tokens")
+	if err != nil {
+		t.Fatal("Error while creating the static token file :", err)
+	}
+	defer os.Remove(file.Name())
+	_, err = file.WriteString(staticTokens)
+	if err != nil {
+		t.Fatal("Error while writing the static token file :", err)
+	}
+	file.Close()
+	err = os.Setenv(extension.StaticTokenFileEnvVar, file.Name())
+	if err != nil {
+		t.Fatal("Error setting up the environment", extension.StaticTokenFileEnvVar, ":", err)
+	}
+	validators, err := getTokenValidators(nil, logger)
+	if err != nil || len(validators) != 1 {
+		t.Error("Expected the token validators to be created once the file is available but found error :", err)
+	}
+}
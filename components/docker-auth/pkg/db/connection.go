@@ -21,55 +21,293 @@ package db
 import (
 	"database/sql"
 	"fmt"
+	"net/url"
 	"os"
 	"strconv"
+	"sync"
 	"time"
 
-	_ "github.com/go-sql-driver/mysql"
 	"go.uber.org/zap"
 
 	"github.com/cellery-io/cellery-hub/components/docker-auth/pkg/extension"
 )
 
-func GetDbConnectionPool(logger *zap.SugaredLogger) (*sql.DB, error) {
-	dbDriver := extension.MysqlDriver
-	dbUser := os.Getenv(extension.MysqlUserEnvVar)
-	dbPass := os.Getenv(extension.MysqlPasswordEnvVar)
-	dbName := extension.DbName
-	host := os.Getenv(extension.MysqlHostEnvVar)
-	port := os.Getenv(extension.MysqlPortEnvVar)
-	dbPoolConfigurations, err := resolvePoolingConfigurations(logger)
+// SharedConnectionPool lazily creates the database connection pools of the primary and the replica endpoints, which
+// are shared by all the requests served by a plugin. The endpoints are pinged periodically if replicas are configured
+// so that the reads fail over to a healthy endpoint. The pools are discarded and re-created by the next request if
+// none of the endpoints can be reached.
+type SharedConnectionPool struct {
+	mutex           sync.Mutex
+	endpoints       []*Endpoint
+	stopHealthCheck chan struct{}
+}
+
+// Get returns the connection pool of the primary endpoint, creating the pools if they do not exist yet
+func (p *SharedConnectionPool) Get(logger *zap.SugaredLogger) (*sql.DB, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	err := p.initialize(logger)
 	if err != nil {
-		logger.Debugf("No db connection pooling configurations found : %s", err)
-		return nil, fmt.Errorf("failed to fetch db connection pooling configurations : %v", err)
+		return nil, err
+	}
+	primary := p.endpoints[0]
+	if !primary.isHealthy {
+		return nil, fmt.Errorf("primary db endpoint %s is unavailable", primary.Name)
 	}
+	return primary.Db, nil
+}
 
-	conn := fmt.Sprint(dbUser, ":", dbPass, "@tcp(", host, ":", port, ")/"+dbName)
-	logger.Debugf("Creating a new db connection pool: %v", conn)
+// GetReader returns the endpoint to be used for reads. The first healthy replica in the configured order is used so
+// that the reads stick to the same replica, failing over to the next replica and finally to the primary.
+func (p *SharedConnectionPool) GetReader(logger *zap.SugaredLogger) (*Endpoint, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	err := p.initialize(logger)
+	if err != nil {
+		return nil, err
+	}
+	for _, endpoint := range p.endpoints[1:] {
+		if endpoint.isHealthy {
+			return endpoint, nil
+		}
+	}
+	if p.endpoints[0].isHealthy {
+		return p.endpoints[0], nil
+	}
+	return nil, fmt.Errorf("none of the db endpoints are available")
+}
 
-	dbConnection, err := sql.Open(dbDriver, conn)
+// ResetIfUnhealthy pings the endpoints so that the next request fails over to a healthy endpoint. The pools are
+// discarded if none of the endpoints can be reached, so that fresh pools are created by the next request.
+func (p *SharedConnectionPool) ResetIfUnhealthy(logger *zap.SugaredLogger) {
+	if !p.checkHealth(logger) {
+		p.mutex.Lock()
+		defer p.mutex.Unlock()
+		logger.Debugf("Discarding the db connection pools since none of the endpoints are available")
+		p.close(logger)
+	}
+}
+
+// Close closes the connection pools if they have been created
+func (p *SharedConnectionPool) Close(logger *zap.SugaredLogger) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.endpoints != nil {
+		p.close(logger)
+		logger.Debugf("DB connection pool closed")
+	}
+}
 
+func (p *SharedConnectionPool) initialize(logger *zap.SugaredLogger) error {
+	if p.endpoints != nil {
+		return nil
+	}
+	endpoints, err := GetDbEndpoints(logger)
 	if err != nil {
-		return nil, fmt.Errorf("error occurred while establishing database connection pool "+
-			" : %v", err)
+		return err
+	}
+	p.endpoints = endpoints
+	if len(endpoints) > 1 {
+		interval, err := resolveHealthCheckInterval()
+		if err != nil {
+			p.close(logger)
+			return err
+		}
+		p.stopHealthCheck = make(chan struct{})
+		go p.runHealthCheck(interval, p.stopHealthCheck, logger)
 	}
+	return nil
+}
 
-	dbConnection.SetMaxOpenConns(dbPoolConfigurations[extension.MaxIdleConnectionsEnvVar])
-	dbConnection.SetMaxIdleConns(dbPoolConfigurations[extension.ConnectionMaxLifetimeEnvVar])
-	dbConnection.SetConnMaxLifetime(time.Minute * time.Duration(dbPoolConfigurations[extension.
-		ConnectionMaxLifetimeEnvVar]))
+func (p *SharedConnectionPool) runHealthCheck(interval time.Duration, stop chan struct{},
+	logger *zap.SugaredLogger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			p.checkHealth(logger)
+		}
+	}
+}
+
+// checkHealth pings the endpoints without holding the lock, since pinging an unreachable endpoint can take a while,
+// and returns whether any of the endpoints is healthy
+func (p *SharedConnectionPool) checkHealth(logger *zap.SugaredLogger) bool {
+	p.mutex.Lock()
+	endpoints := p.endpoints
+	p.mutex.Unlock()
+	if endpoints == nil {
+		return true
+	}
+	health := make([]bool, len(endpoints))
+	isAnyHealthy := false
+	for i, endpoint := range endpoints {
+		health[i] = endpoint.Db.Ping() == nil
+		isAnyHealthy = isAnyHealthy || health[i]
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if len(p.endpoints) == 0 || p.endpoints[0] != endpoints[0] {
+		// The pools were discarded while the endpoints were pinged
+		return true
+	}
+	for i, endpoint := range endpoints {
+		if endpoint.isHealthy != health[i] {
+			logger.Infof("The %s db endpoint %s is %s", endpoint.Role, endpoint.Name, healthState(health[i]))
+		}
+		endpoint.isHealthy = health[i]
+	}
+	return isAnyHealthy
+}
+
+func (p *SharedConnectionPool) close(logger *zap.SugaredLogger) {
+	if p.stopHealthCheck != nil {
+		close(p.stopHealthCheck)
+		p.stopHealthCheck = nil
+	}
+	closeEndpoints(p.endpoints, logger)
+	p.endpoints = nil
+}
+
+func healthState(isHealthy bool) string {
+	if isHealthy {
+		return "available"
+	}
+	return "unavailable"
+}
+
+// GetDbEndpoints creates the connection pools of the primary and the replica endpoints configured through the
+// environment. The primary is the first endpoint. An error is returned only if none of the endpoints can be reached.
+func GetDbEndpoints(logger *zap.SugaredLogger) ([]*Endpoint, error) {
+	dbDriver, err := extension.ResolveDbDriver()
+	if err != nil {
+		return nil, err
+	}
+	dbPoolConfigurations, err := resolvePoolingConfigurations(logger)
+	if err != nil {
+		logger.Debugf("No db connection pooling configurations found : %s", err)
+		return nil, fmt.Errorf("failed to fetch db connection pooling configurations : %v", err)
+	}
+	addresses, err := resolveEndpointAddresses(dbDriver)
+	if err != nil {
+		return nil, err
+	}
+	var endpoints []*Endpoint
+	isAnyHealthy := false
+	for i, address := range addresses {
+		endpoint := &Endpoint{Name: address, Role: replicaRole}
+		if i == 0 {
+			endpoint.Role = primaryRole
+		}
+		endpoint.Db, err = openDbConnection(dbDriver, address, logger)
+		if err != nil {
+			closeEndpoints(endpoints, logger)
+			return nil, err
+		}
+		configureConnectionPool(endpoint.Db, dbPoolConfigurations)
+		err = endpoint.Db.Ping()
+		if err != nil {
+			logger.Errorf("Error while pinging the %s db endpoint %s : %v", endpoint.Role, address, err)
+		}
+		endpoint.isHealthy = err == nil
+		isAnyHealthy = isAnyHealthy || endpoint.isHealthy
+		endpoints = append(endpoints, endpoint)
+	}
+	if !isAnyHealthy {
+		closeEndpoints(endpoints, logger)
+		return nil, fmt.Errorf("error occurred while pinging database connection pool " +
+			" : none of the db endpoints are available")
+	}
+	logger.Debugf("Ping successful. DB connection pools established for %d endpoints", len(endpoints))
+	return endpoints, nil
+}
+
+// GetDbConnection opens the Cellery Hub database on the primary endpoint without configuring the connection pool,
+// hence it is meant for short lived tools such as the migrate command
+func GetDbConnection(logger *zap.SugaredLogger) (*sql.DB, error) {
+	dbDriver, err := extension.ResolveDbDriver()
+	if err != nil {
+		return nil, err
+	}
+	addresses, err := resolveEndpointAddresses(dbDriver)
+	if err != nil {
+		return nil, err
+	}
+	dbConnection, err := openDbConnection(dbDriver, addresses[0], logger)
+	if err != nil {
+		return nil, err
+	}
 
 	err = dbConnection.Ping()
 	if err != nil {
+		closeConnectionPool(dbConnection, logger)
 		return nil, fmt.Errorf("error occurred while pinging database connection pool "+
 			" : %v", err)
 	}
 
-	logger.Debugf("Ping successful. DB connection pool established")
+	logger.Debugf("Ping successful")
 
 	return dbConnection, nil
 }
 
+func openDbConnection(dbDriver string, address string, logger *zap.SugaredLogger) (*sql.DB, error) {
+	connector, err := newDbConnector(dbDriver, address, logger)
+	if err != nil {
+		return nil, fmt.Errorf("error occurred while establishing database connection pool "+
+			" : %v", err)
+	}
+	logger.Debugf("Creating a new %s db connection pool: %v", dbDriver, connector.redactedDataSourceName())
+	return sql.OpenDB(connector), nil
+}
+
+// postgresSslParameters are the SSL parameters of the PostgreSQL connections along with the environment variables
+// they are configured through. The certificate files are read by the driver on each connection, hence the rotated
+// files are used by the new connections.
+var postgresSslParameters = map[string]string{
+	"sslmode":     extension.PostgresSslModeEnvVar,
+	"sslrootcert": extension.PostgresSslRootCertFileEnvVar,
+	"sslcert":     extension.PostgresSslCertFileEnvVar,
+	"sslkey":      extension.PostgresSslKeyFileEnvVar,
+}
+
+// resolveDataSourceName builds the data source name of the Cellery Hub database on the host:port address for the
+// driver. The TLS configuration name is only used by MySQL, while PostgreSQL uses the SSL parameters.
+func resolveDataSourceName(dbDriver string, address string, user string, password string,
+	tlsConfigName string) string {
+	if dbDriver == extension.PostgresDriver {
+		dataSourceName := url.URL{
+			Scheme: extension.PostgresDriver,
+			User:   url.UserPassword(user, password),
+			Host:   address,
+			Path:   extension.PostgresDbName,
+		}
+		query := url.Values{}
+		for parameter, envVar := range postgresSslParameters {
+			if value := os.Getenv(envVar); len(value) > 0 {
+				query.Set(parameter, value)
+			}
+		}
+		dataSourceName.RawQuery = query.Encode()
+		return dataSourceName.String()
+	}
+	// The DATETIME columns such as the expiry of the personal access tokens are scanned into time.Time
+	dataSourceName := fmt.Sprint(user, ":", password, "@tcp(", address, ")/"+extension.DbName, "?parseTime=true")
+	if len(tlsConfigName) > 0 {
+		dataSourceName += "&tls=" + url.QueryEscape(tlsConfigName)
+	}
+	return dataSourceName
+}
+
+func configureConnectionPool(dbConnection *sql.DB, dbPoolConfigurations map[string]int) {
+	dbConnection.SetMaxOpenConns(dbPoolConfigurations[extension.MaxOpenConnectionsEnvVar])
+	dbConnection.SetMaxIdleConns(dbPoolConfigurations[extension.MaxIdleConnectionsEnvVar])
+	dbConnection.SetConnMaxLifetime(time.Minute * time.Duration(dbPoolConfigurations[extension.
+		ConnectionMaxLifetimeEnvVar]))
+}
+
 func resolvePoolingConfigurations(logger *zap.SugaredLogger) (map[string]int, error) {
 	m := make(map[string]int)
 
@@ -96,3 +334,20 @@ func resolvePoolingConfigurations(logger *zap.SugaredLogger) (map[string]int, er
 
 	return m, nil
 }
+
+func closeEndpoints(endpoints []*Endpoint, logger *zap.SugaredLogger) {
+	for _, endpoint := range endpoints {
+		closeConnectionPool(endpoint.Db, logger)
+	}
+}
+
+func closeConnectionPool(dbConnectionPool *sql.DB, logger *zap.SugaredLogger) {
+	err := extension.ReleasePreparedStatements(dbConnectionPool)
+	if err != nil {
+		logger.Errorf("Error while releasing the statements of the db connection pool : %v", err)
+	}
+	err = dbConnectionPool.Close()
+	if err != nil {
+		logger.Errorf("Error while closing the db connection pool : %v", err)
+	}
+}
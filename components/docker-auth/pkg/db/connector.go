@@ -0,0 +1,86 @@
+/*
+ * Copyright (c) 2019 WSO2 Inc. (http:www.wso2.org) All Rights Reserved.
+ *
+ * WSO2 Inc. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http:www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+
+	"github.com/cellery-io/cellery-hub/components/docker-auth/pkg/extension"
+)
+
+const redactedPassword = "xxxxx"
+
+// dbConnector creates the connections to an endpoint with the credentials read at the time of connecting, hence the
+// new connections of a pool use the rotated credentials without re-creating the pool
+type dbConnector struct {
+	dbDriver      string
+	address       string
+	tlsConfigName string
+	credentials   *credentials
+	logger        *zap.SugaredLogger
+}
+
+func newDbConnector(dbDriver string, address string, logger *zap.SugaredLogger) (*dbConnector, error) {
+	connector := &dbConnector{dbDriver: dbDriver, address: address, credentials: resolveCredentials(dbDriver),
+		logger: logger}
+	if dbDriver == extension.MysqlDriver {
+		tlsConfigName, err := resolveMysqlTlsConfig(logger)
+		if err != nil {
+			return nil, err
+		}
+		connector.tlsConfigName = tlsConfigName
+	}
+	return connector, nil
+}
+
+func (c *dbConnector) Connect(context.Context) (driver.Conn, error) {
+	user, password, err := c.credentials.get(c.logger)
+	if err != nil {
+		return nil, err
+	}
+	if len(c.tlsConfigName) > 0 {
+		// The previously registered configuration is used until the rotated CA certificates can be loaded
+		if err := mysqlTls.refresh(c.logger); err != nil {
+			c.logger.Errorf("Error while loading the rotated MySQL CA certificates : %v", err)
+		}
+	}
+	return c.Driver().Open(resolveDataSourceName(c.dbDriver, c.address, user, password, c.tlsConfigName))
+}
+
+func (c *dbConnector) Driver() driver.Driver {
+	if c.dbDriver == extension.PostgresDriver {
+		return &pq.Driver{}
+	}
+	return mysql.MySQLDriver{}
+}
+
+// redactedDataSourceName returns the data source name of the endpoint with the password redacted, which can be
+// logged safely
+func (c *dbConnector) redactedDataSourceName() string {
+	user, _, err := c.credentials.get(c.logger)
+	if err != nil {
+		user = ""
+	}
+	return resolveDataSourceName(c.dbDriver, c.address, user, redactedPassword, c.tlsConfigName)
+}
@@ -0,0 +1,274 @@
+/*
+ * Copyright (c) 2019 WSO2 Inc. (http:www.wso2.org) All Rights Reserved.
+ *
+ * WSO2 Inc. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http:www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package db
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"go.uber.org/zap"
+
+	"github.com/cellery-io/cellery-hub/components/docker-auth/pkg/extension"
+)
+
+// secretFile is a file mounted from a secret such as a Kubernetes secret. The file is read again when it changes,
+// hence the secret can be rotated without restarting the plugin.
+type secretFile struct {
+	path    string
+	mutex   sync.Mutex
+	modTime time.Time
+	size    int64
+	content []byte
+}
+
+// read returns the content of the file and whether it changed since the previous read
+func (f *secretFile) read() ([]byte, bool, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return nil, false, fmt.Errorf("error while reading the secret file %s : %v", f.path, err)
+	}
+	if f.content != nil && info.ModTime().Equal(f.modTime) && info.Size() == f.size {
+		return f.content, false, nil
+	}
+	content, err := ioutil.ReadFile(f.path)
+	if err != nil {
+		return nil, false, fmt.Errorf("error while reading the secret file %s : %v", f.path, err)
+	}
+	isChanged := f.content != nil && string(content) != string(f.content)
+	f.modTime, f.size, f.content = info.ModTime(), info.Size(), content
+	return content, isChanged, nil
+}
+
+// credentials are the user name and the password of the database. Each of them is read from the file configured
+// through the environment if available, and otherwise from the environment variable itself.
+type credentials struct {
+	user         string
+	password     string
+	userFile     *secretFile
+	passwordFile *secretFile
+}
+
+func resolveCredentials(dbDriver string) *credentials {
+	userEnvVar, passwordEnvVar := extension.MysqlUserEnvVar, extension.MysqlPasswordEnvVar
+	userFileEnvVar, passwordFileEnvVar := extension.MysqlUserFileEnvVar, extension.MysqlPasswordFileEnvVar
+	if dbDriver == extension.PostgresDriver {
+		userEnvVar, passwordEnvVar = extension.PostgresUserEnvVar, extension.PostgresPasswordEnvVar
+		userFileEnvVar, passwordFileEnvVar = extension.PostgresUserFileEnvVar, extension.PostgresPasswordFileEnvVar
+	}
+	dbCredentials := &credentials{user: os.Getenv(userEnvVar), password: os.Getenv(passwordEnvVar)}
+	if file := os.Getenv(userFileEnvVar); len(file) > 0 {
+		dbCredentials.userFile = &secretFile{path: file}
+	}
+	if file := os.Getenv(passwordFileEnvVar); len(file) > 0 {
+		dbCredentials.passwordFile = &secretFile{path: file}
+	}
+	return dbCredentials
+}
+
+// get returns the current user name and password, reading the files again if they have been rotated
+func (c *credentials) get(logger *zap.SugaredLogger) (string, string, error) {
+	user, err := readSecret(c.user, c.userFile, logger)
+	if err != nil {
+		return "", "", err
+	}
+	password, err := readSecret(c.password, c.passwordFile, logger)
+	if err != nil {
+		return "", "", err
+	}
+	return user, password, nil
+}
+
+func readSecret(value string, file *secretFile, logger *zap.SugaredLogger) (string, error) {
+	if file == nil {
+		return value, nil
+	}
+	content, isChanged, err := file.read()
+	if err != nil {
+		return "", err
+	}
+	if isChanged {
+		logger.Infof("DB credentials in %s have been rotated, new connections will use the rotated credentials",
+			file.path)
+	}
+	return strings.TrimSpace(string(content)), nil
+}
+
+// resolveMysqlTlsConfig registers the TLS configuration of the MySQL connections if TLS is enabled through the
+// environment and returns its name. TLS is enabled if requested explicitly or if any of the certificate files are
+// configured.
+func resolveMysqlTlsConfig(logger *zap.SugaredLogger) (string, error) {
+	caCertFile := os.Getenv(extension.MysqlCaCertFileEnvVar)
+	clientCertFile := os.Getenv(extension.MysqlClientCertFileEnvVar)
+	clientKeyFile := os.Getenv(extension.MysqlClientKeyFileEnvVar)
+	isTlsEnabled := false
+	if value := os.Getenv(extension.MysqlTlsEnvVar); len(value) > 0 {
+		var err error
+		isTlsEnabled, err = strconv.ParseBool(value)
+		if err != nil {
+			return "", fmt.Errorf("invalid value %s for %s : %v", value, extension.MysqlTlsEnvVar, err)
+		}
+	}
+	if !isTlsEnabled && len(caCertFile) == 0 && len(clientCertFile) == 0 && len(clientKeyFile) == 0 {
+		return "", nil
+	}
+	return mysqlTls.register(caCertFile, clientCertFile, clientKeyFile, logger)
+}
+
+// mysqlTlsRegistration is the TLS configuration registered with the MySQL driver. The configuration is shared by
+// all the endpoints, hence it is registered once and registered again only if the configured files or the CA
+// certificates change. The CA certificate file is checked by the connectors before each new connection, while the
+// client certificate is loaded again by the TLS handshake when its files change, hence both can be rotated without
+// re-creating the connection pools.
+type mysqlTlsRegistration struct {
+	mutex          sync.Mutex
+	caCertPath     string
+	clientCertPath string
+	clientKeyPath  string
+	caCertFile     *secretFile
+	clientCert     *clientCertificate
+	isRegistered   bool
+	// registerTlsConfig registers the configuration with the driver
+	registerTlsConfig func(key string, config *tls.Config) error
+}
+
+var mysqlTls = &mysqlTlsRegistration{registerTlsConfig: mysql.RegisterTLSConfig}
+
+// refresh registers the configuration again if the CA certificates have been rotated since it was registered
+func (r *mysqlTlsRegistration) refresh(logger *zap.SugaredLogger) error {
+	r.mutex.Lock()
+	caCertFile, clientCertFile, clientKeyFile := r.caCertPath, r.clientCertPath, r.clientKeyPath
+	r.mutex.Unlock()
+	_, err := r.register(caCertFile, clientCertFile, clientKeyFile, logger)
+	return err
+}
+
+func (r *mysqlTlsRegistration) register(caCertFile string, clientCertFile string, clientKeyFile string,
+	logger *zap.SugaredLogger) (string, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.caCertPath != caCertFile || r.clientCertPath != clientCertFile || r.clientKeyPath != clientKeyFile {
+		r.caCertPath, r.clientCertPath, r.clientKeyPath = caCertFile, clientCertFile, clientKeyFile
+		r.caCertFile, r.clientCert, r.isRegistered = nil, nil, false
+		if len(caCertFile) > 0 {
+			r.caCertFile = &secretFile{path: caCertFile}
+		}
+		if len(clientCertFile) > 0 || len(clientKeyFile) > 0 {
+			r.clientCert = &clientCertificate{certFile: &secretFile{path: clientCertFile},
+				keyFile: &secretFile{path: clientKeyFile}}
+		}
+	}
+	var caCerts []byte
+	isCaCertChanged := false
+	if r.caCertFile != nil {
+		var err error
+		caCerts, isCaCertChanged, err = r.caCertFile.read()
+		if err != nil {
+			r.isRegistered = false
+			return "", fmt.Errorf("error reading the MySQL CA certificate file : %v", err)
+		}
+	}
+	if r.isRegistered && !isCaCertChanged {
+		return extension.MysqlTlsConfigName, nil
+	}
+	// The configuration is built again by the next call unless it is registered
+	r.isRegistered = false
+	tlsConfig := &tls.Config{}
+	if r.caCertFile != nil {
+		rootCAs := x509.NewCertPool()
+		if !rootCAs.AppendCertsFromPEM(caCerts) {
+			return "", fmt.Errorf("no certificates found in the MySQL CA certificate file %s", caCertFile)
+		}
+		tlsConfig.RootCAs = rootCAs
+		logger.Debugf("Trusting the MySQL CA certificates in %s", caCertFile)
+	}
+	if r.clientCert != nil {
+		clientCert := r.clientCert
+		_, err := clientCert.get(logger)
+		if err != nil {
+			return "", err
+		}
+		tlsConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return clientCert.get(logger)
+		}
+		logger.Debugf("Using the client certificate %s for mutual TLS with MySQL", clientCertFile)
+	}
+	err := r.registerTlsConfig(extension.MysqlTlsConfigName, tlsConfig)
+	if err != nil {
+		return "", fmt.Errorf("error while registering the MySQL TLS configuration : %v", err)
+	}
+	if isCaCertChanged {
+		logger.Infof("MySQL CA certificates in %s have been rotated, new connections will trust the rotated "+
+			"certificates", caCertFile)
+	}
+	r.isRegistered = true
+	return extension.MysqlTlsConfigName, nil
+}
+
+// clientCertificate is the client certificate used for mutual TLS, which is loaded again when the files change
+type clientCertificate struct {
+	certFile    *secretFile
+	keyFile     *secretFile
+	mutex       sync.Mutex
+	cert        []byte
+	key         []byte
+	certificate *tls.Certificate
+}
+
+// get returns the client certificate, loading it again if the files have changed. The previous certificate is kept
+// if the files cannot be loaded, since the certificate and the key may not have been rotated together yet.
+func (c *clientCertificate) get(logger *zap.SugaredLogger) (*tls.Certificate, error) {
+	cert, _, err := c.certFile.read()
+	if err != nil {
+		return nil, err
+	}
+	key, _, err := c.keyFile.read()
+	if err != nil {
+		return nil, err
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.certificate != nil && bytes.Equal(cert, c.cert) && bytes.Equal(key, c.key) {
+		return c.certificate, nil
+	}
+	certificate, err := tls.X509KeyPair(cert, key)
+	if err != nil {
+		if c.certificate != nil {
+			logger.Debugf("Using the previous MySQL client certificate since the rotated certificate cannot be "+
+				"loaded yet : %v", err)
+			return c.certificate, nil
+		}
+		return nil, fmt.Errorf("error loading the client certificate for MySQL : %v", err)
+	}
+	if c.certificate != nil {
+		logger.Infof("MySQL client certificate %s has been rotated", c.certFile.path)
+	}
+	c.cert, c.key, c.certificate = cert, key, &certificate
+	return c.certificate, nil
+}
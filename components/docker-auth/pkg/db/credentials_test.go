@@ -0,0 +1,292 @@
+/*
+ * Copyright (c) 2019 WSO2 Inc. (http:www.wso2.org) All Rights Reserved.
+ *
+ * WSO2 Inc. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http:www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package db
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/cellery-io/cellery-hub/components/docker-auth/pkg/extension"
+)
+
+func writeFile(t *testing.T, file string, content []byte, modTime time.Time) {
+	err := ioutil.WriteFile(file, content, 0600)
+	if err != nil {
+		t.Fatal("Error while writing the file", file, ":", err)
+	}
+	// The modification time is set explicitly since the file may be rewritten within the timestamp resolution
+	err = os.Chtimes(file, modTime, modTime)
+	if err != nil {
+		t.Fatal("Error while setting the modification time of", file, ":", err)
+	}
+}
+
+func writeCertificate(t *testing.T, dir string, name string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal("Error while generating the key :", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+		IsCA:         true,
+		KeyUsage:     x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal("Error while creating the certificate :", err)
+	}
+	keyBytes, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal("Error while encoding the key :", err)
+	}
+	certFile, keyFile := filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key")
+	modTime := time.Now().Add(-time.Minute)
+	writeFile(t, certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert}), modTime)
+	writeFile(t, keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyBytes}), modTime)
+	return certFile, keyFile
+}
+
+func TestCredentialFileRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "credentials")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	passwordFile := filepath.Join(dir, "password")
+	writeFile(t, passwordFile, []byte("secret\n"), time.Now().Add(-time.Minute))
+	envVars := map[string]string{
+		extension.MysqlUserEnvVar:         "celleryhub",
+		extension.MysqlPasswordEnvVar:     "ignored",
+		extension.MysqlPasswordFileEnvVar: passwordFile,
+	}
+	for key, value := range envVars {
+		err := os.Setenv(key, value)
+		if err != nil {
+			t.Fatal("Error setting up the environment", key, ":", err)
+		}
+		defer os.Unsetenv(key)
+	}
+	logger := zap.NewExample().Sugar()
+	dbCredentials := resolveCredentials(extension.MysqlDriver)
+	user, password, err := dbCredentials.get(logger)
+	if err != nil || user != "celleryhub" || password != "secret" {
+		t.Error("Expected the password to be read from the file but found", user, password, err)
+	}
+
+	writeFile(t, passwordFile, []byte("rotated"), time.Now())
+	_, password, err = dbCredentials.get(logger)
+	if err != nil || password != "rotated" {
+		t.Error("Expected the rotated password but found", password, err)
+	}
+
+	err = os.Remove(passwordFile)
+	if err != nil {
+		t.Fatal("Error while removing the password file :", err)
+	}
+	_, _, err = dbCredentials.get(logger)
+	if err == nil {
+		t.Error("Expected an error when the password file is missing")
+	}
+}
+
+func TestRedactedDataSourceName(t *testing.T) {
+	envVars := map[string]string{
+		extension.MysqlUserEnvVar:        "celleryhub",
+		extension.MysqlPasswordEnvVar:    "secret",
+		extension.PostgresUserEnvVar:     "celleryhub",
+		extension.PostgresPasswordEnvVar: "secret",
+	}
+	for key, value := range envVars {
+		err := os.Setenv(key, value)
+		if err != nil {
+			t.Fatal("Error setting up the environment", key, ":", err)
+		}
+		defer os.Unsetenv(key)
+	}
+	logger := zap.NewExample().Sugar()
+	for _, dbDriver := range []string{extension.MysqlDriver, extension.PostgresDriver} {
+		connector, err := newDbConnector(dbDriver, "localhost:1", logger)
+		if err != nil {
+			t.Fatal("Error while creating the connector :", err)
+		}
+		dataSourceName := connector.redactedDataSourceName()
+		if strings.Contains(dataSourceName, "secret") || !strings.Contains(dataSourceName, redactedPassword) {
+			t.Error("Expected the password to be redacted but found", dataSourceName)
+		}
+	}
+}
+
+func TestMysqlTlsConfig(t *testing.T) {
+	defer os.Unsetenv(extension.MysqlTlsEnvVar)
+	logger := zap.NewExample().Sugar()
+	tlsConfigName, err := resolveMysqlTlsConfig(logger)
+	if err != nil || tlsConfigName != "" {
+		t.Error("Expected TLS to be disabled by default but found", tlsConfigName, err)
+	}
+	err = os.Setenv(extension.MysqlTlsEnvVar, "yes")
+	if err != nil {
+		t.Fatal("Error setting up the environment :", err)
+	}
+	_, err = resolveMysqlTlsConfig(logger)
+	if err == nil {
+		t.Error("Expected an error for an invalid TLS flag")
+	}
+
+	dir, err := ioutil.TempDir("", "tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	caCertFile, _ := writeCertificate(t, dir, "ca")
+	clientCertFile, clientKeyFile := writeCertificate(t, dir, "client")
+	envVars := map[string]string{
+		extension.MysqlTlsEnvVar:            "true",
+		extension.MysqlCaCertFileEnvVar:     caCertFile,
+		extension.MysqlClientCertFileEnvVar: clientCertFile,
+		extension.MysqlClientKeyFileEnvVar:  clientKeyFile,
+	}
+	for key, value := range envVars {
+		err := os.Setenv(key, value)
+		if err != nil {
+			t.Fatal("Error setting up the environment", key, ":", err)
+		}
+		defer os.Unsetenv(key)
+	}
+	tlsConfigName, err = resolveMysqlTlsConfig(logger)
+	if err != nil || tlsConfigName != extension.MysqlTlsConfigName {
+		t.Error("Expected the TLS configuration to be registered but found", tlsConfigName, err)
+	}
+
+	writeFile(t, caCertFile, []byte("not a certificate"), time.Now())
+	_, err = resolveMysqlTlsConfig(logger)
+	if err == nil {
+		t.Error("Expected an error for a CA certificate file without certificates")
+	}
+}
+
+func TestMysqlTlsRegistration(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	caCertFile, _ := writeCertificate(t, dir, "ca")
+	clientCertFile, clientKeyFile := writeCertificate(t, dir, "client")
+	registrations := 0
+	registration := &mysqlTlsRegistration{registerTlsConfig: func(string, *tls.Config) error {
+		registrations++
+		return nil
+	}}
+	logger := zap.NewExample().Sugar()
+	for i := 0; i < 3; i++ {
+		_, err = registration.register(caCertFile, clientCertFile, clientKeyFile, logger)
+		if err != nil {
+			t.Fatal("Error while registering the TLS configuration :", err)
+		}
+	}
+	if registrations != 1 {
+		t.Error("Expected the TLS configuration to be registered once for all the endpoints but found",
+			registrations, "registrations")
+	}
+
+	// the client certificate is rotated without registering the configuration again
+	writeCertificate(t, dir, "client")
+	_, err = registration.register(caCertFile, clientCertFile, clientKeyFile, logger)
+	if err != nil || registrations != 1 {
+		t.Error("Expected the rotated client certificate to be used without registering again but found",
+			registrations, "registrations", err)
+	}
+	// rotated CA certificates are registered again when the connectors refresh the configuration
+	err = registration.refresh(logger)
+	if err != nil || registrations != 1 {
+		t.Error("Expected the configuration not to be registered again without changes but found",
+			registrations, "registrations", err)
+	}
+	writeCertificate(t, dir, "ca")
+	err = registration.refresh(logger)
+	if err != nil || registrations != 2 {
+		t.Error("Expected the configuration to be registered again for the rotated CA certificates but found",
+			registrations, "registrations", err)
+	}
+	_, err = registration.register("", clientCertFile, clientKeyFile, logger)
+	if err != nil || registrations != 3 {
+		t.Error("Expected the configuration to be registered again for the changed files but found",
+			registrations, "registrations", err)
+	}
+}
+
+func TestClientCertificateRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	certFile, keyFile := writeCertificate(t, dir, "client")
+	logger := zap.NewExample().Sugar()
+	clientCert := &clientCertificate{certFile: &secretFile{path: certFile}, keyFile: &secretFile{path: keyFile}}
+	certificate, err := clientCert.get(logger)
+	if err != nil {
+		t.Fatal("Error while loading the client certificate :", err)
+	}
+
+	// Until the key is rotated as well, the previous certificate is used
+	rotatedDir := filepath.Join(dir, "rotated")
+	err = os.Mkdir(rotatedDir, 0700)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotatedCertFile, rotatedKeyFile := writeCertificate(t, rotatedDir, "client")
+	rotatedCert, err := ioutil.ReadFile(rotatedCertFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, certFile, rotatedCert, time.Now())
+	current, err := clientCert.get(logger)
+	if err != nil || current != certificate {
+		t.Error("Expected the previous client certificate to be used but found error :", err)
+	}
+
+	rotatedKey, err := ioutil.ReadFile(rotatedKeyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, keyFile, rotatedKey, time.Now())
+	current, err = clientCert.get(logger)
+	if err != nil || current == certificate {
+		t.Error("Expected the rotated client certificate to be used but found error :", err)
+	}
+}
@@ -0,0 +1,79 @@
+/*
+ * Copyright (c) 2019 WSO2 Inc. (http:www.wso2.org) All Rights Reserved.
+ *
+ * WSO2 Inc. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http:www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cellery-io/cellery-hub/components/docker-auth/pkg/extension"
+)
+
+const primaryRole = "primary"
+const replicaRole = "replica"
+
+// Endpoint is a database server holding the Cellery Hub database. The primary endpoint is the server configured
+// through the host and port of the driver while the replica endpoints are read-only copies of the primary.
+type Endpoint struct {
+	Name      string
+	Role      string
+	Db        *sql.DB
+	isHealthy bool
+}
+
+// resolveEndpointAddresses returns the host:port addresses of the primary followed by the replicas listed in the
+// environment. The port of the primary is used for the replicas listed without a port.
+func resolveEndpointAddresses(dbDriver string) ([]string, error) {
+	host, port := os.Getenv(extension.MysqlHostEnvVar), os.Getenv(extension.MysqlPortEnvVar)
+	if dbDriver == extension.PostgresDriver {
+		host, port = os.Getenv(extension.PostgresHostEnvVar), os.Getenv(extension.PostgresPortEnvVar)
+	}
+	addresses := []string{net.JoinHostPort(host, port)}
+	for _, replica := range strings.Split(os.Getenv(extension.DbReplicaHostsEnvVar), ",") {
+		replica = strings.TrimSpace(replica)
+		if replica == "" {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(replica); err != nil {
+			if strings.Contains(replica, ":") && !strings.HasPrefix(replica, "[") {
+				return nil, fmt.Errorf("invalid db replica host %s : %v", replica, err)
+			}
+			replica = net.JoinHostPort(strings.Trim(replica, "[]"), port)
+		}
+		addresses = append(addresses, replica)
+	}
+	return addresses, nil
+}
+
+func resolveHealthCheckInterval() (time.Duration, error) {
+	interval := extension.DefaultDbHealthCheckInterval
+	if value := os.Getenv(extension.DbHealthCheckIntervalEnvVar); value != "" {
+		var err error
+		interval, err = strconv.Atoi(value)
+		if err != nil || interval <= 0 {
+			return 0, fmt.Errorf("invalid db health check interval %s", value)
+		}
+	}
+	return time.Duration(interval) * time.Second, nil
+}
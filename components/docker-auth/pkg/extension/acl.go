@@ -27,69 +27,166 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/cesanta/docker_auth/auth_server/api"
-	_ "github.com/go-sql-driver/mysql"
 )
 
-func IsUserAuthorized(db *sql.DB, actions []string, username string, repository string, labels api.Labels,
-	logger *zap.SugaredLogger, execId string) (bool, error) {
+// IsUserAuthorized evaluates each of the requested actions separately and returns the decision which holds the
+// subset of actions the user is allowed to perform on the repository along with the reasons for the denied actions
+func IsUserAuthorized(store ACLStore, actions []string, username string, repository string, labels api.Labels,
+	logger *zap.SugaredLogger, execId string) (*Decision, error) {
 
 	logger.Debugf("[%s] Required actions for the username are :%s", execId, actions)
 	logger.Debugf("[%s] Received labels are :%s", execId, labels)
 
-	isPullOnly := false
-	isPullNDeleteAction := false
-	isPushAction := false
-	if len(actions) == 1 && actions[0] == pullAction {
-		logger.Debugf("[%s] Received a request for pull only action", execId)
-		isPullOnly = true
-	} else if len(actions) == 2 {
-		if actions[0] == pullAction && actions[1] == pushAction {
-			logger.Debugf("[%s] Received a request for push action", execId)
-			isPushAction = true
-		} else if actions[0] == deleteAction && actions[1] == pullAction {
-			logger.Debugf("[%s] Received a request for pull and delete actions", execId)
-			isPullNDeleteAction = true
-		}
+	requiredActions, unsupportedActions := ResolveRequiredActions(actions)
+	if len(unsupportedActions) > 0 {
+		logger.Debugf("[%s] Received unrecognized actions %s", execId, unsupportedActions)
+	}
+	outcomes := make(map[string]ActionOutcome)
+	if len(requiredActions) == 0 {
+		logger.Debugf("[%s] Not received any supported action", execId)
+		return NewDecision(username, repository, actions, outcomes), nil
 	}
 
 	logger.Debugf("[%s] Label map length : %d", execId, len(labels))
-	if len(labels) < 1 {
+	if len(labels) < 1 || len(labels[AuthSuccessLabel]) < 1 {
 		logger.Debugf("[%s] Not received any label", execId)
-		return false, nil
+		for _, action := range requiredActions {
+			outcomes[action] = ActionOutcome{Reason: ReasonUnauthenticated}
+		}
+		return NewDecision(username, repository, actions, outcomes), nil
 	}
-
-	if labels["isAuthSuccess"][0] == "true" {
+	isAuthenticated := labels[AuthSuccessLabel][0] == "true"
+	if isAuthenticated {
 		logger.Debugf("[%s] Validating access for authenticated user", execId)
 	} else {
-		if isPullOnly {
-			logger.Debugf("[%s] Validating access for unauthenticated user for pull action", execId)
-		} else {
-			logger.Debugf("[%s] Denying access for unauthenticated user for push/delete actions", execId)
-			return false, nil
-		}
+		logger.Debugf("[%s] Validating access for unauthenticated user", execId)
 	}
 
-	organization, image, err := getOrganizationAndImage(repository, logger, execId)
+	organization, image, err := GetOrganizationAndImage(repository, logger, execId)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 	logger.Debugf("[%s] Image name is declared as :%s", execId, image)
-	if isPullOnly {
+	aclUser := username
+	if !isAuthenticated {
+		// The account of an unauthenticated request is not verified, hence its roles are not used
+		aclUser = ""
+	}
+	request := newAclRequest(store, aclUser, organization, image)
+	for _, action := range requiredActions {
+		isAuthorized, reason, err := isAuthorizedForAction(request, action, isAuthenticated, labels, logger,
+			execId)
+		if err != nil {
+			return nil, err
+		}
+		outcomes[action] = ActionOutcome{IsGranted: isAuthorized, Reason: reason}
+	}
+	return NewDecision(username, repository, actions, outcomes), nil
+}
+
+// ResolveGrantedActions returns the distinct requested actions which are granted, in the order they were requested.
+// The wildcard action is granted only if all the supported actions are granted.
+func ResolveGrantedActions(actions []string, isActionGranted map[string]bool) []string {
+	var grantedActions []string
+	for _, action := range actions {
+		isGranted := isActionGranted[action]
+		if action == wildcardAction {
+			isGranted = true
+			for _, supportedAction := range supportedActions {
+				isGranted = isGranted && isActionGranted[supportedAction]
+			}
+		}
+		if isGranted && !containsString(grantedActions, action) {
+			grantedActions = append(grantedActions, action)
+		}
+	}
+	return grantedActions
+}
+
+// ResolveRequiredActions converts the requested actions into the distinct set of supported actions to be
+// authorized, irrespective of the order they were requested in. The wildcard action requires all the supported
+// actions. Actions which are not supported are returned separately.
+func ResolveRequiredActions(actions []string) ([]string, []string) {
+	requestedActions := make(map[string]bool)
+	var unsupportedActions []string
+	for _, action := range actions {
+		if action == wildcardAction {
+			for _, supportedAction := range supportedActions {
+				requestedActions[supportedAction] = true
+			}
+		} else if containsString(supportedActions, action) {
+			requestedActions[action] = true
+		} else {
+			unsupportedActions = append(unsupportedActions, action)
+		}
+	}
+	var requiredActions []string
+	for _, supportedAction := range supportedActions {
+		if requestedActions[supportedAction] {
+			requiredActions = append(requiredActions, supportedAction)
+		}
+	}
+	return requiredActions, unsupportedActions
+}
+
+// isAuthorizedForAction evaluates a single action independently of the other requested actions. The reason is
+// returned if the action is denied.
+func isAuthorizedForAction(request *aclRequest, action string, isAuthenticated bool, labels api.Labels,
+	logger *zap.SugaredLogger, execId string) (bool, ReasonCode, error) {
+	username := request.user
+	organization := request.organization
+	image := request.image
+	if !isAuthenticated {
+		if action != pullAction {
+			logger.Debugf("[%s] Denying access for unauthenticated user for %s action", execId, action)
+			return false, ReasonUnauthenticated, nil
+		}
+		return isAnonymousPullAuthorized(request, logger, execId)
+	}
+	if robotOrganizations, isRobot := labels[RobotOrganizationLabel]; isRobot {
+		if !isRobotAuthorized(action, username, organization, robotOrganizations, labels[RobotActionsLabel],
+			logger, execId) {
+			return false, ReasonRobotNotAllowed, nil
+		}
+		if action == pushAction {
+			return isPushAllowed(request, logger, execId)
+		}
+		return true, "", nil
+	}
+	if tokenScopes, isScopedToken := labels[TokenScopesLabel]; isScopedToken {
+		if !isWithinTokenScope(action, organization, tokenScopes, labels[TokenOrganizationLabel], logger,
+			execId) {
+			return false, ReasonOutOfTokenScope, nil
+		}
+	}
+	isAuthorized, reason, isCached := aclDecisions.getDecision(request.store, username, organization, image, action,
+		logger, execId)
+	if isCached {
+		return isAuthorized, reason, nil
+	}
+	var err error
+	switch action {
+	case pullAction:
 		logger.Debugf("[%s] Received a pulling task", execId)
-		return isAuthorizedToPull(db, username, organization, image, logger, execId)
-	} else if isPushAction {
+		isAuthorized, reason, err = isAuthorizedToPull(request, logger, execId)
+	case pushAction:
 		logger.Debugf("[%s] Received a pushing task", execId)
-		return isAuthorizedToPush(db, username, organization, logger, execId)
-	} else if isPullNDeleteAction {
+		isAuthorized, reason, err = isAuthorizedToPush(request, logger, execId)
+	case deleteAction:
 		logger.Debugf("[%s] Received a deleting task", execId)
-		return isAuthorizedToDelete(db, username, organization, logger, execId)
-	} else {
+		isAuthorized, reason, err = isRoleAuthorized(request, deleteAction, logger, execId)
+	default:
 		logger.Debugf("[%s] Received an unrecognized task", execId)
-		return false, fmt.Errorf("unrecognized task requested")
+		reason = ReasonUnsupportedAction
+	}
+	if err != nil {
+		return false, "", err
 	}
+	aclDecisions.putDecision(username, organization, image, action, isAuthorized, reason)
+	return isAuthorized, reason, nil
 }
 
-func getOrganizationAndImage(imageFullName string, logger *zap.SugaredLogger, execId string) (string, string, error) {
+func GetOrganizationAndImage(imageFullName string, logger *zap.SugaredLogger, execId string) (string, string, error) {
 	tokens := strings.Split(imageFullName, "/")
 	logger.Debugf("[%s] Organization and image info: %s", execId, tokens)
 	if len(tokens) == 2 {
@@ -100,140 +197,176 @@ func getOrganizationAndImage(imageFullName string, logger *zap.SugaredLogger, ex
 	}
 }
 
-func getImageVisibility(db *sql.DB, image string, organization string, logger *zap.SugaredLogger,
-	execId string) (string, error) {
-	logger.Debugf("[%s] Retrieving image visibility for image %s in organization %s", execId,
-		image, organization)
-	var visibility = ""
-	results, err := db.Query(getVisibilityQuery, image, organization)
-	defer func() {
-		closeResultSet(results, "getImageVisibility", logger, execId)
-	}()
+// isAuthorizedToPull checks whether the user is allowed to pull the image. The reason is returned if the user
+// is not allowed.
+func isAuthorizedToPull(request *aclRequest, logger *zap.SugaredLogger, execId string) (bool, ReasonCode,
+	error) {
+	user := request.user
+	organization := request.organization
+	image := request.image
+	logger.Debugf("[%s] ACL is checking whether the user %s is authorized to pull the image %s in the "+
+		" organization %s.", execId, user, image, organization)
+
+	visibility, isImageFound, err := resolveVisibility(request, logger, execId)
 	if err != nil {
-		return visibility, fmt.Errorf("error while executing the mysql query getVisibilityQuery :%s", err)
+		return false, "", err
 	}
-	if results.Next() {
-		err = results.Scan(&visibility)
-		logger.Debugf("[%s] Visibility of the image %s/%s is found as %s from the db", execId, organization,
-			image, visibility)
-	} else {
-		logger.Debugf("[%s] Visibility of the image %s/%s is not found in the db", execId, organization,
-			image)
+	if strings.EqualFold(visibility, publicVisibility) {
+		logger.Debugf("[%s] Visibility of the image %s/%s is public. Hence user %s is authorized to pull",
+			execId, organization, image, user)
+		return true, "", nil
 	}
-	if err != nil {
-		return visibility, fmt.Errorf("[%s] Error in retrieving the visibility for %s/%s from the "+
-			"database :%s", execId, organization, image, err)
+	logger.Debugf("[%s] Visibility is not public for image %s/%s to the user %s", execId, organization,
+		image, user)
+	// Check whether the roles of the user allow pulling private images
+	isAuthorized, reason, err := isRoleAuthorized(request, pullAction, logger, execId)
+	if err != nil || isAuthorized || reason != ReasonNotMember {
+		return isAuthorized, reason, err
+	}
+	if isImageFound {
+		return false, ReasonImagePrivate, nil
 	}
-	return visibility, nil
+	return false, ReasonImageNotFound, nil
 }
 
-func isUserAvailable(db *sql.DB, organization, user string, logger *zap.SugaredLogger, execId string) (bool, error) {
-	logger.Debugf("[%s] Checking whether the user %s exists in the organization %s", execId, user,
-		organization)
-	results, err := db.Query(getUserAvailabilityQuery, user, organization)
-	defer func() {
-		closeResultSet(results, "isUserAvailable", logger, execId)
-	}()
+// isAnonymousPullAuthorized checks whether an unauthenticated request is allowed to pull the image. Only public
+// images can be pulled, irrespective of the account in the request. The decision is not cached since it depends only
+// on the visibility of the image, which is cached separately.
+func isAnonymousPullAuthorized(request *aclRequest, logger *zap.SugaredLogger, execId string) (bool, ReasonCode,
+	error) {
+	visibility, _, err := resolveVisibility(request, logger, execId)
 	if err != nil {
-		return false, fmt.Errorf("error while executing the mysql query getUserAvailabilityQuery :%s\n", err)
+		return false, "", err
 	}
-	if results.Next() {
-		logger.Debugf("[%s] User %s is available in the organization :%s", execId, user, organization)
-		return true, nil
-	} else {
-		logger.Debugf("[%s] User %s is not available in the organization :%s", execId, user, organization)
-		return false, nil
+	if strings.EqualFold(visibility, publicVisibility) {
+		logger.Debugf("[%s] Visibility of the image %s/%s is public. Hence unauthenticated user is authorized to "+
+			"pull", execId, request.organization, request.image)
+		return true, "", nil
 	}
+	logger.Debugf("[%s] Denying access for unauthenticated user to pull the image %s/%s which is not public",
+		execId, request.organization, request.image)
+	return false, ReasonUnauthenticated, nil
 }
 
-func isAuthorizedToPull(db *sql.DB, user string, organization string, image string,
-	logger *zap.SugaredLogger, execId string) (bool, error) {
-
-	logger.Debugf("[%s] ACL is checking whether the user %s is authorized to pull the image %s in the "+
-		" organization %s.", execId, user, image, organization)
-
-	visibility, err := getImageVisibility(db, image, organization, logger, execId)
-
+// resolveVisibility returns the visibility of the image and whether the image exists, using the cached visibility
+// if available
+func resolveVisibility(request *aclRequest, logger *zap.SugaredLogger, execId string) (string, bool, error) {
+	organization := request.organization
+	image := request.image
+	visibility, isImageFound, isCached := aclDecisions.getVisibility(request.store, organization, image, logger,
+		execId)
+	if isCached {
+		return visibility, isImageFound, nil
+	}
+	record, err := request.getRecord(logger, execId)
 	if err != nil {
-		logger.Debugf("[%s] User %s is not authorized to pull the image %s/%s.", execId, user,
-			organization, image)
-		return false, fmt.Errorf("error occured while geting visibility of image. User %s is not "+
-			"authorized to pull the image %s/%s", user, organization, image)
-	} else if strings.EqualFold(visibility, publicVisibility) {
-		logger.Debugf("[%s] Visibility of the image %s/%s is public. Hence user %s is authorized to pull",
-			execId, organization, image, user)
-		return true, nil
-	} else {
-		logger.Debugf("[%s] Visibility is not public for image %s/%s to the user %s", execId, organization,
-			image, user)
-		// Check whether the username exists in the organization when a fresh image come and tries to push
-		return isUserAvailable(db, organization, user, logger, execId)
+		return "", false, &StoreError{Err: fmt.Errorf("error occured while geting visibility of the image "+
+			"%s/%s :%s", organization, image, err)}
 	}
+	aclDecisions.putVisibility(organization, image, record.Visibility, record.IsImageFound)
+	return record.Visibility, record.IsImageFound, nil
 }
 
-func isAuthorizedToPush(db *sql.DB, user string, organization string,
-	logger *zap.SugaredLogger, execId string) (bool, error) {
+// isAuthorizedToPush checks whether the roles of the user allow pushing to the image and whether the push is
+// allowed by the organization. The reason is returned if the user is not allowed.
+func isAuthorizedToPush(request *aclRequest, logger *zap.SugaredLogger, execId string) (bool, ReasonCode,
+	error) {
+	isAuthorized, reason, err := isRoleAuthorized(request, pushAction, logger, execId)
+	if err != nil || !isAuthorized {
+		return isAuthorized, reason, err
+	}
+	return isPushAllowed(request, logger, execId)
+}
 
-	logger.Debugf("[%s] User %s is trying to push to organization :%s", execId, user, organization)
-	results, err := db.Query(getUserRoleQuery, user, organization)
-	defer func() {
-		closeResultSet(results, "isAuthorizedToPush", logger, execId)
-	}()
+// isPushAllowed checks the rules which apply to a push irrespective of the account pushing it. The names of new
+// images should satisfy the naming policy and the push should be within the quotas of the organization.
+func isPushAllowed(request *aclRequest, logger *zap.SugaredLogger, execId string) (bool, ReasonCode, error) {
+	isAllowed, reason, err := isNamingPolicySatisfied(request, logger, execId)
+	if err != nil || !isAllowed {
+		return isAllowed, reason, err
+	}
+	return isWithinQuota(request, logger, execId)
+}
+
+// isRoleAuthorized checks whether the roles of the user allow the action on the image. If the user is a collaborator
+// of the image either directly or through a team, the roles granted on the image override the organization level
+// roles. Otherwise the effective permission of the user is the union of the actions allowed by the direct role of the
+// user in the organization and the roles granted to the teams of the user. The actions of the roles are defined in
+// the database.
+func isRoleAuthorized(request *aclRequest, action string, logger *zap.SugaredLogger, execId string) (bool,
+	ReasonCode, error) {
+	logger.Debugf("[%s] User %s is trying to perform %s action on image %s/%s", execId, request.user, action,
+		request.organization, request.image)
+	record, err := request.getRecord(logger, execId)
 	if err != nil {
-		return false, fmt.Errorf("error while executing the mysql query getUserRoleQuery :%s", err)
+		return false, "", err
+	}
+	userRoles := record.Roles()
+	if len(userRoles) == 0 {
+		logger.Debugf("[%s] User %s does not have any role in the organization %s", execId, request.user,
+			request.organization)
+		return false, ReasonNotMember, nil
 	}
-	if results.Next() {
-		var userRole string
-		// for each row, scan the result into our tag composite object
-		err = results.Scan(&userRole)
+	for _, userRole := range userRoles {
+		roleActions, err := roles.getActions(request.store, userRole, logger, execId)
 		if err != nil {
-			return false, fmt.Errorf("[%s] Error in retrieving the username role from the "+
-				"database :%s", execId, err)
+			return false, "", err
 		}
-		logger.Errorf("[%s] User role is declared as %s\n", execId, userRole)
-		if (userRole == userAdminRole) || (userRole == userPushRole) {
-			logger.Debugf("[%s] User is allowed to push the image", execId)
-			return true, nil
-		} else {
-			logger.Debugf("[%s] User does not have push rights", execId)
-			return false, err
+		if containsString(roleActions, action) {
+			logger.Debugf("[%s] Role %s allows the user to perform %s action on image %s/%s", execId, userRole,
+				action, request.organization, request.image)
+			return true, "", nil
 		}
 	}
-	return false, nil
+	logger.Debugf("[%s] Roles %s do not allow %s action on image %s/%s", execId, userRoles, action,
+		request.organization, request.image)
+	return false, ReasonInsufficientRole, nil
 }
 
-func isAuthorizedToDelete(db *sql.DB, user string, organization string,
-	logger *zap.SugaredLogger, execId string) (bool, error) {
+// isRobotAuthorized checks the requested action against the organization and the actions the robot account is
+// scoped to. These are resolved from the database at authentication time, hence no queries are executed here.
+func isRobotAuthorized(action string, username string, organization string, robotOrganizations []string,
+	robotActions []string, logger *zap.SugaredLogger, execId string) bool {
+	if !strings.HasPrefix(username, RobotUsernamePrefix) || len(robotOrganizations) != 1 {
+		logger.Debugf("[%s] Robot labels received for the non robot user %s", execId, username)
+		return false
+	}
+	if robotOrganizations[0] != organization {
+		logger.Debugf("[%s] Robot account %s is not allowed to access the organization %s", execId, username,
+			organization)
+		return false
+	}
+	if !containsString(robotActions, action) {
+		logger.Debugf("[%s] Robot account %s is not allowed to perform the %s action", execId, username, action)
+		return false
+	}
+	logger.Debugf("[%s] Robot account %s is allowed to perform the %s action", execId, username, action)
+	return true
+}
 
-	logger.Debugf("[%s] User %s is trying to perform delete action on organization :%s", execId, user,
-		organization)
-	results, err := db.Query(getUserRoleQuery, user, organization)
-	defer func() {
-		closeResultSet(results, "isAuthorizedToDelete", logger, execId)
-	}()
-	if err != nil {
-		return false, fmt.Errorf("error while executing the mysql query for getting the user role :%s", err)
+// isWithinTokenScope checks whether the requested action is within the scopes of a scoped token such as a
+// personal access token. The user still requires the relevant permission for the action.
+func isWithinTokenScope(action string, organization string, tokenScopes []string, tokenOrganizations []string,
+	logger *zap.SugaredLogger, execId string) bool {
+	if len(tokenOrganizations) > 0 && !containsString(tokenOrganizations, organization) {
+		logger.Debugf("[%s] Token is restricted to the organization %s and cannot access %s", execId,
+			tokenOrganizations, organization)
+		return false
 	}
-	if results.Next() {
-		var userRole string
-		// for each row, scan the result into our tag composite object
-		err = results.Scan(&userRole)
-		if err != nil {
-			logger.Debugf("[%s] Error in retrieving the user role from the database :%s", execId, err)
-			return false, err
-		}
-		logger.Debugf("[%s] User role is declared as %s for the organization %s", execId,
-			userRole, organization)
-		if userRole == userAdminRole {
-			logger.Debugf("[%s] User is allowed to delete the image under organization %s", execId, organization)
-			return true, nil
-		} else {
-			logger.Debugf("[%s] User does not have delete rights to delete images of organization %s", execId,
-				organization)
-			return false, err
+	if !containsString(tokenScopes, action) {
+		logger.Debugf("[%s] Action %s is not within the token scopes %s", execId, action, tokenScopes)
+		return false
+	}
+	return true
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
 		}
 	}
-	return false, nil
+	return false
 }
 
 func closeResultSet(r *sql.Rows, caller string, logger *zap.SugaredLogger, execId string) {
@@ -0,0 +1,94 @@
+/*
+ * Copyright (c) 2019 WSO2 Inc. (http:www.wso2.org) All Rights Reserved.
+ *
+ * WSO2 Inc. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http:www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package extension
+
+import (
+	"testing"
+
+	"github.com/cesanta/docker_auth/auth_server/api"
+	"go.uber.org/zap"
+)
+
+// The queries executed separately for each authorization before the ACL record was retrieved with a single query
+const separateVisibilityQuery = "SELECT REGISTRY_ARTIFACT_IMAGE.VISIBILITY, " +
+	"REGISTRY_ORGANIZATION.DEFAULT_IMAGE_VISIBILITY FROM REGISTRY_ORGANIZATION " +
+	"LEFT JOIN REGISTRY_ARTIFACT_IMAGE ON REGISTRY_ORGANIZATION.ORG_NAME=REGISTRY_ARTIFACT_IMAGE.ORG_NAME " +
+	"AND REGISTRY_ARTIFACT_IMAGE.IMAGE_NAME=? " +
+	"WHERE REGISTRY_ORGANIZATION.ORG_NAME=? LIMIT 1"
+const separateCollaboratorRolesQuery = "SELECT DISTINCT ROLE_NAME FROM REGISTRY_IMAGE_COLLABORATOR " +
+	"WHERE REGISTRY_IMAGE_COLLABORATOR.ORG_NAME=? AND REGISTRY_IMAGE_COLLABORATOR.IMAGE_NAME=? AND " +
+	"((REGISTRY_IMAGE_COLLABORATOR.COLLABORATOR_TYPE='user' AND REGISTRY_IMAGE_COLLABORATOR.COLLABORATOR_NAME=?) " +
+	"OR (REGISTRY_IMAGE_COLLABORATOR.COLLABORATOR_TYPE='team' AND REGISTRY_IMAGE_COLLABORATOR.COLLABORATOR_NAME IN " +
+	"(SELECT TEAM_NAME FROM REGISTRY_TEAM_USER_MAPPING " +
+	"WHERE REGISTRY_TEAM_USER_MAPPING.ORG_NAME=? AND REGISTRY_TEAM_USER_MAPPING.USER_UUID=?)))"
+const separateOrganizationRolesQuery = "SELECT USER_ROLE FROM REGISTRY_ORG_USER_MAPPING " +
+	"WHERE REGISTRY_ORG_USER_MAPPING.USER_UUID=? AND REGISTRY_ORG_USER_MAPPING.ORG_NAME=? " +
+	"UNION SELECT REGISTRY_TEAM_PERMISSION.ROLE_NAME FROM REGISTRY_TEAM_PERMISSION " +
+	"INNER JOIN REGISTRY_TEAM_USER_MAPPING ON REGISTRY_TEAM_USER_MAPPING.ORG_NAME=REGISTRY_TEAM_PERMISSION.ORG_NAME " +
+	"AND REGISTRY_TEAM_USER_MAPPING.TEAM_NAME=REGISTRY_TEAM_PERMISSION.TEAM_NAME " +
+	"WHERE REGISTRY_TEAM_USER_MAPPING.USER_UUID=? AND REGISTRY_TEAM_PERMISSION.ORG_NAME=?"
+
+func BenchmarkSeparateAclQueries(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		executeBenchmarkQuery(b, separateVisibilityQuery, "newImage", "cellery")
+		executeBenchmarkQuery(b, separateCollaboratorRolesQuery, "cellery", "newImage", "wso2.com", "cellery",
+			"wso2.com")
+		executeBenchmarkQuery(b, separateOrganizationRolesQuery, "wso2.com", "cellery", "wso2.com", "cellery")
+	}
+}
+
+func executeBenchmarkQuery(b *testing.B, query string, args ...interface{}) {
+	results, err := dbConnection.Query(query, args...)
+	if err != nil {
+		b.Fatal("Error while executing the query :", err)
+	}
+	for results.Next() {
+	}
+	if err = results.Close(); err != nil {
+		b.Fatal("Error while closing the result set :", err)
+	}
+}
+
+func BenchmarkGetACLRecord(b *testing.B) {
+	logger := zap.NewNop().Sugar()
+	for i := 0; i < b.N; i++ {
+		_, err := aclStore.GetACLRecord("wso2.com", "cellery", "newImage", logger, testUser)
+		if err != nil {
+			b.Fatal("Error while retrieving the ACL record :", err)
+		}
+	}
+}
+
+// BenchmarkIsUserAuthorized measures the authorization of a push to a private image without the decision cache
+func BenchmarkIsUserAuthorized(b *testing.B) {
+	decisions := aclDecisions
+	aclDecisions = newAclCache(0, 0, 0)
+	defer func() {
+		aclDecisions = decisions
+	}()
+	logger := zap.NewNop().Sugar()
+	labels := api.Labels{AuthSuccessLabel: []string{"true"}}
+	for i := 0; i < b.N; i++ {
+		_, err := IsUserAuthorized(aclStore, []string{"pull", "push"}, "wso2.com", "cellery/newImage",
+			labels, logger, testUser)
+		if err != nil {
+			b.Fatal("Error while authorizing :", err)
+		}
+	}
+}
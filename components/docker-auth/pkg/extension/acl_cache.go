@@ -0,0 +1,207 @@
+/*
+ * Copyright (c) 2019 WSO2 Inc. (http:www.wso2.org) All Rights Reserved.
+ *
+ * WSO2 Inc. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http:www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package extension
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// aclCache caches the authorization decisions derived from the ACL store for each user, organization, image and
+// action along with the visibility of the images. The entries expire after the TTL. The plugin also polls the
+// version of the ACL store, which changes whenever the memberships, roles, grants or visibilities change, and
+// discards all the entries when the version or the store changes.
+type aclCache struct {
+	mutex               sync.Mutex
+	store               ACLStore
+	ttl                 time.Duration
+	maxSize             int
+	versionPollInterval time.Duration
+	decisions           map[string]cachedDecision
+	visibilities        map[string]cachedVisibility
+	version             int64
+	lastVersionPoll     time.Time
+	now                 func() time.Time
+}
+
+type cachedDecision struct {
+	isAuthorized bool
+	reason       ReasonCode
+	expiry       time.Time
+}
+
+type cachedVisibility struct {
+	visibility   string
+	isImageFound bool
+	expiry       time.Time
+}
+
+var aclDecisions = newAclCache(
+	time.Duration(resolveNonNegativeIntEnvVar(DecisionCacheTtlEnvVar, DefaultDecisionCacheTtl))*time.Second,
+	resolveNonNegativeIntEnvVar(DecisionCacheMaxSizeEnvVar, DefaultDecisionCacheMaxSize),
+	time.Duration(resolveNonNegativeIntEnvVar(AclVersionPollIntervalEnvVar,
+		DefaultAclVersionPollInterval))*time.Second)
+
+func newAclCache(ttl time.Duration, maxSize int, versionPollInterval time.Duration) *aclCache {
+	return &aclCache{
+		ttl:                 ttl,
+		maxSize:             maxSize,
+		versionPollInterval: versionPollInterval,
+		decisions:           make(map[string]cachedDecision),
+		visibilities:        make(map[string]cachedVisibility),
+		version:             -1,
+		now:                 time.Now,
+	}
+}
+
+func resolveNonNegativeIntEnvVar(envVar string, defaultValue int) int {
+	if value, err := strconv.Atoi(os.Getenv(envVar)); err == nil && value >= 0 {
+		return value
+	}
+	return defaultValue
+}
+
+func (c *aclCache) isEnabled() bool {
+	return c.ttl > 0 && c.maxSize > 0
+}
+
+func (c *aclCache) getDecision(store ACLStore, user string, organization string, image string, action string,
+	logger *zap.SugaredLogger, execId string) (bool, ReasonCode, bool) {
+	if !c.isEnabled() {
+		return false, "", false
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.invalidateIfVersionChanged(store, logger, execId)
+	key := strings.Join([]string{user, organization, image, action}, "\x00")
+	decision, exists := c.decisions[key]
+	if !exists || !c.now().Before(decision.expiry) {
+		return false, "", false
+	}
+	logger.Debugf("[%s] Using the cached decision for %s action on %s/%s by the user %s", execId, action,
+		organization, image, user)
+	return decision.isAuthorized, decision.reason, true
+}
+
+func (c *aclCache) putDecision(user string, organization string, image string, action string, isAuthorized bool,
+	reason ReasonCode) {
+	if !c.isEnabled() {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if len(c.decisions) >= c.maxSize {
+		c.decisions = c.removeExpiredDecisions()
+	}
+	key := strings.Join([]string{user, organization, image, action}, "\x00")
+	c.decisions[key] = cachedDecision{isAuthorized: isAuthorized, reason: reason, expiry: c.now().Add(c.ttl)}
+}
+
+func (c *aclCache) getVisibility(store ACLStore, organization string, image string, logger *zap.SugaredLogger,
+	execId string) (string, bool, bool) {
+	if !c.isEnabled() {
+		return "", false, false
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.invalidateIfVersionChanged(store, logger, execId)
+	visibility, exists := c.visibilities[organization+"/"+image]
+	if !exists || !c.now().Before(visibility.expiry) {
+		return "", false, false
+	}
+	logger.Debugf("[%s] Using the cached visibility %s of the image %s/%s", execId, visibility.visibility,
+		organization, image)
+	return visibility.visibility, visibility.isImageFound, true
+}
+
+func (c *aclCache) putVisibility(organization string, image string, visibility string, isImageFound bool) {
+	if !c.isEnabled() {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if len(c.visibilities) >= c.maxSize {
+		c.visibilities = c.removeExpiredVisibilities()
+	}
+	c.visibilities[organization+"/"+image] = cachedVisibility{visibility: visibility, isImageFound: isImageFound,
+		expiry: c.now().Add(c.ttl)}
+}
+
+// removeExpiredDecisions returns the decisions which are not expired. All the decisions are discarded if the
+// cache is still full.
+func (c *aclCache) removeExpiredDecisions() map[string]cachedDecision {
+	decisions := make(map[string]cachedDecision)
+	for key, decision := range c.decisions {
+		if c.now().Before(decision.expiry) {
+			decisions[key] = decision
+		}
+	}
+	if len(decisions) >= c.maxSize {
+		return make(map[string]cachedDecision)
+	}
+	return decisions
+}
+
+// removeExpiredVisibilities returns the visibilities which are not expired. All the visibilities are discarded if
+// the cache is still full.
+func (c *aclCache) removeExpiredVisibilities() map[string]cachedVisibility {
+	visibilities := make(map[string]cachedVisibility)
+	for key, visibility := range c.visibilities {
+		if c.now().Before(visibility.expiry) {
+			visibilities[key] = visibility
+		}
+	}
+	if len(visibilities) >= c.maxSize {
+		return make(map[string]cachedVisibility)
+	}
+	return visibilities
+}
+
+// invalidateIfVersionChanged polls the ACL version once the poll interval elapses and discards the cached entries
+// along with the loaded roles if the version has changed. The entries are also discarded if the version cannot
+// be read since the changes cannot be tracked, and if the entries were derived from a different store.
+func (c *aclCache) invalidateIfVersionChanged(store ACLStore, logger *zap.SugaredLogger, execId string) {
+	if store != c.store {
+		c.store = store
+		c.version = -1
+	} else if c.now().Sub(c.lastVersionPoll) < c.versionPollInterval {
+		return
+	}
+	c.lastVersionPoll = c.now()
+	version, err := store.GetVersion(logger, execId)
+	if err != nil {
+		logger.Errorf("[%s] Discarding the cached decisions since the ACL version cannot be read :%s", execId,
+			err)
+		version = -1
+	} else if version == c.version {
+		return
+	} else {
+		logger.Debugf("[%s] ACL version changed from %d to %d, hence discarding the cached decisions", execId,
+			c.version, version)
+	}
+	c.version = version
+	c.decisions = make(map[string]cachedDecision)
+	c.visibilities = make(map[string]cachedVisibility)
+	roles.invalidate()
+}
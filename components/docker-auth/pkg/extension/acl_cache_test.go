@@ -0,0 +1,116 @@
+/*
+ * Copyright (c) 2019 WSO2 Inc. (http:www.wso2.org) All Rights Reserved.
+ *
+ * WSO2 Inc. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http:www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package extension
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestAclCacheExpiry(t *testing.T) {
+	logger := zap.NewExample().Sugar()
+	currentTime := time.Now()
+	cache := newAclCache(30*time.Second, 10, time.Hour)
+	cache.now = func() time.Time {
+		return currentTime
+	}
+	// The initial version is polled on the first lookup
+	cache.getDecision(aclStore, "wso2.com", "cellery", "image", "push", logger, testUser)
+	cache.putDecision("wso2.com", "cellery", "image", "push", false, "denied")
+	cache.putVisibility("cellery", "image", "PUBLIC", true)
+
+	isAuthorized, reason, isCached := cache.getDecision(aclStore, "wso2.com", "cellery", "image", "push",
+		logger, testUser)
+	if !isCached || isAuthorized || reason != "denied" {
+		t.Error("Expected the cached decision to be returned")
+	}
+	if _, _, isCached = cache.getDecision(aclStore, "wso2.com", "cellery", "image", "pull", logger,
+		testUser); isCached {
+		t.Error("Expected no decision to be cached for the pull action")
+	}
+	visibility, isImageFound, isCached := cache.getVisibility(aclStore, "cellery", "image", logger, testUser)
+	if !isCached || visibility != "PUBLIC" || !isImageFound {
+		t.Error("Expected the cached visibility to be returned")
+	}
+
+	currentTime = currentTime.Add(time.Minute)
+	if _, _, isCached = cache.getDecision(aclStore, "wso2.com", "cellery", "image", "push", logger,
+		testUser); isCached {
+		t.Error("Expected the decision to be expired")
+	}
+	if _, _, isCached = cache.getVisibility(aclStore, "cellery", "image", logger, testUser); isCached {
+		t.Error("Expected the visibility to be expired")
+	}
+}
+
+func TestAclCacheVersionInvalidation(t *testing.T) {
+	logger := zap.NewExample().Sugar()
+	currentTime := time.Now()
+	cache := newAclCache(time.Hour, 10, 5*time.Second)
+	cache.now = func() time.Time {
+		return currentTime
+	}
+	cache.getDecision(aclStore, "wso2.com", "cellery", "image", "push", logger, testUser)
+	cache.putDecision("wso2.com", "cellery", "image", "push", true, "")
+
+	_, err := dbConnection.Exec("UPDATE REGISTRY_ACL_VERSION SET VERSION = VERSION + 1 WHERE ID = 1")
+	if err != nil {
+		t.Fatal("Error while updating the ACL version :", err)
+	}
+	// The version is not polled until the poll interval elapses
+	if _, _, isCached := cache.getDecision(aclStore, "wso2.com", "cellery", "image", "push", logger,
+		testUser); !isCached {
+		t.Error("Expected the decision to be cached until the version is polled")
+	}
+	currentTime = currentTime.Add(10 * time.Second)
+	if _, _, isCached := cache.getDecision(aclStore, "wso2.com", "cellery", "image", "push", logger,
+		testUser); isCached {
+		t.Error("Expected the decision to be discarded after the version changed")
+	}
+
+	cache.putDecision("wso2.com", "cellery", "image", "push", true, "")
+	currentTime = currentTime.Add(10 * time.Second)
+	if _, _, isCached := cache.getDecision(aclStore, "wso2.com", "cellery", "image", "push", logger,
+		testUser); !isCached {
+		t.Error("Expected the decision to be cached when the version is unchanged")
+	}
+}
+
+func TestAclCacheDisabled(t *testing.T) {
+	logger := zap.NewExample().Sugar()
+	cache := newAclCache(0, 10, time.Second)
+	cache.putDecision("wso2.com", "cellery", "image", "push", true, "")
+	if _, _, isCached := cache.getDecision(aclStore, "wso2.com", "cellery", "image", "push", logger,
+		testUser); isCached {
+		t.Error("Expected the decisions not to be cached when the TTL is zero")
+	}
+}
+
+func TestAclCacheStoreChange(t *testing.T) {
+	logger := zap.NewExample().Sugar()
+	cache := newAclCache(time.Hour, 10, time.Hour)
+	cache.getDecision(aclStore, "wso2.com", "cellery", "image", "push", logger, testUser)
+	cache.putDecision("wso2.com", "cellery", "image", "push", true, "")
+	if _, _, isCached := cache.getDecision(NewMemoryStore(), "wso2.com", "cellery", "image", "push", logger,
+		testUser); isCached {
+		t.Error("Expected the decisions to be discarded when the store changes")
+	}
+}
@@ -0,0 +1,67 @@
+/*
+ * Copyright (c) 2019 WSO2 Inc. (http:www.wso2.org) All Rights Reserved.
+ *
+ * WSO2 Inc. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http:www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package extension
+
+import (
+	"go.uber.org/zap"
+)
+
+// ACLRecord holds the data required for authorizing a user on an image
+type ACLRecord struct {
+	IsOrganizationFound bool
+	Visibility          string
+	IsImageFound        bool
+	CollaboratorRoles   []string
+	OrganizationRoles   []string
+}
+
+// Roles returns the roles granted to the user on the image if the user is a collaborator of the image. Otherwise
+// the direct role of the user in the organization along with the roles granted to the teams of the user are
+// returned.
+func (r *ACLRecord) Roles() []string {
+	if len(r.CollaboratorRoles) > 0 {
+		return r.CollaboratorRoles
+	}
+	return r.OrganizationRoles
+}
+
+// aclRequest loads the ACL record of the user for the image on demand, once for all the actions of an
+// authorization request
+type aclRequest struct {
+	store        ACLStore
+	user         string
+	organization string
+	image        string
+	record       *ACLRecord
+}
+
+func newAclRequest(store ACLStore, user string, organization string, image string) *aclRequest {
+	return &aclRequest{store: store, user: user, organization: organization, image: image}
+}
+
+func (r *aclRequest) getRecord(logger *zap.SugaredLogger, execId string) (*ACLRecord, error) {
+	if r.record == nil {
+		record, err := r.store.GetACLRecord(r.user, r.organization, r.image, logger, execId)
+		if err != nil {
+			return nil, &StoreError{Err: err}
+		}
+		r.record = record
+	}
+	return r.record, nil
+}
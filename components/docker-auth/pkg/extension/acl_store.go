@@ -0,0 +1,57 @@
+/*
+ * Copyright (c) 2019 WSO2 Inc. (http:www.wso2.org) All Rights Reserved.
+ *
+ * WSO2 Inc. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http:www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package extension
+
+import (
+	"go.uber.org/zap"
+)
+
+// ACLStore provides the data the ACL is evaluated against, hence the authorization logic does not depend on a
+// particular database. Stores which refer to the same data should be equal when compared, since the cached
+// decisions and roles are discarded whenever a different store is used.
+type ACLStore interface {
+	// GetACLRecord returns the visibility of the image along with the roles of the user in the organization and
+	// the roles granted to the user on the image
+	GetACLRecord(user string, organization string, image string, logger *zap.SugaredLogger,
+		execId string) (*ACLRecord, error)
+	// GetRolePermissions returns the actions allowed for each role
+	GetRolePermissions(logger *zap.SugaredLogger, execId string) (map[string][]string, error)
+	// GetOrganizationQuota returns the quotas of the organization along with the number of images in the
+	// organization. Nil is returned if the organization does not have any quotas.
+	GetOrganizationQuota(organization string, logger *zap.SugaredLogger, execId string) (*OrganizationQuota, error)
+	// GetVersion returns the version of the ACL data, which changes whenever the data used for authorization
+	// decisions changes
+	GetVersion(logger *zap.SugaredLogger, execId string) (int64, error)
+}
+
+// StoreError is returned when the ACL store fails to provide the data, hence the failures of the database can be
+// told apart from the invalid requests and configurations
+type StoreError struct {
+	Err error
+}
+
+func (e *StoreError) Error() string {
+	return e.Err.Error()
+}
+
+// IsStoreError returns true if the error is caused by a failure of the ACL store
+func IsStoreError(err error) bool {
+	_, isStoreError := err.(*StoreError)
+	return isStoreError
+}
@@ -22,12 +22,14 @@ import (
 	"database/sql"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
 	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -35,13 +37,18 @@ import (
 
 	"github.com/cesanta/docker_auth/auth_server/api"
 	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/mattn/go-sqlite3"
 )
 
 var dbConnection *sql.DB
+var aclStore ACLStore
 
 const testUser = "testUser"
 const errorExitCode = 1
 
+// The tests run against an SQLite database created with the test scripts unless the MySQL store is selected
+const testStoreEnvVar = "ACL_TEST_STORE"
+
 func createConn() bool {
 	dbDriver := MysqlDriver
 	dbUser := "root"
@@ -108,6 +115,24 @@ func setEnv() {
 
 func TestMain(m *testing.M) {
 	fmt.Println("Acl test started to run")
+	if os.Getenv(testStoreEnvVar) == MysqlDriver {
+		setupMysqlStore()
+		m.Run()
+		// Cleaning up the docker container
+		teardown()
+		return
+	}
+	dir, err := ioutil.TempDir("", "acl")
+	if err != nil {
+		log.Fatal("Error while creating the directory for the SQLite database :", err)
+	}
+	defer os.RemoveAll(dir)
+	setupSqliteStore(filepath.Join(dir, "acl.db"))
+	defer dbConnection.Close()
+	m.Run()
+}
+
+func setupMysqlStore() {
 	// make target dir
 	makedir("../../target/test/mysql_scripts")
 	err := moveFiles("../../test/init.sql", "../../target/test/mysql_scripts/1_init.sql")
@@ -144,9 +169,56 @@ func TestMain(m *testing.M) {
 	}
 	time.Sleep(20 * time.Millisecond)
 	fmt.Println("Docker container created")
-	m.Run()
-	// Cleaning up the docker container
-	teardown()
+	aclStore = NewMySQLStore(dbConnection)
+}
+
+func setupSqliteStore(file string) {
+	var err error
+	dbConnection, err = openSqliteTestDb(file)
+	if err != nil {
+		log.Fatal("Error while setting up the SQLite database :", err)
+	}
+	aclStore = NewSQLiteStore(dbConnection)
+}
+
+// openSqliteTestDb opens an SQLite database with the tables of Cellery Hub and the test data
+func openSqliteTestDb(file string) (*sql.DB, error) {
+	db, err := sql.Open(SqliteDriver, fmt.Sprintf("file:%s?_foreign_keys=on", file))
+	if err != nil {
+		return nil, err
+	}
+	script, err := ioutil.ReadFile("../../test/init_sqlite.sql")
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	if _, err = db.Exec(string(script)); err != nil {
+		db.Close()
+		return nil, err
+	}
+	err = loadTestData(db, "../../test/data.sql")
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// loadTestData executes the insert statements of the MySQL test data script, which are portable to SQLite
+func loadTestData(db *sql.DB, file string) error {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	for _, statement := range strings.Split(string(content), ";\n") {
+		if !strings.HasPrefix(strings.TrimSpace(statement), "INSERT INTO") {
+			continue
+		}
+		if _, err = db.Exec(statement); err != nil {
+			return fmt.Errorf("error while executing %s :%s", statement, err)
+		}
+	}
+	return nil
 }
 
 func TestValidateAccess(t *testing.T) {
@@ -154,6 +226,15 @@ func TestValidateAccess(t *testing.T) {
 	label[0] = "true"
 	authLabels := api.Labels{}
 	authLabels["isAuthSuccess"] = label
+	robotLabels := api.Labels{}
+	robotLabels["isAuthSuccess"] = label
+	robotLabels[RobotOrganizationLabel] = []string{"cellery"}
+	robotLabels[RobotActionsLabel] = []string{"pull", "push"}
+	unauthenticatedLabels := api.Labels{}
+	unauthenticatedLabels["isAuthSuccess"] = []string{"false"}
+	pullTokenLabels := api.Labels{}
+	pullTokenLabels["isAuthSuccess"] = label
+	pullTokenLabels[TokenScopesLabel] = []string{"pull"}
 
 	values := []struct {
 		actions    []string
@@ -162,6 +243,19 @@ func TestValidateAccess(t *testing.T) {
 		labels     api.Labels
 	}{
 		{[]string{"pull"}, "wso2.com", "cellery/newImag", authLabels},
+		//	robot account pushing to a private image of its organization
+		{[]string{"pull", "push"}, "robot$cellery+ci", "cellery/newImage", robotLabels},
+		//	member pulling a private image with a pull only personal access token
+		{[]string{"pull"}, "wso2.com", "cellery/newImage", pullTokenLabels},
+		//	actions requested in any order and combination
+		{[]string{"push", "pull"}, "wso2.com", "cellery/newImage", authLabels},
+		{[]string{"push"}, "wso2.com", "cellery/newImage", authLabels},
+		{[]string{"pull", "push", "delete"}, "admin.com", "cellery/newImage", authLabels},
+		{[]string{"*"}, "admin.com", "cellery/image", authLabels},
+		//	unauthenticated user pulling a public image
+		{[]string{"pull"}, "", "cellery/image", unauthenticatedLabels},
+		//	unauthenticated user pulling an image which is not registered in an organization with public images
+		{[]string{"pull"}, "", "opensource/newImage", unauthenticatedLabels},
 		{[]string{"pull"}, "admin@wso2.com", "cellery/image", authLabels},
 		{[]string{"pull", "push"}, "admin.com", "cellery/image", authLabels},
 		//	user trying to push with a new image which does not exists in the db
@@ -174,13 +268,14 @@ func TestValidateAccess(t *testing.T) {
 	}
 	logger := zap.NewExample().Sugar()
 	for _, value := range values {
-		isAuthorized, err := IsUserAuthorized(dbConnection, value.actions, value.username, value.repository, value.labels,
-			logger, testUser)
+		decision, err := IsUserAuthorized(aclStore, value.actions, value.username, value.repository,
+			value.labels, logger, testUser)
 		if err != nil {
-			log.Println("Error while validating the access token :", err)
+			t.Error("Error while validating the access :", err)
+			continue
 		}
-		if !isAuthorized {
-			t.Error("Access is not allowed for username :", value.username)
+		if strings.Join(decision.GrantedActions, ",") != strings.Join(value.actions, ",") {
+			t.Error("Access is not allowed for username :", value.username, "for", value.actions, "actions")
 		}
 	}
 }
@@ -190,51 +285,256 @@ func TestInvalidAccess(t *testing.T) {
 	label[0] = "true"
 	authLabels := api.Labels{}
 	authLabels["isAuthSuccess"] = label
+	robotLabels := api.Labels{}
+	robotLabels["isAuthSuccess"] = label
+	robotLabels[RobotOrganizationLabel] = []string{"cellery"}
+	robotLabels[RobotActionsLabel] = []string{"pull"}
+	pullTokenLabels := api.Labels{}
+	pullTokenLabels["isAuthSuccess"] = label
+	pullTokenLabels[TokenScopesLabel] = []string{"pull"}
+	pullTokenLabels[TokenOrganizationLabel] = []string{"cellery"}
+	unauthenticatedLabels := api.Labels{}
+	unauthenticatedLabels["isAuthSuccess"] = []string{"false"}
+
+	values := []struct {
+		actions        []string
+		username       string
+		repository     string
+		labels         api.Labels
+		grantedActions []string
+	}{
+		//	pull only robot account trying to push
+		{[]string{"pull", "push"}, "robot$cellery+reader", "cellery/image", robotLabels, []string{"pull"}},
+		//	robot account trying to pull from another organization
+		{[]string{"pull"}, "robot$cellery+reader", "is/pqr", robotLabels, nil},
+		//	robot labels received for a user who is not a robot
+		{[]string{"pull"}, "user.com", "cellery/newImage", robotLabels, nil},
+		//	user with push rights trying to push with a pull only personal access token
+		{[]string{"pull", "push"}, "wso2.com", "cellery/newImage", pullTokenLabels, []string{"pull"}},
+		//	personal access token restricted to another organization
+		{[]string{"pull"}, "other.com", "is/pqr", pullTokenLabels, nil},
+		//	user with push rights trying to delete
+		{[]string{"*"}, "wso2.com", "cellery/image", authLabels, nil},
+		{[]string{"pull", "delete"}, "wso2.com", "cellery/image", authLabels, []string{"pull"}},
+		//	unrecognized actions
+		{[]string{"pull", "tag"}, "admin.com", "cellery/image", authLabels, []string{"pull"}},
+		{[]string{}, "admin.com", "cellery/image", authLabels, nil},
+		//	unauthenticated user pushing to a public image
+		{[]string{"push"}, "", "cellery/image", unauthenticatedLabels, nil},
+		// new user trying to pull a private image
+		{[]string{"pull"}, "user.com", "cellery/newImag", authLabels, nil},
+		//	a user with pull permission trying to push
+		{[]string{"pull", "push"}, "pull.com", "cellery/image", authLabels, []string{"pull"}},
+		//	user trying to pull a public image
+		{[]string{"pull"}, "other.com", "cellery/newImage", authLabels, nil},
+		{[]string{"pull", "push"}, "other.com", "cellery/image", authLabels, []string{"pull"}},
+		{[]string{"pull", "push"}, "other.com", "cellery/pqr", authLabels, nil},
+	}
+	logger := zap.NewExample().Sugar()
+	for _, value := range values {
+		decision, err := IsUserAuthorized(aclStore, value.actions, value.username, value.repository,
+			value.labels, logger, testUser)
+		if err != nil {
+			t.Error("Error while validating the access :", err)
+			continue
+		}
+		if strings.Join(decision.GrantedActions, ",") != strings.Join(value.grantedActions, ",") {
+			t.Error("Expected granted actions", value.grantedActions, "but found", decision.GrantedActions, "for",
+				value.username, "requesting", value.actions)
+		}
+	}
+}
+
+func TestPartialGrants(t *testing.T) {
+	label := make([]string, 1)
+	label[0] = "true"
+	authLabels := api.Labels{}
+	authLabels["isAuthSuccess"] = label
+	robotLabels := api.Labels{}
+	robotLabels["isAuthSuccess"] = label
+	robotLabels[RobotOrganizationLabel] = []string{"cellery"}
+	robotLabels[RobotActionsLabel] = []string{"pull"}
+	unauthenticatedLabels := api.Labels{}
+	unauthenticatedLabels["isAuthSuccess"] = []string{"false"}
+
+	values := []struct {
+		actions        []string
+		username       string
+		repository     string
+		labels         api.Labels
+		grantedActions []string
+	}{
+		//	member with pull rights requesting to push as well
+		{[]string{"pull", "push"}, "other.com", "is/pqr", authLabels, []string{"pull"}},
+		//	member with push rights requesting all the actions
+		{[]string{"pull", "push", "delete"}, "wso2.com", "cellery/newImage", authLabels,
+			[]string{"pull", "push"}},
+		{[]string{"*", "pull"}, "wso2.com", "cellery/image", authLabels, []string{"pull"}},
+		//	duplicated and unrecognized actions
+		{[]string{"pull", "tag", "pull"}, "admin.com", "cellery/image", authLabels, []string{"pull"}},
+		//	pull only robot account requesting to push
+		{[]string{"push", "pull"}, "robot$cellery+reader", "cellery/image", robotLabels, []string{"pull"}},
+		//	unauthenticated user requesting to push to a public image
+		{[]string{"pull", "push"}, "", "cellery/image", unauthenticatedLabels, []string{"pull"}},
+		{[]string{"pull", "push"}, "other.com", "cellery/newImage", authLabels, nil},
+		//	team member requesting to push and delete an image which the team is allowed to push
+		{[]string{"pull", "push", "delete"}, "team.com", "is/abc", authLabels, []string{"pull", "push"}},
+		//	image collaborator who is not a member of the organization
+		{[]string{"pull", "push", "delete"}, "contractor.com", "cellery/newImage", authLabels,
+			[]string{"pull", "push"}},
+		{[]string{"*"}, "admin.com", "cellery/restricted", authLabels, nil},
+	}
+	logger := zap.NewExample().Sugar()
+	for _, value := range values {
+		decision, err := IsUserAuthorized(aclStore, value.actions, value.username, value.repository,
+			value.labels, logger, testUser)
+		if err != nil {
+			t.Error("Error while validating the access :", err)
+			continue
+		}
+		if strings.Join(decision.GrantedActions, ",") != strings.Join(value.grantedActions, ",") {
+			t.Error("Expected granted actions", value.grantedActions, "but found", decision.GrantedActions, "for",
+				value.username, "requesting", value.actions)
+		}
+	}
+}
+
+func TestDecisionReasons(t *testing.T) {
+	label := make([]string, 1)
+	label[0] = "true"
+	authLabels := api.Labels{}
+	authLabels["isAuthSuccess"] = label
+	robotLabels := api.Labels{}
+	robotLabels["isAuthSuccess"] = label
+	robotLabels[RobotOrganizationLabel] = []string{"cellery"}
+	robotLabels[RobotActionsLabel] = []string{"pull"}
+	pullTokenLabels := api.Labels{}
+	pullTokenLabels["isAuthSuccess"] = label
+	pullTokenLabels[TokenScopesLabel] = []string{"pull"}
+	unauthenticatedLabels := api.Labels{}
+	unauthenticatedLabels["isAuthSuccess"] = []string{"false"}
 
 	values := []struct {
 		actions    []string
 		username   string
 		repository string
 		labels     api.Labels
+		decision   string
 	}{
-		// new user trying to pull a private image
-		{[]string{"pull"}, "user.com", "cellery/newImag", authLabels},
-		//	a user with pull permission trying to push
-		{[]string{"pull", "push"}, "pull.com", "cellery/image", authLabels},
-		//	user trying to pull a public image
-		{[]string{"pull"}, "other.com", "cellery/newImage", authLabels},
-		{[]string{"pull", "push"}, "other.com", "cellery/image", authLabels},
-		{[]string{"pull", "push"}, "other.com", "cellery/pqr", authLabels},
+		{[]string{"pull", "push"}, "wso2.com", "cellery/newImage", authLabels, `account="wso2.com" ` +
+			`repository="cellery/newImage" requested=[pull,push] granted=[pull,push] denied=[] rules=[]`},
+		{[]string{"pull", "push", "delete"}, "other.com", "is/pqr", authLabels, `account="other.com" ` +
+			`repository="is/pqr" requested=[pull,push,delete] granted=[pull] ` +
+			`denied=[push:INSUFFICIENT_ROLE,delete:INSUFFICIENT_ROLE] rules=[]`},
+		{[]string{"pull", "push"}, "user.com", "cellery/newImage", authLabels, `account="user.com" ` +
+			`repository="cellery/newImage" requested=[pull,push] granted=[] ` +
+			`denied=[pull:IMAGE_PRIVATE,push:NOT_MEMBER] rules=[]`},
+		{[]string{"pull"}, "user.com", "cellery/sample", authLabels, `account="user.com" ` +
+			`repository="cellery/sample" requested=[pull] granted=[] denied=[pull:IMAGE_NOT_FOUND] rules=[]`},
+		{[]string{"*"}, "", "cellery/image", unauthenticatedLabels, `account="" repository="cellery/image" ` +
+			`requested=[*] granted=[] denied=[push:UNAUTHENTICATED,delete:UNAUTHENTICATED] rules=[]`},
+		{[]string{"push"}, "robot$cellery+reader", "cellery/image", robotLabels, `account="robot$cellery+reader" ` +
+			`repository="cellery/image" requested=[push] granted=[] denied=[push:ROBOT_NOT_ALLOWED] rules=[]`},
+		{[]string{"push"}, "wso2.com", "cellery/image", pullTokenLabels, `account="wso2.com" ` +
+			`repository="cellery/image" requested=[push] granted=[] denied=[push:OUT_OF_TOKEN_SCOPE] rules=[]`},
+		{[]string{"pull", "push"}, "limited.com", "limited/new", authLabels, `account="limited.com" ` +
+			`repository="limited/new" requested=[pull,push] granted=[pull] denied=[push:IMAGE_QUOTA_EXCEEDED] ` +
+			`rules=[]`},
+		{[]string{"push"}, "wso2.com", "cellery/my_image", authLabels, `account="wso2.com" ` +
+			`repository="cellery/my_image" requested=[push] granted=[] denied=[push:INVALID_NAME] rules=[]`},
+		{[]string{"pull", "tag"}, "wso2.com", "cellery/image", api.Labels{}, `account="wso2.com" ` +
+			`repository="cellery/image" requested=[pull,tag] granted=[] ` +
+			`denied=[pull:UNAUTHENTICATED,tag:UNSUPPORTED_ACTION] rules=[]`},
 	}
 	logger := zap.NewExample().Sugar()
 	for _, value := range values {
-		isAuthorized, err := IsUserAuthorized(dbConnection, value.actions, value.username, value.repository, value.labels,
-			logger, testUser)
+		decision, err := IsUserAuthorized(aclStore, value.actions, value.username, value.repository,
+			value.labels, logger, testUser)
 		if err != nil {
-			log.Println("Error while validating the access token :", err)
+			t.Error("Error while validating the access :", err)
+			continue
 		}
-		if isAuthorized {
-			t.Error("Access is not allowed for username :", value.username)
+		if decision.String() != value.decision {
+			t.Error("Expected the decision", value.decision, "but found", decision.String())
 		}
 	}
 }
 
-func TestIsAuthorizedToPush(t *testing.T) {
+func TestResolveRequiredActions(t *testing.T) {
+	values := []struct {
+		actions            []string
+		requiredActions    []string
+		unsupportedActions []string
+	}{
+		{[]string{"pull"}, []string{"pull"}, nil},
+		{[]string{"push", "pull"}, []string{"pull", "push"}, nil},
+		{[]string{"delete", "pull", "delete"}, []string{"pull", "delete"}, nil},
+		{[]string{"*"}, []string{"pull", "push", "delete"}, nil},
+		{[]string{"pull", "*"}, []string{"pull", "push", "delete"}, nil},
+		{[]string{"tag", "pull"}, []string{"pull"}, []string{"tag"}},
+		{[]string{}, nil, nil},
+	}
+	for _, value := range values {
+		requiredActions, unsupportedActions := ResolveRequiredActions(value.actions)
+		if strings.Join(requiredActions, ",") != strings.Join(value.requiredActions, ",") {
+			t.Error("Expected required actions", value.requiredActions, "but found", requiredActions, "for",
+				value.actions)
+		}
+		if strings.Join(unsupportedActions, ",") != strings.Join(value.unsupportedActions, ",") {
+			t.Error("Expected unsupported actions", value.unsupportedActions, "but found", unsupportedActions,
+				"for", value.actions)
+		}
+	}
+}
+
+func TestIsRoleAuthorized(t *testing.T) {
 	values := []struct {
+		action       string
 		username     string
 		organization string
+		image        string
+		isAuthorized bool
 	}{
-		{"wso2.com", "cellery"},
-		{"admin.com", "cellery"},
+		{"push", "wso2.com", "cellery", "image", true},
+		{"push", "admin.com", "cellery", "image", true},
+		{"delete", "admin.com", "cellery", "newImage", true},
+		{"delete", "wso2.com", "cellery", "image", false},
+		{"push", "other.com", "is", "pqr", false},
+		//	role which is only defined in the database
+		{"delete", "maintainer.com", "is", "pqr", true},
+		{"push", "maintainer.com", "is", "pqr", false},
+		//	role which is not defined in the database
+		{"pull", "unknown.com", "is", "pqr", false},
+		{"push", "wso2.com", "is", "pqr", false},
+		//	user who is not a direct member of the organization with an organization level team grant
+		{"pull", "team.com", "is", "pqr", true},
+		{"push", "team.com", "is", "pqr", false},
+		//	union of the direct role and the team grants
+		{"pull", "maintainer.com", "is", "pqr", true},
+		//	image collaborator through a team
+		{"push", "team.com", "is", "abc", true},
+		{"delete", "team.com", "is", "abc", false},
+		//	image collaborator who is not a member of the organization
+		{"push", "contractor.com", "cellery", "newImage", true},
+		{"delete", "contractor.com", "cellery", "newImage", false},
+		{"pull", "contractor.com", "cellery", "sample", false},
+		//	image level grants overriding the organization level roles
+		{"push", "maintainer.com", "is", "abc", true},
+		{"delete", "maintainer.com", "is", "abc", false},
+		{"pull", "admin.com", "cellery", "restricted", true},
+		{"delete", "admin.com", "cellery", "restricted", false},
+		{"delete", "admin.com", "cellery", "newImage", true},
 	}
 	logger := zap.NewExample().Sugar()
 	for _, value := range values {
-		isAuthorized, err := isAuthorizedToPush(dbConnection, value.username, value.organization, logger, testUser)
-		if !isAuthorized {
-			t.Error("Cannot authorize ", value.username, "for ", value.organization, " organization")
-		}
+		request := newAclRequest(aclStore, value.username, value.organization, value.image)
+		isAuthorized, _, err := isRoleAuthorized(request, value.action, logger, testUser)
 		if err != nil {
-			log.Println("Error while pushing :", err)
+			t.Error("Error while checking the roles :", err)
+		}
+		if isAuthorized != value.isAuthorized {
+			t.Error("Expected authorization", value.isAuthorized, "for", value.username, "to perform",
+				value.action, "on", value.organization+"/"+value.image)
 		}
 	}
 }
@@ -244,62 +544,173 @@ func TestIsAuthorizedToPull(t *testing.T) {
 		username     string
 		organization string
 		image        string
+		isAuthorized bool
+		reason       ReasonCode
 	}{
-		{"wso2.com", "cellery", "image"},
-		{"wso2.com", "cellery", "newImage"},
-		{"ibm.com", "cellery", "image"},
+		{"wso2.com", "cellery", "image", true, ""},
+		{"wso2.com", "cellery", "newImage", true, ""},
+		{"ibm.com", "cellery", "image", true, ""},
+		//	image which is not registered yet in an organization with public default visibility
+		{"ibm.com", "opensource", "newImage", true, ""},
+		{"ibm.com", "cellery", "newImage", false, ReasonImagePrivate},
+		{"ibm.com", "cellery", "sample", false, ReasonImageNotFound},
+		//	member whose role does not allow pulling
+		{"unknown.com", "is", "pqr", false, ReasonInsufficientRole},
 	}
 	logger := zap.NewExample().Sugar()
 	for _, value := range values {
-		isAuthorized, err := isAuthorizedToPull(dbConnection, value.username, value.organization, value.image,
-			logger, testUser)
+		request := newAclRequest(aclStore, value.username, value.organization, value.image)
+		isAuthorized, reason, err := isAuthorizedToPull(request, logger, testUser)
 		if err != nil {
 			log.Println("Error while validating the access token :", err)
 		}
-		if !isAuthorized {
-			t.Error("Cannot authorize ", value.username, "for ", value.organization,
-				" organization in image ", value.username)
+		if isAuthorized != value.isAuthorized {
+			t.Error("Expected authorization", value.isAuthorized, "for", value.username, "to pull",
+				value.organization+"/"+value.image)
+		}
+		if reason != value.reason {
+			t.Error("Expected reason", value.reason, "but found", reason)
 		}
 	}
 }
 
-func TestIsUserAvailable(t *testing.T) {
+func TestIsAuthorizedToPush(t *testing.T) {
+	defer func(names []string) {
+		reservedNames = names
+	}(reservedNames)
+	reservedNames = []string{"cellery", "library"}
 	values := []struct {
-		organization string
 		username     string
+		organization string
+		image        string
+		isAuthorized bool
+		reason       ReasonCode
 	}{
-		{"cellery", "wso2.com"},
-		{"cellery", "admin.com"},
+		//	organization without quotas
+		{"wso2.com", "cellery", "image", true, ""},
+		{"wso2.com", "cellery", "sample", true, ""},
+		{"other.com", "is", "pqr", false, ReasonInsufficientRole},
+		//	organization which has reached the image quota
+		{"limited.com", "limited", "fresh", true, ""},
+		{"limited.com", "limited", "new", false, ReasonImageQuotaExceeded},
+		//	pushes to existing images are within the quotas
+		{"limited.com", "limited", "full", true, ""},
+		//	names of new images which do not satisfy the naming policy
+		{"wso2.com", "cellery", "New-Image", false, ReasonInvalidName},
+		{"wso2.com", "cellery", "image-", false, ReasonInvalidName},
+		{"wso2.com", "cellery", "library", false, ReasonReservedName},
+		{"wso2.com", "cellery", strings.Repeat("a", 256), false, ReasonNameTooLong},
+		{"wso2.com", "cellery", strings.Repeat("a", 255), true, ""},
+		//	names of existing organizations are not validated against the naming policy
+		{"wso2.com", "cellery", "hello", true, ""},
+		//	existing images are not validated against the naming policy
+		{"wso2.com", "cellery", "newImage", true, ""},
+		//	quotas are not evaluated for users who are not allowed to push
+		{"user.com", "limited", "new", false, ReasonNotMember},
 	}
 	logger := zap.NewExample().Sugar()
 	for _, value := range values {
-		isAvailable, err := isUserAvailable(dbConnection, value.organization, value.username, logger, testUser)
-		if !isAvailable {
-			t.Error("For username " + value.username + " user is " + value.organization + " invalid")
+		request := newAclRequest(aclStore, value.username, value.organization, value.image)
+		isAuthorized, reason, err := isAuthorizedToPush(request, logger, testUser)
+		if err != nil {
+			t.Error("Error while checking the push access :", err)
+		}
+		if isAuthorized != value.isAuthorized {
+			t.Error("Expected authorization", value.isAuthorized, "for", value.username, "to push",
+				value.organization+"/"+value.image)
+		}
+		if reason != value.reason {
+			t.Error("Expected reason", value.reason, "but found", reason)
+		}
+	}
+}
+
+func TestValidateName(t *testing.T) {
+	defer func(names []string) {
+		reservedNames = names
+	}(reservedNames)
+	reservedNames = resolveReservedNames("admin, v2,,")
+	values := []struct {
+		name   string
+		reason ReasonCode
+	}{
+		{"hello", ""},
+		{"hello-world-2", ""},
+		{"0", ""},
+		{"", ReasonInvalidName},
+		{"Hello", ReasonInvalidName},
+		{"hello_world", ReasonInvalidName},
+		{"hello--world", ReasonInvalidName},
+		{"-hello", ReasonInvalidName},
+		{"hello.world", ReasonInvalidName},
+		{"v2", ReasonReservedName},
+		{"admin", ReasonReservedName},
+		{"admin-2", ""},
+		{strings.Repeat("a", 256), ReasonNameTooLong},
+	}
+	for _, value := range values {
+		if reason := validateName(value.name); reason != value.reason {
+			t.Error("Expected reason", value.reason, "for the name", value.name, "but found", reason)
 		}
+	}
+}
+
+func TestGetAclRecordRoles(t *testing.T) {
+	values := []struct {
+		organization      string
+		image             string
+		username          string
+		collaboratorRoles []string
+		organizationRoles []string
+	}{
+		{"cellery", "image", "wso2.com", nil, []string{"push"}},
+		{"cellery", "image", "admin.com", nil, []string{"admin"}},
+		{"is", "pqr", "maintainer.com", nil, []string{"maintainer"}},
+		{"is", "pqr", "team.com", nil, []string{"pull"}},
+		{"is", "abc", "team.com", []string{"push"}, []string{"pull"}},
+		{"cellery", "newImage", "contractor.com", []string{"push"}, nil},
+		{"cellery", "image", "user.com", nil, nil},
+	}
+	logger := zap.NewExample().Sugar()
+	for _, value := range values {
+		record, err := aclStore.GetACLRecord(value.username, value.organization, value.image, logger,
+			testUser)
 		if err != nil {
-			log.Println("Error while validating the access token :", err)
+			t.Fatal("Error while retrieving the ACL record :", err)
+		}
+		if strings.Join(record.CollaboratorRoles, ",") != strings.Join(value.collaboratorRoles, ",") ||
+			strings.Join(record.OrganizationRoles, ",") != strings.Join(value.organizationRoles, ",") {
+			t.Error("Expected the collaborator roles", value.collaboratorRoles, "and the organization roles",
+				value.organizationRoles, "for", value.username, "but found", record.CollaboratorRoles, "and",
+				record.OrganizationRoles)
 		}
 	}
 }
 
 func TestGetImageVisibility(t *testing.T) {
 	values := []struct {
-		organization string
-		image        string
-		visib        string
+		organization        string
+		image               string
+		visib               string
+		isImageFound        bool
+		isOrganizationFound bool
 	}{
-		{"cellery", "image", "public"},
-		{"is", "pqr", "private"},
+		{"cellery", "image", "public", true, true},
+		{"is", "pqr", "private", true, true},
+		//	images which are not registered yet
+		{"cellery", "sample", "private", false, true},
+		{"opensource", "newImage", "public", false, true},
+		{"unknown", "image", "", false, false},
 	}
 	logger := zap.NewExample().Sugar()
 	for _, value := range values {
-		visibility, err := getImageVisibility(dbConnection, value.image, value.organization, logger, testUser)
+		record, err := aclStore.GetACLRecord("wso2.com", value.organization, value.image, logger, testUser)
 		if err != nil {
-			log.Println("Error while validating the access token :", err)
+			t.Fatal("Error while retrieving the ACL record :", err)
 		}
-		if !strings.EqualFold(visibility, value.visib) {
-			t.Error("Visibility test fails")
+		if !strings.EqualFold(record.Visibility, value.visib) || record.IsImageFound != value.isImageFound ||
+			record.IsOrganizationFound != value.isOrganizationFound {
+			t.Error("Visibility test fails for", value.organization+"/"+value.image)
 		}
 	}
 }
@@ -312,3 +723,78 @@ func teardown() {
 	}
 	defer dbConnection.Close()
 }
+
+// Requests served by different endpoints use the statements prepared on their own pools, hence switching between
+// the endpoints does not close the statements used by the other requests
+func TestPreparedStatementPerPool(t *testing.T) {
+	dir, err := ioutil.TempDir("", "acl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	otherDb, err := openSqliteTestDb(filepath.Join(dir, "other.db"))
+	if err != nil {
+		t.Fatal("Error while setting up the SQLite database :", err)
+	}
+	defer otherDb.Close()
+	otherStore := NewSQLiteStore(otherDb)
+	logger := zap.NewExample().Sugar()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		for _, store := range []ACLStore{aclStore, otherStore} {
+			wg.Add(1)
+			go func(store ACLStore) {
+				defer wg.Done()
+				record, err := store.GetACLRecord("wso2.com", "cellery", "image", logger, testUser)
+				if err != nil || !record.IsImageFound {
+					t.Error("Expected the ACL record of cellery/image but found error :", err)
+				}
+			}(store)
+		}
+	}
+	wg.Wait()
+
+	err = ReleasePreparedStatements(otherDb)
+	if err != nil {
+		t.Error("Error while releasing the prepared statements :", err)
+	}
+	if _, exists := aclRecordStatement.statements[otherDb]; exists {
+		t.Error("Expected the statement of the released pool to be removed")
+	}
+	_, err = aclStore.GetACLRecord("wso2.com", "cellery", "image", logger, testUser)
+	if err != nil {
+		t.Error("Expected the statement of the other pool to be usable but found error :", err)
+	}
+}
+
+// The roles of the account in an unauthenticated request are not used, since the account is not verified. Hence
+// the decisions of the authenticated requests of the account are not shared with the unauthenticated requests.
+func TestUnauthenticatedPullWithMemberAccount(t *testing.T) {
+	authLabels := api.Labels{"isAuthSuccess": []string{"true"}}
+	unauthenticatedLabels := api.Labels{"isAuthSuccess": []string{"false"}}
+	values := []struct {
+		labels         api.Labels
+		repository     string
+		grantedActions []string
+	}{
+		{authLabels, "cellery/newImage", []string{"pull"}},
+		{unauthenticatedLabels, "cellery/newImage", nil},
+		{authLabels, "cellery/newImage", []string{"pull"}},
+		{unauthenticatedLabels, "cellery/image", []string{"pull"}},
+		//	organization of the member with an image which does not exist yet
+		{unauthenticatedLabels, "cellery/unknown", nil},
+	}
+	logger := zap.NewExample().Sugar()
+	for _, value := range values {
+		decision, err := IsUserAuthorized(aclStore, []string{"pull"}, "wso2.com", value.repository, value.labels,
+			logger, testUser)
+		if err != nil {
+			t.Error("Error while validating the access :", err)
+			continue
+		}
+		if strings.Join(decision.GrantedActions, ",") != strings.Join(value.grantedActions, ",") {
+			t.Error("Expected granted actions", value.grantedActions, "but found", decision.GrantedActions,
+				"for", value.repository, "with the labels", value.labels)
+		}
+	}
+}
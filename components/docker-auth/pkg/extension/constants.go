@@ -18,35 +18,168 @@
 
 package extension
 
-const userAdminRole = "admin"
-const userPushRole = "push"
-
 const MysqlUserEnvVar = "MYSQL_USER"
 const MysqlPasswordEnvVar = "MYSQL_PASSWORD"
 const MysqlHostEnvVar = "MYSQL_HOST"
 const MysqlPortEnvVar = "MYSQL_PORT"
+const MysqlUserFileEnvVar = "MYSQL_USER_FILE"
+const MysqlPasswordFileEnvVar = "MYSQL_PASSWORD_FILE"
+const MysqlTlsEnvVar = "MYSQL_TLS"
+const MysqlCaCertFileEnvVar = "MYSQL_CA_CERT_FILE"
+const MysqlClientCertFileEnvVar = "MYSQL_CLIENT_CERT_FILE"
+const MysqlClientKeyFileEnvVar = "MYSQL_CLIENT_KEY_FILE"
+const MysqlTlsConfigName = "cellery-hub"
 const MysqlDriver = "mysql"
 const DbName = "CELLERY_HUB"
+const DbDriverEnvVar = "DB_DRIVER"
+const PostgresUserEnvVar = "POSTGRES_USER"
+const PostgresPasswordEnvVar = "POSTGRES_PASSWORD"
+const PostgresHostEnvVar = "POSTGRES_HOST"
+const PostgresPortEnvVar = "POSTGRES_PORT"
+const PostgresUserFileEnvVar = "POSTGRES_USER_FILE"
+const PostgresPasswordFileEnvVar = "POSTGRES_PASSWORD_FILE"
+const PostgresSslModeEnvVar = "POSTGRES_SSL_MODE"
+const PostgresSslRootCertFileEnvVar = "POSTGRES_SSL_ROOT_CERT_FILE"
+const PostgresSslCertFileEnvVar = "POSTGRES_SSL_CERT_FILE"
+const PostgresSslKeyFileEnvVar = "POSTGRES_SSL_KEY_FILE"
+const PostgresDriver = "postgres"
+const PostgresDbName = "cellery_hub"
+const DbReplicaHostsEnvVar = "DB_REPLICA_HOSTS"
+const DbHealthCheckIntervalEnvVar = "DB_HEALTH_CHECK_INTERVAL"
+const DefaultDbHealthCheckInterval = 10
 const IdpUsernameEnvVar = "USERNAME"
 const IdppasswordEnvVar = "PASSWORD"
+const IdpConnectTimeoutEnvVar = "IDP_CONNECT_TIMEOUT"
+const IdpReadTimeoutEnvVar = "IDP_READ_TIMEOUT"
+const IdpMaxIdleConnectionsEnvVar = "IDP_MAX_IDLE_CONNECTIONS"
+const IdpCaCertFileEnvVar = "IDP_CA_CERT_FILE"
+const IdpClientCertFileEnvVar = "IDP_CLIENT_CERT_FILE"
+const IdpClientKeyFileEnvVar = "IDP_CLIENT_KEY_FILE"
+const IdpProxyUrlEnvVar = "IDP_PROXY_URL"
+const DefaultIdpConnectTimeout = 5
+const DefaultIdpReadTimeout = 10
+const DefaultIdpMaxIdleConnections = 10
+const IdpMaxRetriesEnvVar = "IDP_MAX_RETRIES"
+const IdpRetryBackoffEnvVar = "IDP_RETRY_BACKOFF"
+const IdpMaxRetryBackoffEnvVar = "IDP_MAX_RETRY_BACKOFF"
+const IdpCircuitBreakerThresholdEnvVar = "IDP_CIRCUIT_BREAKER_THRESHOLD"
+const IdpCircuitBreakerOpenDurationEnvVar = "IDP_CIRCUIT_BREAKER_OPEN_DURATION"
+const DefaultIdpMaxRetries = 2
+const DefaultIdpRetryBackoff = 100
+const DefaultIdpMaxRetryBackoff = 1000
+const DefaultIdpCircuitBreakerThreshold = 5
+const DefaultIdpCircuitBreakerOpenDuration = 30
 
 const MaxOpenConnectionsEnvVar = "MAX_OPEN_CONNECTIONS"
 const MaxIdleConnectionsEnvVar = "MAX_IDLE_CONNECTIONS"
 const ConnectionMaxLifetimeEnvVar = "MAX_LIFE_TIME"
 
+const RoleRefreshIntervalEnvVar = "ROLE_REFRESH_INTERVAL"
+const DefaultRoleRefreshInterval = 60
+
+const PolicyFileEnvVar = "POLICY_FILE"
+
+const AclStoreEnvVar = "ACL_STORE"
+const SqliteFileEnvVar = "SQLITE_FILE"
+const DatabaseAclStore = "database"
+const SqliteAclStore = "sqlite"
+const SqliteDriver = "sqlite3"
+
+const DecisionCacheTtlEnvVar = "DECISION_CACHE_TTL"
+const DecisionCacheMaxSizeEnvVar = "DECISION_CACHE_MAX_SIZE"
+const AclVersionPollIntervalEnvVar = "ACL_VERSION_POLL_INTERVAL"
+const DefaultDecisionCacheTtl = 30
+const DefaultDecisionCacheMaxSize = 10000
+const DefaultAclVersionPollInterval = 5
+
+const TokenCacheMaxSizeEnvVar = "TOKEN_CACHE_MAX_SIZE"
+const TokenCacheTtlEnvVar = "TOKEN_CACHE_TTL"
+const TokenCacheNegativeTtlEnvVar = "TOKEN_CACHE_NEGATIVE_TTL"
+const DefaultTokenCacheMaxSize = 1000
+const DefaultTokenCacheTtl = 300
+const DefaultTokenCacheNegativeTtl = 30
+
+const TokenValidatorsEnvVar = "TOKEN_VALIDATORS"
+const IntrospectionTokenValidator = "introspection"
+const JwtTokenValidator = "jwt"
+const StaticTokenValidator = "static"
+const PersonalAccessTokenValidator = "personal-access-token"
+const DefaultTokenValidators = PersonalAccessTokenValidator + "," + IntrospectionTokenValidator
+const PersonalAccessTokenPrefix = "chpat_"
+const StaticTokenFileEnvVar = "STATIC_TOKEN_FILE"
+const JwksUrlEnvVar = "JWKS_URL"
+const JwksFileEnvVar = "JWKS_FILE"
+const JwksRefreshIntervalEnvVar = "JWKS_REFRESH_INTERVAL"
+const JwtIssuerEnvVar = "JWT_ISSUER"
+const JwtAudienceEnvVar = "JWT_AUDIENCE"
+const JwtUsernameClaimEnvVar = "JWT_USERNAME_CLAIM"
+const DefaultJwksRefreshInterval = 3600
+const DefaultJwtUsernameClaim = "sub"
+
+const RobotUsernamePrefix = "robot$"
+const AuthSuccessLabel = "isAuthSuccess"
+const RobotOrganizationLabel = "robotOrganization"
+const RobotActionsLabel = "robotActions"
+const TokenScopesLabel = "tokenScopes"
+const TokenOrganizationLabel = "tokenOrganization"
+
 const pullAction = "pull"
 const pushAction = "push"
 const deleteAction = "delete"
+const wildcardAction = "*"
 const publicVisibility = "PUBLIC"
+const visibilityRecord = "visibility"
+const collaboratorRecord = "collaborator"
+const organizationRecord = "organization"
+const userCollaborator = "user"
+const teamCollaborator = "team"
+
+var supportedActions = []string{pullAction, pushAction, deleteAction}
+
+// roles available in a new ACL store, matching the roles defined in the Cellery Hub database
+var defaultRolePermissions = map[string][]string{
+	"admin": {pullAction, pushAction, deleteAction},
+	"push":  {pullAction, pushAction},
+	"pull":  {pullAction},
+}
+
+// naming rules of the organizations and images, matching the Cellery naming rules and the database schema. The
+// Cellery naming rules do not reserve any names, hence the reserved names are configured as a comma separated list.
+const celleryIdPattern = "^[a-z0-9]+(-[a-z0-9]+)*$"
+const maxNameLength = 255
+const ReservedNamesEnvVar = "RESERVED_NAMES"
 
 // db queries
-const getVisibilityQuery = "SELECT VISIBILITY FROM REGISTRY_ARTIFACT_IMAGE " +
-	"INNER JOIN REGISTRY_ORGANIZATION ON REGISTRY_ORGANIZATION.ORG_NAME=REGISTRY_ARTIFACT_IMAGE.ORG_NAME " +
-	"WHERE REGISTRY_ARTIFACT_IMAGE.IMAGE_NAME=? AND " +
-	"REGISTRY_ORGANIZATION.ORG_NAME=? LIMIT 1"
-const getUserAvailabilityQuery = "SELECT 1 FROM " +
-	"REGISTRY_ORG_USER_MAPPING " +
-	"WHERE REGISTRY_ORG_USER_MAPPING.USER_UUID=? AND REGISTRY_ORG_USER_MAPPING.ORG_NAME=?"
-const getUserRoleQuery = "SELECT USER_ROLE FROM " +
-	"REGISTRY_ORG_USER_MAPPING " +
-	"WHERE REGISTRY_ORG_USER_MAPPING.USER_UUID=? AND REGISTRY_ORG_USER_MAPPING.ORG_NAME=?"
+const getAclRecordQuery = "SELECT '" + visibilityRecord + "', " +
+	"COALESCE(REGISTRY_ARTIFACT_IMAGE.VISIBILITY, REGISTRY_ORGANIZATION.DEFAULT_IMAGE_VISIBILITY), " +
+	"CASE WHEN REGISTRY_ARTIFACT_IMAGE.VISIBILITY IS NULL THEN 0 ELSE 1 END FROM REGISTRY_ORGANIZATION " +
+	"LEFT JOIN REGISTRY_ARTIFACT_IMAGE ON REGISTRY_ORGANIZATION.ORG_NAME=REGISTRY_ARTIFACT_IMAGE.ORG_NAME " +
+	"AND REGISTRY_ARTIFACT_IMAGE.IMAGE_NAME=? " +
+	"WHERE REGISTRY_ORGANIZATION.ORG_NAME=? " +
+	"UNION ALL SELECT '" + collaboratorRecord + "', REGISTRY_IMAGE_COLLABORATOR.ROLE_NAME, 0 " +
+	"FROM REGISTRY_IMAGE_COLLABORATOR " +
+	"WHERE REGISTRY_IMAGE_COLLABORATOR.ORG_NAME=? AND REGISTRY_IMAGE_COLLABORATOR.IMAGE_NAME=? AND " +
+	"((REGISTRY_IMAGE_COLLABORATOR.COLLABORATOR_TYPE='user' AND REGISTRY_IMAGE_COLLABORATOR.COLLABORATOR_NAME=?) " +
+	"OR (REGISTRY_IMAGE_COLLABORATOR.COLLABORATOR_TYPE='team' AND REGISTRY_IMAGE_COLLABORATOR.COLLABORATOR_NAME IN " +
+	"(SELECT TEAM_NAME FROM REGISTRY_TEAM_USER_MAPPING " +
+	"WHERE REGISTRY_TEAM_USER_MAPPING.ORG_NAME=? AND REGISTRY_TEAM_USER_MAPPING.USER_UUID=?))) " +
+	"UNION ALL SELECT '" + organizationRecord + "', REGISTRY_ORG_USER_MAPPING.USER_ROLE, 0 " +
+	"FROM REGISTRY_ORG_USER_MAPPING " +
+	"WHERE REGISTRY_ORG_USER_MAPPING.USER_UUID=? AND REGISTRY_ORG_USER_MAPPING.ORG_NAME=? " +
+	"UNION ALL SELECT '" + organizationRecord + "', REGISTRY_TEAM_PERMISSION.ROLE_NAME, 0 " +
+	"FROM REGISTRY_TEAM_PERMISSION " +
+	"INNER JOIN REGISTRY_TEAM_USER_MAPPING ON REGISTRY_TEAM_USER_MAPPING.ORG_NAME=REGISTRY_TEAM_PERMISSION.ORG_NAME " +
+	"AND REGISTRY_TEAM_USER_MAPPING.TEAM_NAME=REGISTRY_TEAM_PERMISSION.TEAM_NAME " +
+	"WHERE REGISTRY_TEAM_USER_MAPPING.USER_UUID=? AND REGISTRY_TEAM_PERMISSION.ORG_NAME=?"
+const getAclVersionQuery = "SELECT VERSION FROM REGISTRY_ACL_VERSION WHERE REGISTRY_ACL_VERSION.ID=1"
+const getRolePermissionsQuery = "SELECT ROLE_NAME, ACTION FROM REGISTRY_ROLE_PERMISSION"
+const getOrganizationQuotaQuery = "SELECT REGISTRY_ORG_QUOTA.MAX_IMAGES, " +
+	"(SELECT COUNT(*) FROM REGISTRY_ARTIFACT_IMAGE WHERE REGISTRY_ARTIFACT_IMAGE.ORG_NAME=?) " +
+	"FROM REGISTRY_ORG_QUOTA WHERE REGISTRY_ORG_QUOTA.ORG_NAME=?"
+const GetRobotAccountQuery = "SELECT SECRET_HASH, ACTIONS FROM REGISTRY_ROBOT_ACCOUNT " +
+	"WHERE REGISTRY_ROBOT_ACCOUNT.ORG_NAME=? AND REGISTRY_ROBOT_ACCOUNT.ROBOT_NAME=? AND " +
+	"(REGISTRY_ROBOT_ACCOUNT.EXPIRES_AT IS NULL OR REGISTRY_ROBOT_ACCOUNT.EXPIRES_AT > CURRENT_TIMESTAMP)"
+const GetPersonalAccessTokenQuery = "SELECT USER_UUID, SCOPES, COALESCE(ORG_NAME, ''), EXPIRES_AT " +
+	"FROM REGISTRY_PERSONAL_ACCESS_TOKEN WHERE REGISTRY_PERSONAL_ACCESS_TOKEN.TOKEN_HASH=? AND " +
+	"(REGISTRY_PERSONAL_ACCESS_TOKEN.EXPIRES_AT IS NULL OR " +
+	"REGISTRY_PERSONAL_ACCESS_TOKEN.EXPIRES_AT > CURRENT_TIMESTAMP)"
@@ -0,0 +1,138 @@
+/*
+ * Copyright (c) 2019 WSO2 Inc. (http:www.wso2.org) All Rights Reserved.
+ *
+ * WSO2 Inc. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http:www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package extension
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ReasonCode explains why an action was denied
+type ReasonCode string
+
+const (
+	ReasonUnsupportedAction  ReasonCode = "UNSUPPORTED_ACTION"
+	ReasonUnauthenticated    ReasonCode = "UNAUTHENTICATED"
+	ReasonRobotNotAllowed    ReasonCode = "ROBOT_NOT_ALLOWED"
+	ReasonOutOfTokenScope    ReasonCode = "OUT_OF_TOKEN_SCOPE"
+	ReasonNotMember          ReasonCode = "NOT_MEMBER"
+	ReasonInsufficientRole   ReasonCode = "INSUFFICIENT_ROLE"
+	ReasonImagePrivate       ReasonCode = "IMAGE_PRIVATE"
+	ReasonImageNotFound      ReasonCode = "IMAGE_NOT_FOUND"
+	ReasonPolicyDenied       ReasonCode = "POLICY_DENIED"
+	ReasonImageQuotaExceeded ReasonCode = "IMAGE_QUOTA_EXCEEDED"
+	ReasonInvalidName        ReasonCode = "INVALID_NAME"
+	ReasonNameTooLong        ReasonCode = "NAME_TOO_LONG"
+	ReasonReservedName       ReasonCode = "RESERVED_NAME"
+)
+
+// Decision explains the outcome of an authorization request. The granted actions are the requested actions
+// which are allowed, in the order they were requested. Each denied action carries the reason and the policy rule
+// which denied it, if any.
+type Decision struct {
+	Account          string
+	Repository       string
+	RequestedActions []string
+	GrantedActions   []string
+	DeniedActions    []DeniedAction
+	MatchedRules     []string
+}
+
+type DeniedAction struct {
+	Action string
+	Reason ReasonCode
+	Rule   string
+}
+
+// ActionOutcome is the outcome of authorizing a single action along with the policy rule which decided it, if any
+type ActionOutcome struct {
+	IsGranted bool
+	Reason    ReasonCode
+	Rule      string
+}
+
+// NewDecision creates the decision for the requested actions from the outcomes of the required actions
+func NewDecision(account string, repository string, requestedActions []string,
+	outcomes map[string]ActionOutcome) *Decision {
+	decision := &Decision{
+		Account:          account,
+		Repository:       repository,
+		RequestedActions: requestedActions,
+	}
+	requiredActions, unsupportedActions := ResolveRequiredActions(requestedActions)
+	isActionGranted := make(map[string]bool)
+	for _, action := range requiredActions {
+		outcome := outcomes[action]
+		if len(outcome.Rule) > 0 && !containsString(decision.MatchedRules, outcome.Rule) {
+			decision.MatchedRules = append(decision.MatchedRules, outcome.Rule)
+		}
+		if outcome.IsGranted {
+			isActionGranted[action] = true
+			continue
+		}
+		decision.DeniedActions = append(decision.DeniedActions, DeniedAction{
+			Action: action,
+			Reason: outcome.Reason,
+			Rule:   outcome.Rule,
+		})
+	}
+	for _, action := range unsupportedActions {
+		decision.DeniedActions = append(decision.DeniedActions, DeniedAction{
+			Action: action,
+			Reason: ReasonUnsupportedAction,
+		})
+	}
+	decision.GrantedActions = ResolveGrantedActions(requestedActions, isActionGranted)
+	return decision
+}
+
+// Outcomes returns the outcomes of the supported actions in the decision
+func (d *Decision) Outcomes() map[string]ActionOutcome {
+	outcomes := make(map[string]ActionOutcome)
+	for _, action := range d.GrantedActions {
+		if action == wildcardAction {
+			for _, supportedAction := range supportedActions {
+				outcomes[supportedAction] = ActionOutcome{IsGranted: true}
+			}
+		} else {
+			outcomes[action] = ActionOutcome{IsGranted: true}
+		}
+	}
+	for _, deniedAction := range d.DeniedActions {
+		if deniedAction.Reason != ReasonUnsupportedAction {
+			outcomes[deniedAction.Action] = ActionOutcome{Reason: deniedAction.Reason, Rule: deniedAction.Rule}
+		}
+	}
+	return outcomes
+}
+
+// String formats the decision as a single line
+func (d *Decision) String() string {
+	deniedActions := make([]string, 0, len(d.DeniedActions))
+	for _, deniedAction := range d.DeniedActions {
+		deniedActionText := deniedAction.Action + ":" + string(deniedAction.Reason)
+		if len(deniedAction.Rule) > 0 {
+			deniedActionText += ":" + deniedAction.Rule
+		}
+		deniedActions = append(deniedActions, deniedActionText)
+	}
+	return fmt.Sprintf("account=%q repository=%q requested=[%s] granted=[%s] denied=[%s] rules=[%s]",
+		d.Account, d.Repository, strings.Join(d.RequestedActions, ","), strings.Join(d.GrantedActions, ","),
+		strings.Join(deniedActions, ","), strings.Join(d.MatchedRules, ","))
+}
@@ -0,0 +1,60 @@
+/*
+ * Copyright (c) 2019 WSO2 Inc. (http:www.wso2.org) All Rights Reserved.
+ *
+ * WSO2 Inc. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http:www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package extension
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ResolveDbDriver returns the driver of the Cellery Hub database selected through the environment. MySQL is used
+// if a driver is not selected.
+func ResolveDbDriver() (string, error) {
+	driver := os.Getenv(DbDriverEnvVar)
+	switch driver {
+	case "":
+		return MysqlDriver, nil
+	case MysqlDriver, PostgresDriver:
+		return driver, nil
+	default:
+		return "", fmt.Errorf("unsupported database driver %s", driver)
+	}
+}
+
+// RebindQuery converts the ? placeholders of the query into the placeholders of the driver. PostgreSQL uses
+// numbered placeholders while MySQL and SQLite use ? placeholders as they are. The queries of the plugin do not
+// contain ? characters other than the placeholders.
+func RebindQuery(driver string, query string) string {
+	if driver != PostgresDriver {
+		return query
+	}
+	var rebound strings.Builder
+	position := 0
+	for _, char := range query {
+		if char == '?' {
+			position++
+			rebound.WriteString("$" + strconv.Itoa(position))
+		} else {
+			rebound.WriteRune(char)
+		}
+	}
+	return rebound.String()
+}
@@ -0,0 +1,72 @@
+/*
+ * Copyright (c) 2019 WSO2 Inc. (http:www.wso2.org) All Rights Reserved.
+ *
+ * WSO2 Inc. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http:www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package extension
+
+import (
+	"os"
+	"testing"
+)
+
+func TestResolveDbDriver(t *testing.T) {
+	defer os.Unsetenv(DbDriverEnvVar)
+	var testData = []struct {
+		driver         string
+		expectedDriver string
+		expectedError  bool
+	}{
+		{"", MysqlDriver, false},
+		{MysqlDriver, MysqlDriver, false},
+		{PostgresDriver, PostgresDriver, false},
+		{"oracle", "", true},
+	}
+	for _, data := range testData {
+		err := os.Setenv(DbDriverEnvVar, data.driver)
+		if err != nil {
+			t.Fatal("Error setting up the environment :", err)
+		}
+		driver, err := ResolveDbDriver()
+		if (err != nil) != data.expectedError {
+			t.Error("Unexpected error while resolving the driver", data.driver, ":", err)
+		}
+		if driver != data.expectedDriver {
+			t.Error("Expected the driver", data.expectedDriver, "for", data.driver, "but found", driver)
+		}
+	}
+}
+
+func TestRebindQuery(t *testing.T) {
+	query := "SELECT VERSION FROM REGISTRY_ARTIFACT WHERE ORG_NAME=? AND IMAGE_NAME=?"
+	var testData = []struct {
+		driver        string
+		expectedQuery string
+	}{
+		{MysqlDriver, query},
+		{SqliteDriver, query},
+		{PostgresDriver, "SELECT VERSION FROM REGISTRY_ARTIFACT WHERE ORG_NAME=$1 AND IMAGE_NAME=$2"},
+	}
+	for _, data := range testData {
+		rebound := RebindQuery(data.driver, query)
+		if rebound != data.expectedQuery {
+			t.Error("Expected the query", data.expectedQuery, "for", data.driver, "but found", rebound)
+		}
+	}
+	if RebindQuery(PostgresDriver, getAclRecordQuery) == getAclRecordQuery {
+		t.Error("Expected the placeholders of the ACL record query to be rebound")
+	}
+}
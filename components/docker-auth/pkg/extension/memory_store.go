@@ -0,0 +1,246 @@
+/*
+ * Copyright (c) 2019 WSO2 Inc. (http:www.wso2.org) All Rights Reserved.
+ *
+ * WSO2 Inc. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http:www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package extension
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// MemoryStore holds the ACL data in memory. The data is populated through the methods of the store, each of which
+// increments the version of the store so that the cached decisions are discarded.
+type MemoryStore struct {
+	mutex           sync.RWMutex
+	organizations   map[string]*memoryOrganization
+	rolePermissions map[string][]string
+	version         int64
+}
+
+type memoryOrganization struct {
+	defaultVisibility string
+	members           map[string]string
+	teams             map[string]*memoryTeam
+	images            map[string]*memoryImage
+	collaborators     map[string][]memoryCollaborator
+	quota             *OrganizationQuota
+}
+
+type memoryTeam struct {
+	users []string
+	roles []string
+}
+
+type memoryImage struct {
+	visibility string
+}
+
+type memoryCollaborator struct {
+	collaboratorType string
+	name             string
+	role             string
+}
+
+// NewMemoryStore returns an empty store which only holds the default roles
+func NewMemoryStore() *MemoryStore {
+	rolePermissions := make(map[string][]string)
+	for role, actions := range defaultRolePermissions {
+		rolePermissions[role] = append([]string(nil), actions...)
+	}
+	return &MemoryStore{
+		organizations:   make(map[string]*memoryOrganization),
+		rolePermissions: rolePermissions,
+	}
+}
+
+// SetRolePermissions defines the actions allowed for the role, replacing the actions previously allowed
+func (s *MemoryStore) SetRolePermissions(role string, actions ...string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.rolePermissions[role] = append([]string(nil), actions...)
+	s.version++
+}
+
+// AddOrganization adds an organization with the visibility used for the images which are not registered yet
+func (s *MemoryStore) AddOrganization(organization string, defaultVisibility string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.organizations[organization] = &memoryOrganization{
+		defaultVisibility: defaultVisibility,
+		members:           make(map[string]string),
+		teams:             make(map[string]*memoryTeam),
+		images:            make(map[string]*memoryImage),
+		collaborators:     make(map[string][]memoryCollaborator),
+	}
+	s.version++
+}
+
+// AddMember assigns a role in the organization to the user, replacing the role previously assigned
+func (s *MemoryStore) AddMember(organization string, user string, role string) error {
+	return s.update(organization, func(org *memoryOrganization) error {
+		org.members[user] = role
+		return nil
+	})
+}
+
+// AddTeam adds a team with the users as its members
+func (s *MemoryStore) AddTeam(organization string, team string, users ...string) error {
+	return s.update(organization, func(org *memoryOrganization) error {
+		org.teams[team] = &memoryTeam{users: append([]string(nil), users...)}
+		return nil
+	})
+}
+
+// AddTeamPermission grants the role to all the members of the team on all the images of the organization
+func (s *MemoryStore) AddTeamPermission(organization string, team string, role string) error {
+	return s.update(organization, func(org *memoryOrganization) error {
+		memoryTeam, exists := org.teams[team]
+		if !exists {
+			return fmt.Errorf("team %s not found in the organization %s", team, organization)
+		}
+		memoryTeam.roles = append(memoryTeam.roles, role)
+		return nil
+	})
+}
+
+// AddImage registers an image in the organization
+func (s *MemoryStore) AddImage(organization string, image string, visibility string) error {
+	return s.update(organization, func(org *memoryOrganization) error {
+		org.images[image] = &memoryImage{visibility: visibility}
+		return nil
+	})
+}
+
+// AddCollaborator grants the role on the image to a user or to all the members of a team. The collaborator type
+// should be either user or team.
+func (s *MemoryStore) AddCollaborator(organization string, image string, collaboratorType string, name string,
+	role string) error {
+	if collaboratorType != userCollaborator && collaboratorType != teamCollaborator {
+		return fmt.Errorf("invalid collaborator type %s", collaboratorType)
+	}
+	return s.update(organization, func(org *memoryOrganization) error {
+		org.collaborators[image] = append(org.collaborators[image], memoryCollaborator{
+			collaboratorType: collaboratorType,
+			name:             name,
+			role:             role,
+		})
+		return nil
+	})
+}
+
+// SetQuota sets the quotas of the organization. A quota which is not valid is unlimited.
+func (s *MemoryStore) SetQuota(organization string, maxImages sql.NullInt64) error {
+	return s.update(organization, func(org *memoryOrganization) error {
+		org.quota = &OrganizationQuota{MaxImages: maxImages}
+		return nil
+	})
+}
+
+func (s *MemoryStore) update(organization string, apply func(org *memoryOrganization) error) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	org, exists := s.organizations[organization]
+	if !exists {
+		return fmt.Errorf("organization %s not found", organization)
+	}
+	if err := apply(org); err != nil {
+		return err
+	}
+	s.version++
+	return nil
+}
+
+// GetACLRecord returns the visibility of the image along with the roles of the user. The default image
+// visibility of the organization is used for images which are not yet registered.
+func (s *MemoryStore) GetACLRecord(user string, organization string, image string, logger *zap.SugaredLogger,
+	execId string) (*ACLRecord, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	record := &ACLRecord{}
+	org, exists := s.organizations[organization]
+	if !exists {
+		logger.Debugf("[%s] Organization %s not found in the memory store", execId, organization)
+		return record, nil
+	}
+	record.IsOrganizationFound = true
+	record.Visibility = org.defaultVisibility
+	if memoryImage, exists := org.images[image]; exists {
+		record.Visibility = memoryImage.visibility
+		record.IsImageFound = true
+	}
+	for _, collaborator := range org.collaborators[image] {
+		if (collaborator.collaboratorType == userCollaborator && collaborator.name == user) ||
+			(collaborator.collaboratorType == teamCollaborator && org.isTeamMember(collaborator.name, user)) {
+			record.CollaboratorRoles = append(record.CollaboratorRoles, collaborator.role)
+		}
+	}
+	if role, exists := org.members[user]; exists {
+		record.OrganizationRoles = append(record.OrganizationRoles, role)
+	}
+	for team := range org.teams {
+		if !org.isTeamMember(team, user) {
+			continue
+		}
+		for _, role := range org.teams[team].roles {
+			if !containsString(record.OrganizationRoles, role) {
+				record.OrganizationRoles = append(record.OrganizationRoles, role)
+			}
+		}
+	}
+	return record, nil
+}
+
+func (o *memoryOrganization) isTeamMember(team string, user string) bool {
+	memoryTeam, exists := o.teams[team]
+	return exists && containsString(memoryTeam.users, user)
+}
+
+// GetRolePermissions returns the actions allowed for each role
+func (s *MemoryStore) GetRolePermissions(logger *zap.SugaredLogger, execId string) (map[string][]string, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	permissions := make(map[string][]string)
+	for role, actions := range s.rolePermissions {
+		permissions[role] = append([]string(nil), actions...)
+	}
+	return permissions, nil
+}
+
+// GetOrganizationQuota returns the quotas of the organization along with the current usage
+func (s *MemoryStore) GetOrganizationQuota(organization string, logger *zap.SugaredLogger,
+	execId string) (*OrganizationQuota, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	org, exists := s.organizations[organization]
+	if !exists || org.quota == nil {
+		return nil, nil
+	}
+	quota := *org.quota
+	quota.ImageCount = int64(len(org.images))
+	return &quota, nil
+}
+
+// GetVersion returns the number of changes made to the store
+func (s *MemoryStore) GetVersion(logger *zap.SugaredLogger, execId string) (int64, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.version, nil
+}
@@ -0,0 +1,140 @@
+/*
+ * Copyright (c) 2019 WSO2 Inc. (http:www.wso2.org) All Rights Reserved.
+ *
+ * WSO2 Inc. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http:www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package extension
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/cesanta/docker_auth/auth_server/api"
+	"go.uber.org/zap"
+)
+
+func newTestMemoryStore(t *testing.T) *MemoryStore {
+	store := NewMemoryStore()
+	store.AddOrganization("cellery", "private")
+	store.AddOrganization("opensource", "public")
+	store.SetRolePermissions("maintainer", "pull", "delete")
+	for _, err := range []error{
+		store.AddMember("cellery", "wso2.com", "push"),
+		store.AddMember("cellery", "admin.com", "admin"),
+		store.AddMember("cellery", "maintainer.com", "maintainer"),
+		store.AddTeam("cellery", "readers", "team.com"),
+		store.AddTeamPermission("cellery", "readers", "pull"),
+		store.AddImage("cellery", "image", "PUBLIC"),
+		store.AddImage("cellery", "private", "PRIVATE"),
+		store.AddCollaborator("cellery", "private", "user", "contractor.com", "push"),
+		store.AddCollaborator("cellery", "restricted", "team", "readers", "pull"),
+		store.SetQuota("cellery", sql.NullInt64{Int64: 2, Valid: true}),
+	} {
+		if err != nil {
+			t.Fatal("Error while populating the memory store :", err)
+		}
+	}
+	return store
+}
+
+func TestMemoryStoreAuthorization(t *testing.T) {
+	store := newTestMemoryStore(t)
+	authLabels := api.Labels{AuthSuccessLabel: []string{"true"}}
+	values := []struct {
+		actions    []string
+		username   string
+		repository string
+		decision   string
+	}{
+		{[]string{"pull", "push"}, "user.com", "cellery/image", `account="user.com" repository="cellery/image" ` +
+			`requested=[pull,push] granted=[pull] denied=[push:NOT_MEMBER] rules=[]`},
+		{[]string{"pull", "push"}, "user.com", "cellery/private", `account="user.com" ` +
+			`repository="cellery/private" requested=[pull,push] granted=[] ` +
+			`denied=[pull:IMAGE_PRIVATE,push:NOT_MEMBER] rules=[]`},
+		{[]string{"pull"}, "user.com", "opensource/sample", `account="user.com" repository="opensource/sample" ` +
+			`requested=[pull] granted=[pull] denied=[] rules=[]`},
+		{[]string{"*"}, "admin.com", "cellery/image", `account="admin.com" repository="cellery/image" ` +
+			`requested=[*] granted=[*] denied=[] rules=[]`},
+		{[]string{"pull", "delete"}, "maintainer.com", "cellery/private", `account="maintainer.com" ` +
+			`repository="cellery/private" requested=[pull,delete] granted=[pull,delete] denied=[] rules=[]`},
+		{[]string{"pull", "push"}, "team.com", "cellery/private", `account="team.com" ` +
+			`repository="cellery/private" requested=[pull,push] granted=[pull] denied=[push:INSUFFICIENT_ROLE] ` +
+			`rules=[]`},
+		//	image collaborators
+		{[]string{"pull", "push"}, "contractor.com", "cellery/private", `account="contractor.com" ` +
+			`repository="cellery/private" requested=[pull,push] granted=[pull,push] denied=[] rules=[]`},
+		{[]string{"pull", "push"}, "team.com", "cellery/restricted", `account="team.com" ` +
+			`repository="cellery/restricted" requested=[pull,push] granted=[pull] denied=[push:INSUFFICIENT_ROLE] ` +
+			`rules=[]`},
+		//	quotas of the organization
+		{[]string{"push"}, "wso2.com", "cellery/new", `account="wso2.com" repository="cellery/new" ` +
+			`requested=[push] granted=[] denied=[push:IMAGE_QUOTA_EXCEEDED] rules=[]`},
+	}
+	logger := zap.NewExample().Sugar()
+	for _, value := range values {
+		decision, err := IsUserAuthorized(store, value.actions, value.username, value.repository, authLabels,
+			logger, testUser)
+		if err != nil {
+			t.Error("Error while validating the access :", err)
+			continue
+		}
+		if decision.String() != value.decision {
+			t.Error("Expected the decision", value.decision, "but found", decision.String())
+		}
+	}
+}
+
+func TestMemoryStoreRecord(t *testing.T) {
+	store := newTestMemoryStore(t)
+	logger := zap.NewExample().Sugar()
+	record, err := store.GetACLRecord("team.com", "cellery", "restricted", logger, testUser)
+	if err != nil {
+		t.Fatal("Error while retrieving the ACL record :", err)
+	}
+	if !record.IsOrganizationFound || record.IsImageFound || !strings.EqualFold(record.Visibility, "private") ||
+		strings.Join(record.CollaboratorRoles, ",") != "pull" || strings.Join(record.OrganizationRoles, ",") != "pull" {
+		t.Error("Unexpected ACL record for an image which is not registered", record)
+	}
+	record, err = store.GetACLRecord("wso2.com", "unknown", "image", logger, testUser)
+	if err != nil {
+		t.Fatal("Error while retrieving the ACL record :", err)
+	}
+	if record.IsOrganizationFound || len(record.Roles()) > 0 {
+		t.Error("Expected an empty ACL record for an organization which does not exist", record)
+	}
+	if err = store.AddMember("unknown", "wso2.com", "push"); err == nil {
+		t.Error("Expected an error when adding a member to an organization which does not exist")
+	}
+	if err = store.AddCollaborator("cellery", "image", "group", "readers", "pull"); err == nil {
+		t.Error("Expected an error for an invalid collaborator type")
+	}
+}
+
+func TestMemoryStoreVersion(t *testing.T) {
+	store := newTestMemoryStore(t)
+	logger := zap.NewExample().Sugar()
+	version, _ := store.GetVersion(logger, testUser)
+	if err := store.AddImage("cellery", "new", "PRIVATE"); err != nil {
+		t.Fatal("Error while adding the image :", err)
+	}
+	if updatedVersion, _ := store.GetVersion(logger, testUser); updatedVersion <= version {
+		t.Error("Expected the version to change when the store is updated")
+	}
+	if err := store.AddImage("unknown", "new", "PRIVATE"); err == nil {
+		t.Error("Expected an error when adding an image to an organization which does not exist")
+	}
+}
@@ -0,0 +1,85 @@
+/*
+ * Copyright (c) 2019 WSO2 Inc. (http:www.wso2.org) All Rights Reserved.
+ *
+ * WSO2 Inc. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http:www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package extension
+
+import (
+	"os"
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+var celleryIdRegex = regexp.MustCompile(celleryIdPattern)
+var reservedNames = resolveReservedNames(os.Getenv(ReservedNamesEnvVar))
+
+// resolveReservedNames returns the names in the comma separated list which cannot be used for new organizations and
+// images
+func resolveReservedNames(names string) []string {
+	var reserved []string
+	for _, name := range strings.Split(names, ",") {
+		if name = strings.TrimSpace(name); len(name) > 0 {
+			reserved = append(reserved, name)
+		}
+	}
+	return reserved
+}
+
+// validateName checks a name of an organization or an image against the naming rules. The reason is returned if
+// the name is not valid.
+func validateName(name string) ReasonCode {
+	if len(name) > maxNameLength {
+		return ReasonNameTooLong
+	}
+	if !celleryIdRegex.MatchString(name) {
+		return ReasonInvalidName
+	}
+	if containsString(reservedNames, name) {
+		return ReasonReservedName
+	}
+	return ""
+}
+
+// isNamingPolicySatisfied checks the organization and image names of a push which creates a new image. Images
+// which already exist are not validated since they were registered before the naming rules were enforced. For the
+// same reason only the image name is validated if the organization already exists.
+func isNamingPolicySatisfied(request *aclRequest, logger *zap.SugaredLogger, execId string) (bool, ReasonCode,
+	error) {
+	record, err := request.getRecord(logger, execId)
+	if err != nil {
+		return false, "", err
+	}
+	if record.IsImageFound {
+		return true, "", nil
+	}
+	if !record.IsOrganizationFound {
+		if reason := validateName(request.organization); reason != "" {
+			logger.Infof("[%s] Organization name %q does not satisfy the naming policy : %s", execId,
+				request.organization, reason)
+			return false, reason, nil
+		}
+	}
+	if reason := validateName(request.image); reason != "" {
+		logger.Infof("[%s] Image name %q does not satisfy the naming policy : %s", execId, request.image, reason)
+		return false, reason, nil
+	}
+	logger.Debugf("[%s] Name of the new image %s/%s satisfies the naming policy", execId, request.organization,
+		request.image)
+	return true, "", nil
+}
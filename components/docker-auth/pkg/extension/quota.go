@@ -0,0 +1,60 @@
+/*
+ * Copyright (c) 2019 WSO2 Inc. (http:www.wso2.org) All Rights Reserved.
+ *
+ * WSO2 Inc. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http:www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package extension
+
+import (
+	"database/sql"
+
+	"go.uber.org/zap"
+)
+
+// OrganizationQuota holds the quotas of an organization along with the number of images in the organization. A
+// quota which is not set is unlimited.
+type OrganizationQuota struct {
+	MaxImages  sql.NullInt64
+	ImageCount int64
+}
+
+// isWithinQuota checks whether a push to the image is allowed by the quotas of the organization. A push which
+// creates a new image is denied once the organization holds the maximum number of images. Pushes to existing
+// images are always within the quotas.
+func isWithinQuota(request *aclRequest, logger *zap.SugaredLogger, execId string) (bool, ReasonCode, error) {
+	record, err := request.getRecord(logger, execId)
+	if err != nil {
+		return false, "", err
+	}
+	if record.IsImageFound {
+		return true, "", nil
+	}
+	quota, err := request.store.GetOrganizationQuota(request.organization, logger, execId)
+	if err != nil {
+		return false, "", &StoreError{Err: err}
+	}
+	if quota == nil {
+		return true, "", nil
+	}
+	if quota.MaxImages.Valid && quota.ImageCount >= quota.MaxImages.Int64 {
+		logger.Infof("[%s] Organization %s has reached the quota of %d images. Hence the image %s cannot "+
+			"be created", execId, request.organization, quota.MaxImages.Int64, request.image)
+		return false, ReasonImageQuotaExceeded, nil
+	}
+	logger.Debugf("[%s] Push to the image %s/%s is within the quotas of the organization", execId,
+		request.organization, request.image)
+	return true, "", nil
+}
@@ -0,0 +1,81 @@
+/*
+ * Copyright (c) 2019 WSO2 Inc. (http:www.wso2.org) All Rights Reserved.
+ *
+ * WSO2 Inc. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http:www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package extension
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// roleStore holds the actions allowed for each role as defined in the ACL store. The roles are loaded lazily and
+// reloaded once the refresh interval elapses or the ACL store changes, hence new roles can be introduced without
+// restarting the plugin.
+type roleStore struct {
+	mutex           sync.Mutex
+	store           ACLStore
+	permissions     map[string][]string
+	lastRefresh     time.Time
+	refreshInterval time.Duration
+	now             func() time.Time
+}
+
+var roles = newRoleStore(time.Duration(resolveNonNegativeIntEnvVar(RoleRefreshIntervalEnvVar,
+	DefaultRoleRefreshInterval)) * time.Second)
+
+func newRoleStore(refreshInterval time.Duration) *roleStore {
+	return &roleStore{
+		refreshInterval: refreshInterval,
+		now:             time.Now,
+	}
+}
+
+// getActions returns the actions allowed for the role. If the roles cannot be reloaded the previously loaded
+// roles are used until the next refresh.
+func (r *roleStore) getActions(store ACLStore, role string, logger *zap.SugaredLogger, execId string) ([]string,
+	error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.permissions == nil || r.store != store || r.now().Sub(r.lastRefresh) >= r.refreshInterval {
+		r.store = store
+		permissions, err := store.GetRolePermissions(logger, execId)
+		if err != nil {
+			if r.permissions == nil {
+				return nil, &StoreError{Err: err}
+			}
+			logger.Errorf("[%s] Using the previously loaded roles since reloading failed :%s", execId, err)
+		} else {
+			r.permissions = permissions
+		}
+		r.lastRefresh = r.now()
+	}
+	actions, exists := r.permissions[role]
+	if !exists {
+		logger.Warnf("[%s] Role %s is not defined in the database", execId, role)
+	}
+	return actions, nil
+}
+
+// invalidate makes the roles to be reloaded on the next lookup. The loaded roles are still used if reloading fails.
+func (r *roleStore) invalidate() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.lastRefresh = time.Time{}
+}
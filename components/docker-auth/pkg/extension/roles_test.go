@@ -0,0 +1,63 @@
+/*
+ * Copyright (c) 2019 WSO2 Inc. (http:www.wso2.org) All Rights Reserved.
+ *
+ * WSO2 Inc. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http:www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package extension
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestRoleStoreRefresh(t *testing.T) {
+	logger := zap.NewExample().Sugar()
+	currentTime := time.Now()
+	store := newRoleStore(time.Minute)
+	store.now = func() time.Time {
+		return currentTime
+	}
+	actions, err := store.getActions(aclStore, "push", logger, testUser)
+	if err != nil {
+		t.Fatal("Error while loading the roles :", err)
+	}
+	if strings.Join(actions, ",") != "pull,push" && strings.Join(actions, ",") != "push,pull" {
+		t.Error("Expected the push role to allow pull and push actions but found", actions)
+	}
+
+	unavailableDb, err := sql.Open(MysqlDriver, "root:mysql@tcp(localhost:1)/"+DbName)
+	if err != nil {
+		t.Fatal("Error while creating the database handle :", err)
+	}
+	defer unavailableDb.Close()
+	currentTime = currentTime.Add(2 * time.Minute)
+	actions, err = store.getActions(NewMySQLStore(unavailableDb), "admin", logger, testUser)
+	if err != nil {
+		t.Error("Expected the previously loaded roles to be used but found error :", err)
+	}
+	if len(actions) != 3 {
+		t.Error("Expected the admin role to allow all the actions but found", actions)
+	}
+
+	_, err = newRoleStore(time.Minute).getActions(NewMySQLStore(unavailableDb), "admin", logger, testUser)
+	if err == nil {
+		t.Error("Expected an error when the roles were never loaded")
+	}
+}
@@ -0,0 +1,214 @@
+/*
+ * Copyright (c) 2019 WSO2 Inc. (http:www.wso2.org) All Rights Reserved.
+ *
+ * WSO2 Inc. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http:www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package extension
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+
+	_ "github.com/go-sql-driver/mysql"
+	"go.uber.org/zap"
+)
+
+// sqlStore retrieves the ACL data from the Cellery Hub database. The queries are portable apart from the
+// placeholders, which are converted for the driver, hence the same store is used for MySQL, PostgreSQL and SQLite.
+// The store is used as a value, hence the stores created for the same connection pool are equal.
+type sqlStore struct {
+	db     *sql.DB
+	driver string
+}
+
+// NewMySQLStore returns a store which retrieves the ACL data from the MySQL database of Cellery Hub
+func NewMySQLStore(db *sql.DB) ACLStore {
+	return sqlStore{db: db, driver: MysqlDriver}
+}
+
+// NewPostgresStore returns a store which retrieves the ACL data from the PostgreSQL database of Cellery Hub
+func NewPostgresStore(db *sql.DB) ACLStore {
+	return sqlStore{db: db, driver: PostgresDriver}
+}
+
+// NewSQLiteStore returns a store which retrieves the ACL data from an SQLite database with the tables of Cellery Hub.
+// The SQLite driver is registered by the sqlite package, which opens the database.
+func NewSQLiteStore(db *sql.DB) ACLStore {
+	return sqlStore{db: db, driver: SqliteDriver}
+}
+
+// preparedStatement holds the statements prepared on each connection pool with the placeholders of the driver.
+// Requests served concurrently by different endpoints use the statements of their own pools, hence a statement lives
+// as long as its pool and is closed only when the pool is released through ReleasePreparedStatements.
+type preparedStatement struct {
+	mutex      sync.Mutex
+	query      string
+	statements map[*sql.DB]*sql.Stmt
+}
+
+var aclRecordStatement = newPreparedStatement(getAclRecordQuery)
+var organizationQuotaStatement = newPreparedStatement(getOrganizationQuotaQuery)
+var preparedStatements = []*preparedStatement{aclRecordStatement, organizationQuotaStatement}
+
+func newPreparedStatement(query string) *preparedStatement {
+	return &preparedStatement{query: query, statements: make(map[*sql.DB]*sql.Stmt)}
+}
+
+func (p *preparedStatement) get(db *sql.DB, driver string, logger *zap.SugaredLogger, execId string) (*sql.Stmt,
+	error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if statement, exists := p.statements[db]; exists {
+		return statement, nil
+	}
+	logger.Debugf("[%s] Preparing the statement on the connection pool", execId)
+	statement, err := db.Prepare(RebindQuery(driver, p.query))
+	if err != nil {
+		return nil, err
+	}
+	p.statements[db] = statement
+	return statement, nil
+}
+
+func (p *preparedStatement) release(db *sql.DB) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	statement, exists := p.statements[db]
+	if !exists {
+		return nil
+	}
+	delete(p.statements, db)
+	return statement.Close()
+}
+
+// ReleasePreparedStatements closes the statements prepared on the connection pool, hence it should be called when
+// the pool is closed
+func ReleasePreparedStatements(db *sql.DB) error {
+	var releaseErr error
+	for _, statement := range preparedStatements {
+		if err := statement.release(db); err != nil && releaseErr == nil {
+			releaseErr = fmt.Errorf("error while closing the prepared statement :%s", err)
+		}
+	}
+	return releaseErr
+}
+
+// GetACLRecord retrieves the visibility of the image along with the roles of the user in a single round trip. The
+// default image visibility of the organization is used for images which are not yet registered.
+func (s sqlStore) GetACLRecord(user string, organization string, image string, logger *zap.SugaredLogger,
+	execId string) (*ACLRecord, error) {
+	logger.Debugf("[%s] Retrieving the ACL record of the user %s for the image %s/%s", execId, user,
+		organization, image)
+	statement, err := aclRecordStatement.get(s.db, s.driver, logger, execId)
+	if err != nil {
+		return nil, fmt.Errorf("error while preparing the query getAclRecordQuery :%s", err)
+	}
+	results, err := statement.Query(image, organization, organization, image, user, organization, user, user,
+		organization, user, organization)
+	defer func() {
+		closeResultSet(results, "GetACLRecord", logger, execId)
+	}()
+	if err != nil {
+		return nil, fmt.Errorf("error while executing the query getAclRecordQuery :%s", err)
+	}
+	record := &ACLRecord{}
+	for results.Next() {
+		var recordType, value string
+		var isImageFound bool
+		err = results.Scan(&recordType, &value, &isImageFound)
+		if err != nil {
+			return nil, fmt.Errorf("[%s] Error in retrieving the ACL record for %s/%s from the database :%s",
+				execId, organization, image, err)
+		}
+		switch recordType {
+		case visibilityRecord:
+			record.IsOrganizationFound = true
+			record.Visibility = value
+			record.IsImageFound = isImageFound
+		case collaboratorRecord:
+			record.CollaboratorRoles = append(record.CollaboratorRoles, value)
+		case organizationRecord:
+			if !containsString(record.OrganizationRoles, value) {
+				record.OrganizationRoles = append(record.OrganizationRoles, value)
+			}
+		}
+	}
+	if err = results.Err(); err != nil {
+		return nil, fmt.Errorf("[%s] Error while iterating the ACL record :%s", execId, err)
+	}
+	logger.Debugf("[%s] ACL record of the user %s for the image %s/%s : visibility %s, image found %t, "+
+		"collaborator roles %s, organization roles %s", execId, user, organization, image, record.Visibility,
+		record.IsImageFound, record.CollaboratorRoles, record.OrganizationRoles)
+	return record, nil
+}
+
+// GetRolePermissions retrieves the actions allowed for each role as defined in the REGISTRY_ROLE_PERMISSION table
+func (s sqlStore) GetRolePermissions(logger *zap.SugaredLogger, execId string) (map[string][]string, error) {
+	logger.Debugf("[%s] Loading the role permissions from the database", execId)
+	results, err := s.db.Query(getRolePermissionsQuery)
+	defer func() {
+		closeResultSet(results, "GetRolePermissions", logger, execId)
+	}()
+	if err != nil {
+		return nil, fmt.Errorf("error while executing the query getRolePermissionsQuery :%s", err)
+	}
+	permissions := make(map[string][]string)
+	for results.Next() {
+		var role, action string
+		err = results.Scan(&role, &action)
+		if err != nil {
+			return nil, fmt.Errorf("error while retrieving the role permissions from the database :%s", err)
+		}
+		permissions[role] = append(permissions[role], action)
+	}
+	if err = results.Err(); err != nil {
+		return nil, fmt.Errorf("error while iterating the role permissions :%s", err)
+	}
+	logger.Debugf("[%s] Loaded %d roles from the database", execId, len(permissions))
+	return permissions, nil
+}
+
+// GetOrganizationQuota retrieves the quotas of the organization as defined in the REGISTRY_ORG_QUOTA table
+func (s sqlStore) GetOrganizationQuota(organization string, logger *zap.SugaredLogger,
+	execId string) (*OrganizationQuota, error) {
+	logger.Debugf("[%s] Retrieving the quotas of the organization %s", execId, organization)
+	statement, err := organizationQuotaStatement.get(s.db, s.driver, logger, execId)
+	if err != nil {
+		return nil, fmt.Errorf("error while preparing the query getOrganizationQuotaQuery :%s", err)
+	}
+	quota := &OrganizationQuota{}
+	err = statement.QueryRow(organization, organization).Scan(&quota.MaxImages, &quota.ImageCount)
+	if err == sql.ErrNoRows {
+		logger.Debugf("[%s] Organization %s does not have any quotas", execId, organization)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error while retrieving the quotas of the organization %s :%s", organization, err)
+	}
+	return quota, nil
+}
+
+// GetVersion retrieves the version in the REGISTRY_ACL_VERSION table, which is incremented by the database
+// triggers whenever the memberships, roles, grants, visibilities or quotas change
+func (s sqlStore) GetVersion(logger *zap.SugaredLogger, execId string) (int64, error) {
+	var version int64
+	err := s.db.QueryRow(getAclVersionQuery).Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("error while executing the query getAclVersionQuery :%s", err)
+	}
+	return version, nil
+}
@@ -0,0 +1,38 @@
+/*
+ * Copyright (c) 2019 WSO2 Inc. (http:www.wso2.org) All Rights Reserved.
+ *
+ * WSO2 Inc. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http:www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package migrations
+
+const schemaVersionTable = "REGISTRY_SCHEMA_VERSION"
+
+// The statements of the schema version table are portable across MySQL, PostgreSQL and SQLite
+const createSchemaVersionTableQuery = "CREATE TABLE IF NOT EXISTS " + schemaVersionTable + " (" +
+	"VERSION INT NOT NULL, DESCRIPTION VARCHAR(255) NOT NULL, CHECKSUM CHAR(64) NOT NULL, " +
+	"APPLIED_DATE TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, PRIMARY KEY (VERSION))"
+const getAppliedMigrationsQuery = "SELECT VERSION, CHECKSUM FROM " + schemaVersionTable + " ORDER BY VERSION"
+const insertAppliedMigrationQuery = "INSERT INTO " + schemaVersionTable +
+	" (VERSION, DESCRIPTION, CHECKSUM) VALUES (?, ?, ?)"
+
+// getBaselineTableQuery fails unless the database has the tables of the first migration
+const getBaselineTableQuery = "SELECT COUNT(*) FROM REGISTRY_ORGANIZATION"
+
+// migration states reported by the status command
+const StateApplied = "applied"
+const StatePending = "pending"
+const StateModified = "modified"
+const StateUnknown = "unknown"
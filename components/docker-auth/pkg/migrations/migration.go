@@ -0,0 +1,282 @@
+/*
+ * Copyright (c) 2019 WSO2 Inc. (http:www.wso2.org) All Rights Reserved.
+ *
+ * WSO2 Inc. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http:www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package migrations
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/cellery-io/cellery-hub/components/docker-auth/pkg/extension"
+)
+
+// Migration is a single change to the schema of the Cellery Hub database. The statements are provided for each of
+// the supported database drivers and are executed in order. A migration must never be changed once it is released,
+// since the checksums of the applied migrations are verified against the migrations known to the plugin.
+type Migration struct {
+	Version     int
+	Description string
+	Statements  map[string][]string
+}
+
+// MigrationStatus is the state of a migration in the database
+type MigrationStatus struct {
+	Version     int
+	Description string
+	State       string
+}
+
+// Checksum returns the SHA-256 checksum of the statements of the migration for the driver
+func (m *Migration) Checksum(driver string) string {
+	hash := sha256.Sum256([]byte(strings.Join(m.Statements[driver], "\n;\n")))
+	return hex.EncodeToString(hash[:])
+}
+
+// LatestVersion returns the schema version the plugin is built for
+func LatestVersion() int {
+	return latestVersion(schemaMigrations)
+}
+
+// Up applies the pending migrations to the database in order and returns the number of migrations applied
+func Up(db *sql.DB, driver string, logger *zap.SugaredLogger) (int, error) {
+	return up(db, driver, schemaMigrations, logger)
+}
+
+// Status returns the state of each migration known to the plugin along with the migrations applied to the database
+// by a newer release
+func Status(db *sql.DB, driver string, logger *zap.SugaredLogger) ([]MigrationStatus, error) {
+	return status(db, driver, schemaMigrations, logger)
+}
+
+// Baseline records the first migration as applied without executing its statements. Databases created before the
+// migrations were introduced already have the tables of the first migration, hence they are baselined once before the
+// pending migrations are applied.
+func Baseline(db *sql.DB, driver string, logger *zap.SugaredLogger) error {
+	return baseline(db, driver, schemaMigrations, logger)
+}
+
+// CheckSchemaVersion verifies that the database is at the schema version the plugin is built for and that the
+// applied migrations have not been modified
+func CheckSchemaVersion(db *sql.DB, driver string, logger *zap.SugaredLogger) error {
+	return checkSchemaVersion(db, driver, schemaMigrations, logger)
+}
+
+func latestVersion(migrations []Migration) int {
+	if len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].Version
+}
+
+func up(db *sql.DB, driver string, migrations []Migration, logger *zap.SugaredLogger) (int, error) {
+	_, err := db.Exec(createSchemaVersionTableQuery)
+	if err != nil {
+		return 0, fmt.Errorf("error while creating the schema version table :%s", err)
+	}
+	applied, err := getAppliedMigrations(db, logger)
+	if err != nil {
+		return 0, err
+	}
+	err = verifyAppliedMigrations(applied, driver, migrations)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for i := range migrations {
+		migration := &migrations[i]
+		if _, isApplied := applied[migration.Version]; isApplied {
+			continue
+		}
+		err = applyMigration(db, driver, migration, logger)
+		if err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// applyMigration executes the statements of the migration and records it in a single transaction. MySQL commits
+// DDL statements implicitly, hence a failed migration may be left partially applied in MySQL.
+func applyMigration(db *sql.DB, driver string, migration *Migration, logger *zap.SugaredLogger) error {
+	statements, hasStatements := migration.Statements[driver]
+	if !hasStatements {
+		return fmt.Errorf("migration %d does not support the database driver %s", migration.Version, driver)
+	}
+	logger.Debugf("Applying the migration %d : %s", migration.Version, migration.Description)
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("error while starting the transaction of the migration %d :%s", migration.Version, err)
+	}
+	for _, statement := range statements {
+		_, err = tx.Exec(statement)
+		if err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("error while applying the migration %d :%s", migration.Version, err)
+		}
+	}
+	_, err = tx.Exec(extension.RebindQuery(driver, insertAppliedMigrationQuery), migration.Version,
+		migration.Description, migration.Checksum(driver))
+	if err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("error while recording the migration %d :%s", migration.Version, err)
+	}
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("error while committing the migration %d :%s", migration.Version, err)
+	}
+	logger.Infof("Applied the migration %d : %s", migration.Version, migration.Description)
+	return nil
+}
+
+func baseline(db *sql.DB, driver string, migrations []Migration, logger *zap.SugaredLogger) error {
+	_, err := db.Exec(createSchemaVersionTableQuery)
+	if err != nil {
+		return fmt.Errorf("error while creating the schema version table :%s", err)
+	}
+	applied, err := getAppliedMigrations(db, logger)
+	if err != nil {
+		return err
+	}
+	if len(applied) > 0 {
+		return fmt.Errorf("the database already has applied migrations, hence it cannot be baselined")
+	}
+	var count int
+	err = db.QueryRow(getBaselineTableQuery).Scan(&count)
+	if err != nil {
+		return fmt.Errorf("error while reading the tables of the baseline schema, the tables should be created by "+
+			"applying the migrations instead :%s", err)
+	}
+	migration := &migrations[0]
+	_, err = db.Exec(extension.RebindQuery(driver, insertAppliedMigrationQuery), migration.Version,
+		migration.Description, migration.Checksum(driver))
+	if err != nil {
+		return fmt.Errorf("error while recording the migration %d :%s", migration.Version, err)
+	}
+	logger.Infof("Baselined the database at the migration %d : %s", migration.Version, migration.Description)
+	return nil
+}
+
+func status(db *sql.DB, driver string, migrations []Migration, logger *zap.SugaredLogger) ([]MigrationStatus,
+	error) {
+	applied, err := getAppliedMigrations(db, logger)
+	if err != nil {
+		return nil, err
+	}
+	var statuses []MigrationStatus
+	known := make(map[int]bool)
+	for i := range migrations {
+		migration := &migrations[i]
+		known[migration.Version] = true
+		state := StatePending
+		if checksum, isApplied := applied[migration.Version]; isApplied {
+			state = StateApplied
+			if checksum != migration.Checksum(driver) {
+				state = StateModified
+			}
+		}
+		statuses = append(statuses, MigrationStatus{Version: migration.Version,
+			Description: migration.Description, State: state})
+	}
+	for version := range applied {
+		if !known[version] {
+			statuses = append(statuses, MigrationStatus{Version: version, State: StateUnknown})
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+	return statuses, nil
+}
+
+func checkSchemaVersion(db *sql.DB, driver string, migrations []Migration, logger *zap.SugaredLogger) error {
+	applied, err := getAppliedMigrations(db, logger)
+	if err != nil {
+		return err
+	}
+	err = verifyAppliedMigrations(applied, driver, migrations)
+	if err != nil {
+		return err
+	}
+	version := 0
+	for appliedVersion := range applied {
+		if appliedVersion > version {
+			version = appliedVersion
+		}
+	}
+	if version < latestVersion(migrations) {
+		return fmt.Errorf("schema version %d of the database is older than the supported version %d, "+
+			"the pending migrations should be applied", version, latestVersion(migrations))
+	}
+	logger.Debugf("Schema version %d of the database is supported", version)
+	return nil
+}
+
+// verifyAppliedMigrations fails if the database has migrations which are not known to the plugin or migrations
+// which were modified after they were applied
+func verifyAppliedMigrations(applied map[int]string, driver string, migrations []Migration) error {
+	known := make(map[int]*Migration)
+	for i := range migrations {
+		known[migrations[i].Version] = &migrations[i]
+	}
+	for version, checksum := range applied {
+		migration, isKnown := known[version]
+		if !isKnown {
+			return fmt.Errorf("schema version %d of the database is not supported, the latest supported "+
+				"version is %d", version, latestVersion(migrations))
+		}
+		if checksum != migration.Checksum(driver) {
+			return fmt.Errorf("checksum of the applied migration %d does not match the migration", version)
+		}
+	}
+	return nil
+}
+
+func getAppliedMigrations(db *sql.DB, logger *zap.SugaredLogger) (map[int]string, error) {
+	results, err := db.Query(getAppliedMigrationsQuery)
+	if err != nil {
+		return nil, fmt.Errorf("error while reading the applied migrations, the migrations may not have been "+
+			"applied to the database. A database created before the migrations were introduced should be "+
+			"baselined before applying the migrations :%s", err)
+	}
+	defer func() {
+		if err := results.Close(); err != nil {
+			logger.Errorf("Error while closing the applied migrations :%s", err)
+		}
+	}()
+	applied := make(map[int]string)
+	for results.Next() {
+		var version int
+		var checksum string
+		err = results.Scan(&version, &checksum)
+		if err != nil {
+			return nil, fmt.Errorf("error while reading the applied migrations :%s", err)
+		}
+		applied[version] = strings.TrimSpace(checksum)
+	}
+	if err = results.Err(); err != nil {
+		return nil, fmt.Errorf("error while iterating the applied migrations :%s", err)
+	}
+	return applied, nil
+}
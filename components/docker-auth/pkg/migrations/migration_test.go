@@ -0,0 +1,295 @@
+/*
+ * Copyright (c) 2019 WSO2 Inc. (http:www.wso2.org) All Rights Reserved.
+ *
+ * WSO2 Inc. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http:www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package migrations
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"go.uber.org/zap"
+
+	"github.com/cellery-io/cellery-hub/components/docker-auth/pkg/extension"
+)
+
+var testMigrations = []Migration{
+	{
+		Version:     1,
+		Description: "Create the organization table",
+		Statements: map[string][]string{
+			extension.SqliteDriver: {"CREATE TABLE REGISTRY_ORGANIZATION (ORG_NAME VARCHAR(255) PRIMARY KEY)"},
+		},
+	},
+	{
+		Version:     2,
+		Description: "Create the team table",
+		Statements: map[string][]string{
+			extension.SqliteDriver: {
+				"CREATE TABLE REGISTRY_TEAM (ORG_NAME VARCHAR(255), TEAM_NAME VARCHAR(255))",
+				"INSERT INTO REGISTRY_ORGANIZATION (ORG_NAME) VALUES ('cellery')",
+			},
+		},
+	},
+}
+
+func openTestDatabase(t *testing.T) (*sql.DB, func()) {
+	dir, err := ioutil.TempDir("", "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open(extension.SqliteDriver, filepath.Join(dir, "hub.db"))
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal("Error while opening the database :", err)
+	}
+	return db, func() {
+		db.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestSchemaMigrations(t *testing.T) {
+	for i, migration := range schemaMigrations {
+		if migration.Version != i+1 {
+			t.Error("Expected the migration", i+1, "but found the migration", migration.Version)
+		}
+		for _, driver := range []string{extension.MysqlDriver, extension.PostgresDriver, extension.SqliteDriver} {
+			if len(migration.Statements[driver]) == 0 {
+				t.Error("Migration", migration.Version, "does not have statements for", driver)
+			}
+		}
+	}
+	// Released migrations must never change, since the checksums are verified against the applied migrations
+	checksums := map[int]map[string]string{
+		1: {
+			extension.MysqlDriver:    "804a08c6472cba63f1bc214b354c5e7cfc7ceddd2f28510204be13c10129a386",
+			extension.PostgresDriver: "92f77a65f3a57febd2d83293a422581b0b2cc2ee1b8771136f55411c223e07ca",
+		},
+		2: {
+			extension.MysqlDriver:    "39b0ce41aab5eee3480d9e396da17282b11222efce9e9e1fcb0c1eba5eb62823",
+			extension.PostgresDriver: "4e965d0b796ee6ddb2cbd565704ee674e71af4a92d2ab1a6895c2cef36ae3a66",
+		},
+		3: {
+			extension.MysqlDriver:    "297f04edcb7ed0c0cbed3b92372d2df60b50645fe709d911689812d9624793e9",
+			extension.PostgresDriver: "0023eee413bb36002a4f05dfe4f904a820890373c25952c56853d04b3b5de494",
+		},
+		4: {
+			extension.MysqlDriver:    "f2dde538b33d7516402593e5e3cf2f834289790e57c7441c271119fd6bb2c024",
+			extension.PostgresDriver: "998432ff85e44978f598defc9a895e188ddc71da7ddb7013c0d5f2e2bb801315",
+		},
+		5: {
+			extension.MysqlDriver:    "9b66cf1e98995c4566971b7590a16f2f8aca1eea14d8c53bc800cd424b32a521",
+			extension.PostgresDriver: "c26931c47ea469a4c0d4644295000ba88f884c15750e163fb8167b4dcb31ae2c",
+		},
+		6: {
+			extension.MysqlDriver:    "74cc8037e89b6f19612b759cf554b695b9b785911571aec3ed31b8b9db06a0f8",
+			extension.PostgresDriver: "1bfc5582b86c7eb2f73532ea1d22a3589fe40bc2c44c9673c936fbe4fe972bbd",
+		},
+		7: {
+			extension.MysqlDriver:    "9be35dade01397e0b8e6ba9947b06ac91fe31014b3044f35738657a07191847c",
+			extension.PostgresDriver: "5cd5c9fc50c3a9b1e2a0656eed4cfd7804ddfe681bbb736797c5924431b03406",
+		},
+		8: {
+			extension.MysqlDriver:    "a61a8c327f614fc448f11e59e693ad2d8cca4d38391bc9eebf97571554917d03",
+			extension.PostgresDriver: "66bd4d493dbf292a0dc764c6ce0255cbe0146fe56e97699fa8578a95bf13e80e",
+		},
+	}
+	for version, driverChecksums := range checksums {
+		for driver, checksum := range driverChecksums {
+			if schemaMigrations[version-1].Checksum(driver) != checksum {
+				t.Error("Checksum of the released migration", version, "for", driver, "has changed")
+			}
+		}
+	}
+}
+
+func TestMigrateUp(t *testing.T) {
+	db, cleanup := openTestDatabase(t)
+	defer cleanup()
+	logger := zap.NewExample().Sugar()
+
+	_, err := db.Exec(createSchemaVersionTableQuery)
+	if err != nil {
+		t.Fatal("Error while creating the schema version table :", err)
+	}
+	statuses, err := status(db, extension.SqliteDriver, testMigrations, logger)
+	if err != nil {
+		t.Fatal("Error while reading the status :", err)
+	}
+	if len(statuses) != 2 || statuses[0].State != StatePending || statuses[1].State != StatePending {
+		t.Error("Expected both the migrations to be pending but found", statuses)
+	}
+
+	count, err := up(db, extension.SqliteDriver, testMigrations[:1], logger)
+	if err != nil || count != 1 {
+		t.Fatal("Expected the first migration to be applied but found", count, err)
+	}
+	err = checkSchemaVersion(db, extension.SqliteDriver, testMigrations, logger)
+	if err == nil {
+		t.Error("Expected the schema version check to fail with a pending migration")
+	}
+
+	count, err = up(db, extension.SqliteDriver, testMigrations, logger)
+	if err != nil || count != 1 {
+		t.Fatal("Expected the second migration to be applied but found", count, err)
+	}
+	var organization string
+	err = db.QueryRow("SELECT ORG_NAME FROM REGISTRY_ORGANIZATION").Scan(&organization)
+	if err != nil || organization != "cellery" {
+		t.Error("Expected the statements of the second migration to be executed but found", organization, err)
+	}
+	err = checkSchemaVersion(db, extension.SqliteDriver, testMigrations, logger)
+	if err != nil {
+		t.Error("Expected the schema version to be supported but found error :", err)
+	}
+
+	count, err = up(db, extension.SqliteDriver, testMigrations, logger)
+	if err != nil || count != 0 {
+		t.Error("Expected no migrations to be applied again but found", count, err)
+	}
+	statuses, err = status(db, extension.SqliteDriver, testMigrations, logger)
+	if err != nil {
+		t.Fatal("Error while reading the status :", err)
+	}
+	if len(statuses) != 2 || statuses[0].State != StateApplied || statuses[1].State != StateApplied {
+		t.Error("Expected both the migrations to be applied but found", statuses)
+	}
+}
+
+func TestBaseline(t *testing.T) {
+	db, cleanup := openTestDatabase(t)
+	defer cleanup()
+	logger := zap.NewExample().Sugar()
+
+	err := baseline(db, extension.SqliteDriver, testMigrations, logger)
+	if err == nil {
+		t.Error("Expected the baseline to fail for a database without the tables of the first migration")
+	}
+
+	// A database created before the migrations were introduced has the tables of the first migration
+	_, err = db.Exec("CREATE TABLE REGISTRY_ORGANIZATION (ORG_NAME VARCHAR(255) PRIMARY KEY)")
+	if err != nil {
+		t.Fatal("Error while creating the organization table :", err)
+	}
+	err = baseline(db, extension.SqliteDriver, testMigrations, logger)
+	if err != nil {
+		t.Fatal("Error while baselining the database :", err)
+	}
+	statuses, err := status(db, extension.SqliteDriver, testMigrations, logger)
+	if err != nil {
+		t.Fatal("Error while reading the status :", err)
+	}
+	if len(statuses) != 2 || statuses[0].State != StateApplied || statuses[1].State != StatePending {
+		t.Error("Expected only the first migration to be applied but found", statuses)
+	}
+	count, err := up(db, extension.SqliteDriver, testMigrations, logger)
+	if err != nil || count != 1 {
+		t.Fatal("Expected only the second migration to be applied but found", count, err)
+	}
+	err = checkSchemaVersion(db, extension.SqliteDriver, testMigrations, logger)
+	if err != nil {
+		t.Error("Expected the schema version to be supported but found error :", err)
+	}
+
+	err = baseline(db, extension.SqliteDriver, testMigrations, logger)
+	if err == nil {
+		t.Error("Expected the baseline to fail for a database with applied migrations")
+	}
+}
+
+func TestMigrateUpFailure(t *testing.T) {
+	db, cleanup := openTestDatabase(t)
+	defer cleanup()
+	logger := zap.NewExample().Sugar()
+
+	failingMigrations := append(testMigrations[:1:1], Migration{
+		Version:     2,
+		Description: "Insert into a missing table",
+		Statements: map[string][]string{
+			extension.SqliteDriver: {
+				"CREATE TABLE REGISTRY_TEAM (ORG_NAME VARCHAR(255), TEAM_NAME VARCHAR(255))",
+				"INSERT INTO REGISTRY_MISSING (ORG_NAME) VALUES ('cellery')",
+			},
+		},
+	})
+	count, err := up(db, extension.SqliteDriver, failingMigrations, logger)
+	if err == nil || count != 1 {
+		t.Fatal("Expected the second migration to fail but found", count, err)
+	}
+	statuses, err := status(db, extension.SqliteDriver, failingMigrations, logger)
+	if err != nil {
+		t.Fatal("Error while reading the status :", err)
+	}
+	if statuses[0].State != StateApplied || statuses[1].State != StatePending {
+		t.Error("Expected the failed migration to be pending but found", statuses)
+	}
+	_, err = db.Exec("SELECT * FROM REGISTRY_TEAM")
+	if err == nil {
+		t.Error("Expected the statements of the failed migration to be rolled back")
+	}
+
+	_, err = up(db, extension.MysqlDriver, testMigrations, logger)
+	if err == nil {
+		t.Error("Expected the migrations to fail for a driver without statements")
+	}
+}
+
+func TestSchemaVersionCheck(t *testing.T) {
+	db, cleanup := openTestDatabase(t)
+	defer cleanup()
+	logger := zap.NewExample().Sugar()
+
+	err := checkSchemaVersion(db, extension.SqliteDriver, testMigrations, logger)
+	if err == nil {
+		t.Error("Expected the schema version check to fail without the schema version table")
+	}
+	_, err = up(db, extension.SqliteDriver, testMigrations, logger)
+	if err != nil {
+		t.Fatal("Error while applying the migrations :", err)
+	}
+
+	// A plugin built for an older schema version refuses the newer schema
+	err = checkSchemaVersion(db, extension.SqliteDriver, testMigrations[:1], logger)
+	if err == nil {
+		t.Error("Expected the schema version check to fail for an unknown schema version")
+	}
+	statuses, err := status(db, extension.SqliteDriver, testMigrations[:1], logger)
+	if err != nil {
+		t.Fatal("Error while reading the status :", err)
+	}
+	if len(statuses) != 2 || statuses[1].State != StateUnknown {
+		t.Error("Expected the second migration to be unknown but found", statuses)
+	}
+
+	modifiedMigrations := []Migration{testMigrations[0], testMigrations[1]}
+	modifiedMigrations[1].Statements = map[string][]string{
+		extension.SqliteDriver: {"CREATE TABLE REGISTRY_TEAM (ORG_NAME VARCHAR(255))"},
+	}
+	err = checkSchemaVersion(db, extension.SqliteDriver, modifiedMigrations, logger)
+	if err == nil {
+		t.Error("Expected the schema version check to fail for a modified migration")
+	}
+	_, err = up(db, extension.SqliteDriver, modifiedMigrations, logger)
+	if err == nil {
+		t.Error("Expected the migrations to fail for a modified migration")
+	}
+}
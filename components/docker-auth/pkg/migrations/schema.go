@@ -0,0 +1,816 @@
+/*
+ * Copyright (c) 2019 WSO2 Inc. (http:www.wso2.org) All Rights Reserved.
+ *
+ * WSO2 Inc. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http:www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package migrations
+
+import (
+	"github.com/cellery-io/cellery-hub/components/docker-auth/pkg/extension"
+)
+
+// schemaMigrations are the migrations of the Cellery Hub database ordered by the version. The first migration is the
+// schema which existed before the migrations were introduced, hence such databases are baselined at that version.
+// New migrations should be appended with the next version.
+var schemaMigrations = []Migration{
+	{
+		Version:     1,
+		Description: "Create the CELLERY_HUB tables",
+		Statements:  baselineTables,
+	},
+	{
+		Version:     2,
+		Description: "Create the robot account table",
+		Statements:  robotAccountTables,
+	},
+	{
+		Version:     3,
+		Description: "Create the personal access token table",
+		Statements:  personalAccessTokenTables,
+	},
+	{
+		Version:     4,
+		Description: "Create the role permission table with the default roles",
+		Statements:  rolePermissionTables,
+	},
+	{
+		Version:     5,
+		Description: "Create the team tables",
+		Statements:  teamTables,
+	},
+	{
+		Version:     6,
+		Description: "Create the image collaborator table",
+		Statements:  imageCollaboratorTables,
+	},
+	{
+		Version:     7,
+		Description: "Create the ACL version table and the triggers which increment it",
+		Statements:  withAclVersionTriggers(aclVersionTables, aclVersionTriggers),
+	},
+	{
+		Version:     8,
+		Description: "Create the organization quota table",
+		Statements:  withAclVersionTriggers(organizationQuotaTables, organizationQuotaAclVersionTriggers),
+	},
+}
+
+// aclVersionTrigger increments the ACL version when the data used for authorization decisions changes
+type aclVersionTrigger struct {
+	name      string
+	event     string
+	table     string
+	condition string
+}
+
+var aclVersionTriggers = []aclVersionTrigger{
+	{"ORGANIZATION_UPDATE_ACL_VERSION", "UPDATE", "REGISTRY_ORGANIZATION",
+		"NEW.DEFAULT_IMAGE_VISIBILITY <> OLD.DEFAULT_IMAGE_VISIBILITY"},
+	{"ORGANIZATION_DELETE_ACL_VERSION", "DELETE", "REGISTRY_ORGANIZATION", ""},
+	{"ORG_USER_MAPPING_INSERT_ACL_VERSION", "INSERT", "REGISTRY_ORG_USER_MAPPING", ""},
+	{"ORG_USER_MAPPING_UPDATE_ACL_VERSION", "UPDATE", "REGISTRY_ORG_USER_MAPPING", ""},
+	{"ORG_USER_MAPPING_DELETE_ACL_VERSION", "DELETE", "REGISTRY_ORG_USER_MAPPING", ""},
+	{"ARTIFACT_IMAGE_INSERT_ACL_VERSION", "INSERT", "REGISTRY_ARTIFACT_IMAGE", ""},
+	{"ARTIFACT_IMAGE_UPDATE_ACL_VERSION", "UPDATE", "REGISTRY_ARTIFACT_IMAGE", "NEW.VISIBILITY <> OLD.VISIBILITY"},
+	{"ARTIFACT_IMAGE_DELETE_ACL_VERSION", "DELETE", "REGISTRY_ARTIFACT_IMAGE", ""},
+	{"ROLE_PERMISSION_INSERT_ACL_VERSION", "INSERT", "REGISTRY_ROLE_PERMISSION", ""},
+	{"ROLE_PERMISSION_UPDATE_ACL_VERSION", "UPDATE", "REGISTRY_ROLE_PERMISSION", ""},
+	{"ROLE_PERMISSION_DELETE_ACL_VERSION", "DELETE", "REGISTRY_ROLE_PERMISSION", ""},
+	{"TEAM_DELETE_ACL_VERSION", "DELETE", "REGISTRY_TEAM", ""},
+	{"TEAM_USER_MAPPING_INSERT_ACL_VERSION", "INSERT", "REGISTRY_TEAM_USER_MAPPING", ""},
+	{"TEAM_USER_MAPPING_UPDATE_ACL_VERSION", "UPDATE", "REGISTRY_TEAM_USER_MAPPING", ""},
+	{"TEAM_USER_MAPPING_DELETE_ACL_VERSION", "DELETE", "REGISTRY_TEAM_USER_MAPPING", ""},
+	{"TEAM_PERMISSION_INSERT_ACL_VERSION", "INSERT", "REGISTRY_TEAM_PERMISSION", ""},
+	{"TEAM_PERMISSION_UPDATE_ACL_VERSION", "UPDATE", "REGISTRY_TEAM_PERMISSION", ""},
+	{"TEAM_PERMISSION_DELETE_ACL_VERSION", "DELETE", "REGISTRY_TEAM_PERMISSION", ""},
+	{"IMAGE_COLLABORATOR_INSERT_ACL_VERSION", "INSERT", "REGISTRY_IMAGE_COLLABORATOR", ""},
+	{"IMAGE_COLLABORATOR_UPDATE_ACL_VERSION", "UPDATE", "REGISTRY_IMAGE_COLLABORATOR", ""},
+	{"IMAGE_COLLABORATOR_DELETE_ACL_VERSION", "DELETE", "REGISTRY_IMAGE_COLLABORATOR", ""},
+}
+
+var organizationQuotaAclVersionTriggers = []aclVersionTrigger{
+	{"ORG_QUOTA_INSERT_ACL_VERSION", "INSERT", "REGISTRY_ORG_QUOTA", ""},
+	{"ORG_QUOTA_UPDATE_ACL_VERSION", "UPDATE", "REGISTRY_ORG_QUOTA", ""},
+	{"ORG_QUOTA_DELETE_ACL_VERSION", "DELETE", "REGISTRY_ORG_QUOTA", ""},
+}
+
+// withAclVersionTriggers returns the statements followed by the statements which create the triggers for each driver
+func withAclVersionTriggers(statements map[string][]string, triggers []aclVersionTrigger) map[string][]string {
+	return map[string][]string{
+		extension.MysqlDriver: append(append([]string(nil), statements[extension.MysqlDriver]...),
+			mysqlAclVersionTriggers(triggers)...),
+		extension.PostgresDriver: append(append([]string(nil), statements[extension.PostgresDriver]...),
+			postgresAclVersionTriggers(triggers)...),
+		extension.SqliteDriver: append(append([]string(nil), statements[extension.SqliteDriver]...),
+			sqliteAclVersionTriggers(triggers)...),
+	}
+}
+
+func mysqlAclVersionTriggers(triggers []aclVersionTrigger) []string {
+	var statements []string
+	for _, trigger := range triggers {
+		statement := "CREATE TRIGGER " + trigger.name + " AFTER " + trigger.event + " ON " + trigger.table +
+			" FOR EACH ROW UPDATE REGISTRY_ACL_VERSION SET VERSION = VERSION + 1 WHERE ID = 1"
+		if trigger.condition != "" {
+			statement += " AND " + trigger.condition
+		}
+		statements = append(statements, statement)
+	}
+	return statements
+}
+
+// The PostgreSQL triggers execute the INCREMENT_ACL_VERSION function created along with the ACL version table
+func postgresAclVersionTriggers(triggers []aclVersionTrigger) []string {
+	var statements []string
+	for _, trigger := range triggers {
+		statement := "CREATE TRIGGER " + trigger.name + " AFTER " + trigger.event + " ON " + trigger.table +
+			" FOR EACH ROW "
+		if trigger.condition != "" {
+			statement += "WHEN (" + trigger.condition + ") "
+		}
+		statement += "EXECUTE PROCEDURE INCREMENT_ACL_VERSION()"
+		statements = append(statements, statement)
+	}
+	return statements
+}
+func sqliteAclVersionTriggers(triggers []aclVersionTrigger) []string {
+	var statements []string
+	for _, trigger := range triggers {
+		statement := "CREATE TRIGGER " + trigger.name + " AFTER " + trigger.event + " ON " + trigger.table +
+			" FOR EACH ROW "
+		if trigger.condition != "" {
+			statement += "WHEN " + trigger.condition + " "
+		}
+		statement += "BEGIN UPDATE REGISTRY_ACL_VERSION SET VERSION = VERSION + 1 WHERE ID = 1; END"
+		statements = append(statements, statement)
+	}
+	return statements
+}
+
+var baselineTables = map[string][]string{
+	extension.MysqlDriver: {
+		`CREATE TABLE IF NOT EXISTS REGISTRY_ARTIFACT_LOCK
+(
+    ARTIFACT_NAME VARCHAR(255) NOT NULL,
+    LOCK_COUNT    INT DEFAULT 0,
+    PRIMARY KEY (ARTIFACT_NAME)
+)
+    ENGINE = InnoDB
+    DEFAULT CHARSET = latin1`,
+		`CREATE TABLE IF NOT EXISTS REGISTRY_ORGANIZATION
+(
+    ORG_NAME                 VARCHAR(255)               NOT NULL,
+    DESCRIPTION              BLOB,
+    SUMMARY                  VARCHAR(255)                        DEFAULT '',
+    WEBSITE_URL              VARCHAR(255)                        DEFAULT '',
+    DEFAULT_IMAGE_VISIBILITY ENUM ('PUBLIC', 'PRIVATE') NOT NULL DEFAULT 'PUBLIC',
+    FIRST_AUTHOR             VARCHAR(255)               NOT NULL,
+    CREATED_DATE             DATETIME                   NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ORG_NAME)
+)
+    ENGINE = InnoDB
+    DEFAULT CHARSET = latin1`,
+		`CREATE TABLE IF NOT EXISTS REGISTRY_ORG_USER_MAPPING
+(
+    USER_UUID    VARCHAR(36)  NOT NULL,
+    ORG_NAME     VARCHAR(255) NOT NULL,
+    USER_ROLE    VARCHAR(255) NOT NULL,
+    CREATED_DATE DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (USER_UUID, ORG_NAME),
+    FOREIGN KEY (ORG_NAME) REFERENCES REGISTRY_ORGANIZATION (ORG_NAME)
+        ON DELETE CASCADE
+)
+    ENGINE = InnoDB
+    DEFAULT CHARSET = latin1`,
+		`CREATE TABLE IF NOT EXISTS REGISTRY_ARTIFACT_IMAGE
+(
+    ARTIFACT_IMAGE_ID VARCHAR(36)                NOT NULL,
+    ORG_NAME          VARCHAR(255)               NOT NULL,
+    IMAGE_NAME        VARCHAR(255)               NOT NULL,
+    SUMMARY           VARCHAR(255) DEFAULT '',
+    DESCRIPTION       BLOB,
+    FIRST_AUTHOR      VARCHAR(255)               NOT NULL,
+    VISIBILITY        ENUM ('PUBLIC', 'PRIVATE') NOT NULL,
+    PRIMARY KEY (ARTIFACT_IMAGE_ID),
+    CONSTRAINT UC_ARTIFACT_IMG UNIQUE (ORG_NAME, IMAGE_NAME),
+    FOREIGN KEY (ORG_NAME) REFERENCES REGISTRY_ORGANIZATION (ORG_NAME)
+        ON DELETE CASCADE
+)
+    ENGINE = InnoDB
+    DEFAULT CHARSET = latin1`,
+		`CREATE TABLE IF NOT EXISTS IMAGE_KEYWORDS
+(
+    ARTIFACT_IMAGE_ID VARCHAR(36) NOT NULL,
+    KEYWORD           VARCHAR(36) NOT NULL,
+    PRIMARY KEY (ARTIFACT_IMAGE_ID, KEYWORD),
+    FOREIGN KEY (ARTIFACT_IMAGE_ID) REFERENCES REGISTRY_ARTIFACT_IMAGE (ARTIFACT_IMAGE_ID)
+        ON DELETE CASCADE
+)
+    ENGINE = InnoDB
+    DEFAULT CHARSET = latin1`,
+		`CREATE TABLE IF NOT EXISTS REGISTRY_ARTIFACT
+(
+    ARTIFACT_ID       VARCHAR(36)  NOT NULL,
+    ARTIFACT_IMAGE_ID VARCHAR(36)  NOT NULL,
+    VERSION           VARCHAR(50)  NOT NULL,
+    DESCRIPTION       BLOB,
+    PULL_COUNT        INT UNSIGNED          DEFAULT 0,
+    PUSH_COUNT        INT UNSIGNED          DEFAULT 0,
+    LAST_AUTHOR       VARCHAR(255) NOT NULL,
+    FIRST_AUTHOR      VARCHAR(255) NOT NULL,
+    METADATA          BLOB         NOT NULL,
+    VERIFIED          BOOL                  DEFAULT FALSE,
+    STATEFUL          BOOL                  DEFAULT FALSE,
+    CREATED_DATE      DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UPDATED_DATE      DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (ARTIFACT_ID),
+    CONSTRAINT UC_ARTIFACT UNIQUE (ARTIFACT_IMAGE_ID, VERSION),
+    FOREIGN KEY (ARTIFACT_IMAGE_ID) REFERENCES REGISTRY_ARTIFACT_IMAGE (ARTIFACT_IMAGE_ID)
+        ON DELETE CASCADE
+)
+    ENGINE = InnoDB
+    DEFAULT CHARSET = latin1`,
+		`CREATE TABLE IF NOT EXISTS REGISTRY_ARTIFACT_INGRESS
+(
+    ARTIFACT_ID  VARCHAR(36) NOT NULL,
+    INGRESS_TYPE VARCHAR(36) NOT NULL,
+    PRIMARY KEY (ARTIFACT_ID, INGRESS_TYPE),
+    FOREIGN KEY (ARTIFACT_ID) REFERENCES REGISTRY_ARTIFACT (ARTIFACT_ID)
+        ON DELETE CASCADE
+)
+    ENGINE = InnoDB
+    DEFAULT CHARSET = latin1`,
+		`CREATE TABLE IF NOT EXISTS REGISTRY_ARTIFACT_LABEL
+(
+    ARTIFACT_ID VARCHAR(36) NOT NULL,
+    LABEL_KEY   VARCHAR(36) NOT NULL,
+    LABEL_VALUE VARCHAR(36) NOT NULL,
+    PRIMARY KEY (ARTIFACT_ID, LABEL_KEY),
+    FOREIGN KEY (ARTIFACT_ID) REFERENCES REGISTRY_ARTIFACT (ARTIFACT_ID)
+        ON DELETE CASCADE
+)
+    ENGINE = InnoDB
+    DEFAULT CHARSET = latin1`,
+	},
+	extension.PostgresDriver: {
+		`CREATE TABLE IF NOT EXISTS REGISTRY_ARTIFACT_LOCK
+(
+    ARTIFACT_NAME VARCHAR(255) NOT NULL,
+    LOCK_COUNT    INTEGER DEFAULT 0,
+    PRIMARY KEY (ARTIFACT_NAME)
+)`,
+		`CREATE TABLE IF NOT EXISTS REGISTRY_ORGANIZATION
+(
+    ORG_NAME                 VARCHAR(255) NOT NULL,
+    DESCRIPTION              BYTEA,
+    SUMMARY                  VARCHAR(255)          DEFAULT '',
+    WEBSITE_URL              VARCHAR(255)          DEFAULT '',
+    DEFAULT_IMAGE_VISIBILITY VARCHAR(7)   NOT NULL DEFAULT 'PUBLIC'
+        CHECK (UPPER(DEFAULT_IMAGE_VISIBILITY) IN ('PUBLIC', 'PRIVATE')),
+    FIRST_AUTHOR             VARCHAR(255) NOT NULL,
+    CREATED_DATE             TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ORG_NAME)
+)`,
+		`CREATE TABLE IF NOT EXISTS REGISTRY_ORG_USER_MAPPING
+(
+    USER_UUID    VARCHAR(36)  NOT NULL,
+    ORG_NAME     VARCHAR(255) NOT NULL,
+    USER_ROLE    VARCHAR(255) NOT NULL,
+    CREATED_DATE TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (USER_UUID, ORG_NAME),
+    FOREIGN KEY (ORG_NAME) REFERENCES REGISTRY_ORGANIZATION (ORG_NAME)
+        ON DELETE CASCADE
+)`,
+		`CREATE TABLE IF NOT EXISTS REGISTRY_ARTIFACT_IMAGE
+(
+    ARTIFACT_IMAGE_ID VARCHAR(36)  NOT NULL,
+    ORG_NAME          VARCHAR(255) NOT NULL,
+    IMAGE_NAME        VARCHAR(255) NOT NULL,
+    SUMMARY           VARCHAR(255) DEFAULT '',
+    DESCRIPTION       BYTEA,
+    FIRST_AUTHOR      VARCHAR(255) NOT NULL,
+    VISIBILITY        VARCHAR(7)   NOT NULL CHECK (UPPER(VISIBILITY) IN ('PUBLIC', 'PRIVATE')),
+    PRIMARY KEY (ARTIFACT_IMAGE_ID),
+    CONSTRAINT UC_ARTIFACT_IMG UNIQUE (ORG_NAME, IMAGE_NAME),
+    FOREIGN KEY (ORG_NAME) REFERENCES REGISTRY_ORGANIZATION (ORG_NAME)
+        ON DELETE CASCADE
+)`,
+		`CREATE TABLE IF NOT EXISTS IMAGE_KEYWORDS
+(
+    ARTIFACT_IMAGE_ID VARCHAR(36) NOT NULL,
+    KEYWORD           VARCHAR(36) NOT NULL,
+    PRIMARY KEY (ARTIFACT_IMAGE_ID, KEYWORD),
+    FOREIGN KEY (ARTIFACT_IMAGE_ID) REFERENCES REGISTRY_ARTIFACT_IMAGE (ARTIFACT_IMAGE_ID)
+        ON DELETE CASCADE
+)`,
+		`CREATE TABLE IF NOT EXISTS REGISTRY_ARTIFACT
+(
+    ARTIFACT_ID       VARCHAR(36)  NOT NULL,
+    ARTIFACT_IMAGE_ID VARCHAR(36)  NOT NULL,
+    VERSION           VARCHAR(50)  NOT NULL,
+    DESCRIPTION       BYTEA,
+    PULL_COUNT        INTEGER               DEFAULT 0 CHECK (PULL_COUNT >= 0),
+    PUSH_COUNT        INTEGER               DEFAULT 0 CHECK (PUSH_COUNT >= 0),
+    LAST_AUTHOR       VARCHAR(255) NOT NULL,
+    FIRST_AUTHOR      VARCHAR(255) NOT NULL,
+    METADATA          BYTEA        NOT NULL,
+    VERIFIED          BOOLEAN               DEFAULT FALSE,
+    STATEFUL          BOOLEAN               DEFAULT FALSE,
+    CREATED_DATE      TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UPDATED_DATE      TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ARTIFACT_ID),
+    CONSTRAINT UC_ARTIFACT UNIQUE (ARTIFACT_IMAGE_ID, VERSION),
+    FOREIGN KEY (ARTIFACT_IMAGE_ID) REFERENCES REGISTRY_ARTIFACT_IMAGE (ARTIFACT_IMAGE_ID)
+        ON DELETE CASCADE
+)`,
+		`CREATE TABLE IF NOT EXISTS REGISTRY_ARTIFACT_INGRESS
+(
+    ARTIFACT_ID  VARCHAR(36) NOT NULL,
+    INGRESS_TYPE VARCHAR(36) NOT NULL,
+    PRIMARY KEY (ARTIFACT_ID, INGRESS_TYPE),
+    FOREIGN KEY (ARTIFACT_ID) REFERENCES REGISTRY_ARTIFACT (ARTIFACT_ID)
+        ON DELETE CASCADE
+)`,
+		`CREATE TABLE IF NOT EXISTS REGISTRY_ARTIFACT_LABEL
+(
+    ARTIFACT_ID VARCHAR(36) NOT NULL,
+    LABEL_KEY   VARCHAR(36) NOT NULL,
+    LABEL_VALUE VARCHAR(36) NOT NULL,
+    PRIMARY KEY (ARTIFACT_ID, LABEL_KEY),
+    FOREIGN KEY (ARTIFACT_ID) REFERENCES REGISTRY_ARTIFACT (ARTIFACT_ID)
+        ON DELETE CASCADE
+)`,
+	},
+	extension.SqliteDriver: {
+		`CREATE TABLE IF NOT EXISTS REGISTRY_ARTIFACT_LOCK
+(
+    ARTIFACT_NAME VARCHAR(255) NOT NULL,
+    LOCK_COUNT    INTEGER DEFAULT 0,
+    PRIMARY KEY (ARTIFACT_NAME)
+)`,
+		`CREATE TABLE IF NOT EXISTS REGISTRY_ORGANIZATION
+(
+    ORG_NAME                 VARCHAR(255) NOT NULL,
+    DESCRIPTION              BLOB,
+    SUMMARY                  VARCHAR(255)          DEFAULT '',
+    WEBSITE_URL              VARCHAR(255)          DEFAULT '',
+    DEFAULT_IMAGE_VISIBILITY VARCHAR(7)   NOT NULL DEFAULT 'PUBLIC'
+        CHECK (UPPER(DEFAULT_IMAGE_VISIBILITY) IN ('PUBLIC', 'PRIVATE')),
+    FIRST_AUTHOR             VARCHAR(255) NOT NULL,
+    CREATED_DATE             DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ORG_NAME)
+)`,
+		`CREATE TABLE IF NOT EXISTS REGISTRY_ORG_USER_MAPPING
+(
+    USER_UUID    VARCHAR(36)  NOT NULL,
+    ORG_NAME     VARCHAR(255) NOT NULL,
+    USER_ROLE    VARCHAR(255) NOT NULL,
+    CREATED_DATE DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (USER_UUID, ORG_NAME),
+    FOREIGN KEY (ORG_NAME) REFERENCES REGISTRY_ORGANIZATION (ORG_NAME)
+        ON DELETE CASCADE
+)`,
+		`CREATE TABLE IF NOT EXISTS REGISTRY_ARTIFACT_IMAGE
+(
+    ARTIFACT_IMAGE_ID VARCHAR(36)  NOT NULL,
+    ORG_NAME          VARCHAR(255) NOT NULL,
+    IMAGE_NAME        VARCHAR(255) NOT NULL,
+    SUMMARY           VARCHAR(255) DEFAULT '',
+    DESCRIPTION       BLOB,
+    FIRST_AUTHOR      VARCHAR(255) NOT NULL,
+    VISIBILITY        VARCHAR(7)   NOT NULL CHECK (UPPER(VISIBILITY) IN ('PUBLIC', 'PRIVATE')),
+    PRIMARY KEY (ARTIFACT_IMAGE_ID),
+    CONSTRAINT UC_ARTIFACT_IMG UNIQUE (ORG_NAME, IMAGE_NAME),
+    FOREIGN KEY (ORG_NAME) REFERENCES REGISTRY_ORGANIZATION (ORG_NAME)
+        ON DELETE CASCADE
+)`,
+		`CREATE TABLE IF NOT EXISTS IMAGE_KEYWORDS
+(
+    ARTIFACT_IMAGE_ID VARCHAR(36) NOT NULL,
+    KEYWORD           VARCHAR(36) NOT NULL,
+    PRIMARY KEY (ARTIFACT_IMAGE_ID, KEYWORD),
+    FOREIGN KEY (ARTIFACT_IMAGE_ID) REFERENCES REGISTRY_ARTIFACT_IMAGE (ARTIFACT_IMAGE_ID)
+        ON DELETE CASCADE
+)`,
+		`CREATE TABLE IF NOT EXISTS REGISTRY_ARTIFACT
+(
+    ARTIFACT_ID       VARCHAR(36)  NOT NULL,
+    ARTIFACT_IMAGE_ID VARCHAR(36)  NOT NULL,
+    VERSION           VARCHAR(50)  NOT NULL,
+    DESCRIPTION       BLOB,
+    PULL_COUNT        INTEGER               DEFAULT 0,
+    PUSH_COUNT        INTEGER               DEFAULT 0,
+    LAST_AUTHOR       VARCHAR(255) NOT NULL,
+    FIRST_AUTHOR      VARCHAR(255) NOT NULL,
+    METADATA          BLOB         NOT NULL,
+    VERIFIED          BOOLEAN               DEFAULT FALSE,
+    STATEFUL          BOOLEAN               DEFAULT FALSE,
+    CREATED_DATE      DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UPDATED_DATE      DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ARTIFACT_ID),
+    CONSTRAINT UC_ARTIFACT UNIQUE (ARTIFACT_IMAGE_ID, VERSION),
+    FOREIGN KEY (ARTIFACT_IMAGE_ID) REFERENCES REGISTRY_ARTIFACT_IMAGE (ARTIFACT_IMAGE_ID)
+        ON DELETE CASCADE
+)`,
+		`CREATE TABLE IF NOT EXISTS REGISTRY_ARTIFACT_INGRESS
+(
+    ARTIFACT_ID  VARCHAR(36) NOT NULL,
+    INGRESS_TYPE VARCHAR(36) NOT NULL,
+    PRIMARY KEY (ARTIFACT_ID, INGRESS_TYPE),
+    FOREIGN KEY (ARTIFACT_ID) REFERENCES REGISTRY_ARTIFACT (ARTIFACT_ID)
+        ON DELETE CASCADE
+)`,
+		`CREATE TABLE IF NOT EXISTS REGISTRY_ARTIFACT_LABEL
+(
+    ARTIFACT_ID VARCHAR(36) NOT NULL,
+    LABEL_KEY   VARCHAR(36) NOT NULL,
+    LABEL_VALUE VARCHAR(36) NOT NULL,
+    PRIMARY KEY (ARTIFACT_ID, LABEL_KEY),
+    FOREIGN KEY (ARTIFACT_ID) REFERENCES REGISTRY_ARTIFACT (ARTIFACT_ID)
+        ON DELETE CASCADE
+)`,
+	},
+}
+
+var robotAccountTables = map[string][]string{
+	extension.MysqlDriver: {
+		`CREATE TABLE IF NOT EXISTS REGISTRY_ROBOT_ACCOUNT
+(
+    ORG_NAME     VARCHAR(255) NOT NULL,
+    ROBOT_NAME   VARCHAR(255) NOT NULL,
+    SECRET_HASH  VARCHAR(255) NOT NULL,
+    ACTIONS      VARCHAR(255) NOT NULL,
+    EXPIRES_AT   DATETIME,
+    CREATED_DATE DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ORG_NAME, ROBOT_NAME),
+    FOREIGN KEY (ORG_NAME) REFERENCES REGISTRY_ORGANIZATION (ORG_NAME)
+        ON DELETE CASCADE
+)
+    ENGINE = InnoDB
+    DEFAULT CHARSET = latin1`,
+	},
+	extension.PostgresDriver: {
+		`CREATE TABLE IF NOT EXISTS REGISTRY_ROBOT_ACCOUNT
+(
+    ORG_NAME     VARCHAR(255) NOT NULL,
+    ROBOT_NAME   VARCHAR(255) NOT NULL,
+    SECRET_HASH  VARCHAR(255) NOT NULL,
+    ACTIONS      VARCHAR(255) NOT NULL,
+    EXPIRES_AT   TIMESTAMP,
+    CREATED_DATE TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ORG_NAME, ROBOT_NAME),
+    FOREIGN KEY (ORG_NAME) REFERENCES REGISTRY_ORGANIZATION (ORG_NAME)
+        ON DELETE CASCADE
+)`,
+	},
+	extension.SqliteDriver: {
+		`CREATE TABLE IF NOT EXISTS REGISTRY_ROBOT_ACCOUNT
+(
+    ORG_NAME     VARCHAR(255) NOT NULL,
+    ROBOT_NAME   VARCHAR(255) NOT NULL,
+    SECRET_HASH  VARCHAR(255) NOT NULL,
+    ACTIONS      VARCHAR(255) NOT NULL,
+    EXPIRES_AT   DATETIME,
+    CREATED_DATE DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ORG_NAME, ROBOT_NAME),
+    FOREIGN KEY (ORG_NAME) REFERENCES REGISTRY_ORGANIZATION (ORG_NAME)
+        ON DELETE CASCADE
+)`,
+	},
+}
+
+var personalAccessTokenTables = map[string][]string{
+	extension.MysqlDriver: {
+		`CREATE TABLE IF NOT EXISTS REGISTRY_PERSONAL_ACCESS_TOKEN
+(
+    TOKEN_HASH   CHAR(64)     NOT NULL,
+    USER_UUID    VARCHAR(36)  NOT NULL,
+    TOKEN_NAME   VARCHAR(255) NOT NULL,
+    SCOPES       VARCHAR(255) NOT NULL,
+    ORG_NAME     VARCHAR(255),
+    EXPIRES_AT   DATETIME,
+    CREATED_DATE DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (TOKEN_HASH),
+    CONSTRAINT UC_PERSONAL_ACCESS_TOKEN UNIQUE (USER_UUID, TOKEN_NAME),
+    FOREIGN KEY (ORG_NAME) REFERENCES REGISTRY_ORGANIZATION (ORG_NAME)
+        ON DELETE CASCADE
+)
+    ENGINE = InnoDB
+    DEFAULT CHARSET = latin1`,
+	},
+	extension.PostgresDriver: {
+		`CREATE TABLE IF NOT EXISTS REGISTRY_PERSONAL_ACCESS_TOKEN
+(
+    TOKEN_HASH   CHAR(64)     NOT NULL,
+    USER_UUID    VARCHAR(36)  NOT NULL,
+    TOKEN_NAME   VARCHAR(255) NOT NULL,
+    SCOPES       VARCHAR(255) NOT NULL,
+    ORG_NAME     VARCHAR(255),
+    EXPIRES_AT   TIMESTAMP,
+    CREATED_DATE TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (TOKEN_HASH),
+    CONSTRAINT UC_PERSONAL_ACCESS_TOKEN UNIQUE (USER_UUID, TOKEN_NAME),
+    FOREIGN KEY (ORG_NAME) REFERENCES REGISTRY_ORGANIZATION (ORG_NAME)
+        ON DELETE CASCADE
+)`,
+	},
+	extension.SqliteDriver: {
+		`CREATE TABLE IF NOT EXISTS REGISTRY_PERSONAL_ACCESS_TOKEN
+(
+    TOKEN_HASH   CHAR(64)     NOT NULL,
+    USER_UUID    VARCHAR(36)  NOT NULL,
+    TOKEN_NAME   VARCHAR(255) NOT NULL,
+    SCOPES       VARCHAR(255) NOT NULL,
+    ORG_NAME     VARCHAR(255),
+    EXPIRES_AT   DATETIME,
+    CREATED_DATE DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (TOKEN_HASH),
+    CONSTRAINT UC_PERSONAL_ACCESS_TOKEN UNIQUE (USER_UUID, TOKEN_NAME),
+    FOREIGN KEY (ORG_NAME) REFERENCES REGISTRY_ORGANIZATION (ORG_NAME)
+        ON DELETE CASCADE
+)`,
+	},
+}
+
+var rolePermissionTables = map[string][]string{
+	extension.MysqlDriver: {
+		`CREATE TABLE IF NOT EXISTS REGISTRY_ROLE_PERMISSION
+(
+    ROLE_NAME    VARCHAR(255) NOT NULL,
+    ACTION       VARCHAR(255) NOT NULL,
+    CREATED_DATE DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ROLE_NAME, ACTION)
+)
+    ENGINE = InnoDB
+    DEFAULT CHARSET = latin1`,
+		`INSERT IGNORE INTO REGISTRY_ROLE_PERMISSION (ROLE_NAME, ACTION) VALUES ('admin', 'pull'), ('admin', 'push'),
+    ('admin', 'delete'), ('push', 'pull'), ('push', 'push'), ('pull', 'pull')`,
+	},
+	extension.PostgresDriver: {
+		`CREATE TABLE IF NOT EXISTS REGISTRY_ROLE_PERMISSION
+(
+    ROLE_NAME    VARCHAR(255) NOT NULL,
+    ACTION       VARCHAR(255) NOT NULL,
+    CREATED_DATE TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ROLE_NAME, ACTION)
+)`,
+		`INSERT INTO REGISTRY_ROLE_PERMISSION (ROLE_NAME, ACTION) VALUES ('admin', 'pull'), ('admin', 'push'),
+    ('admin', 'delete'), ('push', 'pull'), ('push', 'push'), ('pull', 'pull') ON CONFLICT DO NOTHING`,
+	},
+	extension.SqliteDriver: {
+		`CREATE TABLE IF NOT EXISTS REGISTRY_ROLE_PERMISSION
+(
+    ROLE_NAME    VARCHAR(255) NOT NULL,
+    ACTION       VARCHAR(255) NOT NULL,
+    CREATED_DATE DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ROLE_NAME, ACTION)
+)`,
+		`INSERT OR IGNORE INTO REGISTRY_ROLE_PERMISSION (ROLE_NAME, ACTION) VALUES ('admin', 'pull'), ('admin', 'push'),
+    ('admin', 'delete'), ('push', 'pull'), ('push', 'push'), ('pull', 'pull')`,
+	},
+}
+
+var teamTables = map[string][]string{
+	extension.MysqlDriver: {
+		`CREATE TABLE IF NOT EXISTS REGISTRY_TEAM
+(
+    ORG_NAME     VARCHAR(255) NOT NULL,
+    TEAM_NAME    VARCHAR(255) NOT NULL,
+    DESCRIPTION  VARCHAR(255),
+    CREATED_DATE DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ORG_NAME, TEAM_NAME),
+    FOREIGN KEY (ORG_NAME) REFERENCES REGISTRY_ORGANIZATION (ORG_NAME)
+        ON DELETE CASCADE
+)
+    ENGINE = InnoDB
+    DEFAULT CHARSET = latin1`,
+		`CREATE TABLE IF NOT EXISTS REGISTRY_TEAM_USER_MAPPING
+(
+    ORG_NAME     VARCHAR(255) NOT NULL,
+    TEAM_NAME    VARCHAR(255) NOT NULL,
+    USER_UUID    VARCHAR(36)  NOT NULL,
+    CREATED_DATE DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ORG_NAME, TEAM_NAME, USER_UUID),
+    FOREIGN KEY (ORG_NAME, TEAM_NAME) REFERENCES REGISTRY_TEAM (ORG_NAME, TEAM_NAME)
+        ON DELETE CASCADE
+)
+    ENGINE = InnoDB
+    DEFAULT CHARSET = latin1`,
+		`CREATE TABLE IF NOT EXISTS REGISTRY_TEAM_PERMISSION
+(
+    ORG_NAME     VARCHAR(255) NOT NULL,
+    TEAM_NAME    VARCHAR(255) NOT NULL,
+    ROLE_NAME    VARCHAR(255) NOT NULL,
+    CREATED_DATE DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ORG_NAME, TEAM_NAME, ROLE_NAME),
+    FOREIGN KEY (ORG_NAME, TEAM_NAME) REFERENCES REGISTRY_TEAM (ORG_NAME, TEAM_NAME)
+        ON DELETE CASCADE
+)
+    ENGINE = InnoDB
+    DEFAULT CHARSET = latin1`,
+	},
+	extension.PostgresDriver: {
+		`CREATE TABLE IF NOT EXISTS REGISTRY_TEAM
+(
+    ORG_NAME     VARCHAR(255) NOT NULL,
+    TEAM_NAME    VARCHAR(255) NOT NULL,
+    DESCRIPTION  VARCHAR(255),
+    CREATED_DATE TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ORG_NAME, TEAM_NAME),
+    FOREIGN KEY (ORG_NAME) REFERENCES REGISTRY_ORGANIZATION (ORG_NAME)
+        ON DELETE CASCADE
+)`,
+		`CREATE TABLE IF NOT EXISTS REGISTRY_TEAM_USER_MAPPING
+(
+    ORG_NAME     VARCHAR(255) NOT NULL,
+    TEAM_NAME    VARCHAR(255) NOT NULL,
+    USER_UUID    VARCHAR(36)  NOT NULL,
+    CREATED_DATE TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ORG_NAME, TEAM_NAME, USER_UUID),
+    FOREIGN KEY (ORG_NAME, TEAM_NAME) REFERENCES REGISTRY_TEAM (ORG_NAME, TEAM_NAME)
+        ON DELETE CASCADE
+)`,
+		`CREATE TABLE IF NOT EXISTS REGISTRY_TEAM_PERMISSION
+(
+    ORG_NAME     VARCHAR(255) NOT NULL,
+    TEAM_NAME    VARCHAR(255) NOT NULL,
+    ROLE_NAME    VARCHAR(255) NOT NULL,
+    CREATED_DATE TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ORG_NAME, TEAM_NAME, ROLE_NAME),
+    FOREIGN KEY (ORG_NAME, TEAM_NAME) REFERENCES REGISTRY_TEAM (ORG_NAME, TEAM_NAME)
+        ON DELETE CASCADE
+)`,
+	},
+	extension.SqliteDriver: {
+		`CREATE TABLE IF NOT EXISTS REGISTRY_TEAM
+(
+    ORG_NAME     VARCHAR(255) NOT NULL,
+    TEAM_NAME    VARCHAR(255) NOT NULL,
+    DESCRIPTION  VARCHAR(255),
+    CREATED_DATE DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ORG_NAME, TEAM_NAME),
+    FOREIGN KEY (ORG_NAME) REFERENCES REGISTRY_ORGANIZATION (ORG_NAME)
+        ON DELETE CASCADE
+)`,
+		`CREATE TABLE IF NOT EXISTS REGISTRY_TEAM_USER_MAPPING
+(
+    ORG_NAME     VARCHAR(255) NOT NULL,
+    TEAM_NAME    VARCHAR(255) NOT NULL,
+    USER_UUID    VARCHAR(36)  NOT NULL,
+    CREATED_DATE DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ORG_NAME, TEAM_NAME, USER_UUID),
+    FOREIGN KEY (ORG_NAME, TEAM_NAME) REFERENCES REGISTRY_TEAM (ORG_NAME, TEAM_NAME)
+        ON DELETE CASCADE
+)`,
+		`CREATE TABLE IF NOT EXISTS REGISTRY_TEAM_PERMISSION
+(
+    ORG_NAME     VARCHAR(255) NOT NULL,
+    TEAM_NAME    VARCHAR(255) NOT NULL,
+    ROLE_NAME    VARCHAR(255) NOT NULL,
+    CREATED_DATE DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ORG_NAME, TEAM_NAME, ROLE_NAME),
+    FOREIGN KEY (ORG_NAME, TEAM_NAME) REFERENCES REGISTRY_TEAM (ORG_NAME, TEAM_NAME)
+        ON DELETE CASCADE
+)`,
+	},
+}
+
+var imageCollaboratorTables = map[string][]string{
+	extension.MysqlDriver: {
+		`CREATE TABLE IF NOT EXISTS REGISTRY_IMAGE_COLLABORATOR
+(
+    ORG_NAME          VARCHAR(255)          NOT NULL,
+    IMAGE_NAME        VARCHAR(255)          NOT NULL,
+    COLLABORATOR_TYPE ENUM ('user', 'team') NOT NULL,
+    COLLABORATOR_NAME VARCHAR(255)          NOT NULL,
+    ROLE_NAME         VARCHAR(255)          NOT NULL,
+    CREATED_DATE      DATETIME              NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ORG_NAME, IMAGE_NAME, COLLABORATOR_TYPE, COLLABORATOR_NAME),
+    FOREIGN KEY (ORG_NAME) REFERENCES REGISTRY_ORGANIZATION (ORG_NAME)
+        ON DELETE CASCADE
+)
+    ENGINE = InnoDB
+    DEFAULT CHARSET = latin1`,
+	},
+	extension.PostgresDriver: {
+		`CREATE TABLE IF NOT EXISTS REGISTRY_IMAGE_COLLABORATOR
+(
+    ORG_NAME          VARCHAR(255) NOT NULL,
+    IMAGE_NAME        VARCHAR(255) NOT NULL,
+    COLLABORATOR_TYPE VARCHAR(4)   NOT NULL CHECK (COLLABORATOR_TYPE IN ('user', 'team')),
+    COLLABORATOR_NAME VARCHAR(255) NOT NULL,
+    ROLE_NAME         VARCHAR(255) NOT NULL,
+    CREATED_DATE      TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ORG_NAME, IMAGE_NAME, COLLABORATOR_TYPE, COLLABORATOR_NAME),
+    FOREIGN KEY (ORG_NAME) REFERENCES REGISTRY_ORGANIZATION (ORG_NAME)
+        ON DELETE CASCADE
+)`,
+	},
+	extension.SqliteDriver: {
+		`CREATE TABLE IF NOT EXISTS REGISTRY_IMAGE_COLLABORATOR
+(
+    ORG_NAME          VARCHAR(255) NOT NULL,
+    IMAGE_NAME        VARCHAR(255) NOT NULL,
+    COLLABORATOR_TYPE VARCHAR(4)   NOT NULL CHECK (COLLABORATOR_TYPE IN ('user', 'team')),
+    COLLABORATOR_NAME VARCHAR(255) NOT NULL,
+    ROLE_NAME         VARCHAR(255) NOT NULL,
+    CREATED_DATE      DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ORG_NAME, IMAGE_NAME, COLLABORATOR_TYPE, COLLABORATOR_NAME),
+    FOREIGN KEY (ORG_NAME) REFERENCES REGISTRY_ORGANIZATION (ORG_NAME)
+        ON DELETE CASCADE
+)`,
+	},
+}
+
+var aclVersionTables = map[string][]string{
+	extension.MysqlDriver: {
+		`CREATE TABLE IF NOT EXISTS REGISTRY_ACL_VERSION
+(
+    ID           INT      NOT NULL,
+    VERSION      BIGINT   NOT NULL DEFAULT 0,
+    UPDATED_DATE DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (ID)
+)
+    ENGINE = InnoDB
+    DEFAULT CHARSET = latin1`,
+		`INSERT IGNORE INTO REGISTRY_ACL_VERSION (ID, VERSION) VALUES (1, 0)`,
+	},
+	extension.PostgresDriver: {
+		`CREATE TABLE IF NOT EXISTS REGISTRY_ACL_VERSION
+(
+    ID           INTEGER   NOT NULL,
+    VERSION      BIGINT    NOT NULL DEFAULT 0,
+    UPDATED_DATE TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ID)
+)`,
+		`INSERT INTO REGISTRY_ACL_VERSION (ID, VERSION) VALUES (1, 0) ON CONFLICT DO NOTHING`,
+		`CREATE OR REPLACE FUNCTION INCREMENT_ACL_VERSION() RETURNS TRIGGER AS
+$$
+BEGIN
+    UPDATE REGISTRY_ACL_VERSION SET VERSION = VERSION + 1, UPDATED_DATE = CURRENT_TIMESTAMP WHERE ID = 1;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql`,
+	},
+	extension.SqliteDriver: {
+		`CREATE TABLE IF NOT EXISTS REGISTRY_ACL_VERSION
+(
+    ID           INTEGER  NOT NULL,
+    VERSION      BIGINT   NOT NULL DEFAULT 0,
+    UPDATED_DATE DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ID)
+)`,
+		`INSERT OR IGNORE INTO REGISTRY_ACL_VERSION (ID, VERSION) VALUES (1, 0)`,
+	},
+}
+
+var organizationQuotaTables = map[string][]string{
+	extension.MysqlDriver: {
+		`CREATE TABLE IF NOT EXISTS REGISTRY_ORG_QUOTA
+(
+    ORG_NAME     VARCHAR(255) NOT NULL,
+    MAX_IMAGES   INT UNSIGNED,
+    CREATED_DATE DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ORG_NAME),
+    FOREIGN KEY (ORG_NAME) REFERENCES REGISTRY_ORGANIZATION (ORG_NAME)
+        ON DELETE CASCADE
+)
+    ENGINE = InnoDB
+    DEFAULT CHARSET = latin1`,
+	},
+	extension.PostgresDriver: {
+		`CREATE TABLE IF NOT EXISTS REGISTRY_ORG_QUOTA
+(
+    ORG_NAME     VARCHAR(255) NOT NULL,
+    MAX_IMAGES   INTEGER CHECK (MAX_IMAGES >= 0),
+    CREATED_DATE TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ORG_NAME),
+    FOREIGN KEY (ORG_NAME) REFERENCES REGISTRY_ORGANIZATION (ORG_NAME)
+        ON DELETE CASCADE
+)`,
+	},
+	extension.SqliteDriver: {
+		`CREATE TABLE IF NOT EXISTS REGISTRY_ORG_QUOTA
+(
+    ORG_NAME     VARCHAR(255) NOT NULL,
+    MAX_IMAGES   INTEGER CHECK (MAX_IMAGES >= 0),
+    CREATED_DATE DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ORG_NAME),
+    FOREIGN KEY (ORG_NAME) REFERENCES REGISTRY_ORGANIZATION (ORG_NAME)
+        ON DELETE CASCADE
+)`,
+	},
+}
@@ -0,0 +1,121 @@
+/*
+ * Copyright (c) 2019 WSO2 Inc. (http:www.wso2.org) All Rights Reserved.
+ *
+ * WSO2 Inc. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http:www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package migrations
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/cellery-io/cellery-hub/components/docker-auth/pkg/extension"
+)
+
+var updateScripts = flag.Bool("update", false, "generate the database scripts of the tests from the migrations")
+
+const license = `------------------------------------------------------------------------
+
+Copyright 2019 WSO2, Inc. (http://wso2.com)
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License
+
+------------------------------------------------------------------------
+
+This script is generated from the migrations of the pkg/migrations package by running
+go test ./pkg/migrations -run TestScripts -update
+`
+
+// The database scripts used by the tests execute the statements of the migrations and record them as applied, hence
+// the tests run against the same schema as a migrated database
+var testScripts = []struct {
+	driver   string
+	file     string
+	comment  string
+	preamble string
+}{
+	{extension.MysqlDriver, "../../test/init.sql", "#", `DROP DATABASE IF EXISTS CELLERY_HUB;
+CREATE DATABASE CELLERY_HUB;
+USE CELLERY_HUB;
+
+CREATE USER IF NOT EXISTS 'celleryhub'@'%' IDENTIFIED BY 'celleryhub';
+GRANT ALL ON CELLERY_HUB.* TO 'celleryhub'@'%';
+`},
+	{extension.PostgresDriver, "../../test/init_postgres.sql", "--",
+		"-- The script should be executed against the cellery_hub database, which is selected by setting DB_DRIVER " +
+			"to postgres\n"},
+	{extension.SqliteDriver, "../../test/init_sqlite.sql", "--", ""},
+}
+
+func renderScript(driver string, comment string, preamble string, migrations []Migration) string {
+	var script strings.Builder
+	for _, line := range strings.Split(license, "\n") {
+		script.WriteString(strings.TrimSpace(comment+" "+line) + "\n")
+	}
+	if preamble != "" {
+		script.WriteString("\n" + preamble)
+	}
+	for i := range migrations {
+		migration := &migrations[i]
+		fmt.Fprintf(&script, "\n%s Migration %d : %s\n", comment, migration.Version, migration.Description)
+		for _, statement := range migration.Statements[driver] {
+			script.WriteString(statement + ";\n")
+		}
+	}
+	fmt.Fprintf(&script, "\n%s The migrations applied by the script\n%s;\n", comment, createSchemaVersionTableQuery)
+	for i := range migrations {
+		migration := &migrations[i]
+		fmt.Fprintf(&script, "INSERT INTO %s (VERSION, DESCRIPTION, CHECKSUM) VALUES (%d, '%s', '%s');\n",
+			schemaVersionTable, migration.Version, strings.Replace(migration.Description, "'", "''", -1),
+			migration.Checksum(driver))
+	}
+	return script.String()
+}
+
+func TestScripts(t *testing.T) {
+	for _, testScript := range testScripts {
+		script := renderScript(testScript.driver, testScript.comment, testScript.preamble, schemaMigrations)
+		if *updateScripts {
+			err := ioutil.WriteFile(testScript.file, []byte(script), 0644)
+			if err != nil {
+				t.Error("Error while writing the script", testScript.file, ":", err)
+			}
+			continue
+		}
+		content, err := ioutil.ReadFile(testScript.file)
+		if err != nil {
+			t.Error("Error while reading the script", testScript.file, ":", err)
+			continue
+		}
+		if string(content) != script {
+			t.Error("Script", testScript.file, "does not match the migrations, it should be generated by running "+
+				"go test ./pkg/migrations -run TestScripts -update")
+		}
+	}
+}
@@ -0,0 +1,268 @@
+/*
+ * Copyright (c) 2019 WSO2 Inc. (http:www.wso2.org) All Rights Reserved.
+ *
+ * WSO2 Inc. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http:www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package policy
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+)
+
+const AllowEffect = "allow"
+const DenyEffect = "deny"
+const BeforePhase = "before"
+const AfterPhase = "after"
+
+// Policy holds the rules of a YAML policy file which are evaluated along with the database ACL. The file is
+// reloaded when it is modified. Rules of the before phase are evaluated prior to the database ACL and a matching
+// rule decides the action without consulting the database. Rules of the after phase override the decision of the
+// database ACL. When several rules match, the rule with the highest priority is applied and deny rules take
+// precedence over allow rules of the same priority. All the conditions of a rule should match and the conditions
+// which are not specified match any request.
+//
+//	rules:
+//	  - name: release-team-push
+//	    effect: allow
+//	    priority: 200
+//	    match:
+//	      accounts: ["alice", "bob"]
+//	      organizations: ["cellery"]
+//	      actions: ["push"]
+//	  - name: restrict-push
+//	    effect: deny
+//	    priority: 100
+//	    match:
+//	      organizations: ["cellery"]
+//	      actions: ["push"]
+//	  - name: internal-anonymous-pull
+//	    effect: deny
+//	    phase: after
+//	    match:
+//	      organizations: ["internal"]
+//	      actions: ["pull"]
+//	      authenticated: false
+type Policy struct {
+	mutex   sync.Mutex
+	file    string
+	modTime time.Time
+	rules   []*Rule
+}
+
+type policyFile struct {
+	Rules []*Rule `yaml:"rules"`
+}
+
+type Rule struct {
+	Name     string `yaml:"name"`
+	Effect   string `yaml:"effect"`
+	Priority int    `yaml:"priority"`
+	Phase    string `yaml:"phase"`
+	Match    Match  `yaml:"match"`
+	networks []*net.IPNet
+}
+
+// Match holds the conditions of a rule. Accounts, organizations and images are glob patterns and IPs can be
+// either IP addresses or CIDR ranges. Accounts only match authenticated requests, since the account of an
+// unauthenticated request is merely the user name claimed by the client.
+type Match struct {
+	Accounts      []string `yaml:"accounts"`
+	Organizations []string `yaml:"organizations"`
+	Images        []string `yaml:"images"`
+	Actions       []string `yaml:"actions"`
+	Ips           []string `yaml:"ips"`
+	Authenticated *bool    `yaml:"authenticated"`
+}
+
+// Request holds the attributes of an authorization request which are matched against the rules
+type Request struct {
+	Account         string
+	Organization    string
+	Image           string
+	IP              net.IP
+	IsAuthenticated bool
+}
+
+func NewPolicy(file string, logger *zap.SugaredLogger) (*Policy, error) {
+	policy := &Policy{file: file}
+	err := policy.reloadIfModified(logger)
+	if err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// Evaluate returns the rule of the phase which decides the action for the request. Nil is returned if none of the
+// rules match.
+func (p *Policy) Evaluate(phase string, request *Request, action string, logger *zap.SugaredLogger,
+	execId string) *Rule {
+	p.mutex.Lock()
+	err := p.reloadIfModified(logger)
+	if err != nil {
+		logger.Errorf("[%s] Using previously loaded policy rules since reloading failed : %v", execId, err)
+	}
+	rules := p.rules
+	p.mutex.Unlock()
+
+	for _, rule := range rules {
+		if rule.Phase == phase && rule.matches(request, action) {
+			logger.Debugf("[%s] Policy rule %s with %s effect matched the %s action in %s phase", execId,
+				rule.Name, rule.Effect, action, phase)
+			return rule
+		}
+	}
+	return nil
+}
+
+func (r *Rule) matches(request *Request, action string) bool {
+	if len(r.Match.Actions) > 0 && !containsString(r.Match.Actions, action) {
+		return false
+	}
+	if r.Match.Authenticated != nil && *r.Match.Authenticated != request.IsAuthenticated {
+		return false
+	}
+	if len(r.Match.Accounts) > 0 && !request.IsAuthenticated {
+		return false
+	}
+	if !matchesAnyPattern(r.Match.Accounts, request.Account) ||
+		!matchesAnyPattern(r.Match.Organizations, request.Organization) ||
+		!matchesAnyPattern(r.Match.Images, request.Image) {
+		return false
+	}
+	if len(r.networks) > 0 {
+		for _, network := range r.networks {
+			if request.IP != nil && network.Contains(request.IP) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func matchesAnyPattern(patterns []string, value string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if isMatched, _ := path.Match(pattern, value); isMatched {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Policy) reloadIfModified(logger *zap.SugaredLogger) error {
+	fileInfo, err := os.Stat(p.file)
+	if err != nil {
+		return fmt.Errorf("error reading the policy file : %v", err)
+	}
+	if p.rules != nil && fileInfo.ModTime().Equal(p.modTime) {
+		return nil
+	}
+	content, err := ioutil.ReadFile(p.file)
+	if err != nil {
+		return fmt.Errorf("error reading the policy file : %v", err)
+	}
+	rules, err := parseRules(content)
+	if err != nil {
+		return err
+	}
+	p.rules = rules
+	p.modTime = fileInfo.ModTime()
+	logger.Debugf("Loaded %d rules from the policy file %s", len(rules), p.file)
+	return nil
+}
+
+func parseRules(content []byte) ([]*Rule, error) {
+	var file policyFile
+	err := yaml.UnmarshalStrict(content, &file)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling the policy file : %v", err)
+	}
+	rules := make([]*Rule, 0, len(file.Rules))
+	for i, rule := range file.Rules {
+		if len(rule.Name) == 0 {
+			rule.Name = fmt.Sprintf("rule-%d", i+1)
+		}
+		if rule.Effect != AllowEffect && rule.Effect != DenyEffect {
+			return nil, fmt.Errorf("invalid effect '%s' in the policy rule %s", rule.Effect, rule.Name)
+		}
+		if len(rule.Phase) == 0 {
+			rule.Phase = BeforePhase
+		} else if rule.Phase != BeforePhase && rule.Phase != AfterPhase {
+			return nil, fmt.Errorf("invalid phase '%s' in the policy rule %s", rule.Phase, rule.Name)
+		}
+		for _, patterns := range [][]string{rule.Match.Accounts, rule.Match.Organizations, rule.Match.Images} {
+			for _, pattern := range patterns {
+				if _, err := path.Match(pattern, ""); err != nil {
+					return nil, fmt.Errorf("invalid pattern '%s' in the policy rule %s", pattern, rule.Name)
+				}
+			}
+		}
+		for _, ip := range rule.Match.Ips {
+			network, err := parseNetwork(ip)
+			if err != nil {
+				return nil, fmt.Errorf("invalid IP '%s' in the policy rule %s", ip, rule.Name)
+			}
+			rule.networks = append(rule.networks, network)
+		}
+		rules = append(rules, rule)
+	}
+	// Rules are kept in the order they should be evaluated so that the first matching rule decides
+	sort.SliceStable(rules, func(i, j int) bool {
+		if rules[i].Priority != rules[j].Priority {
+			return rules[i].Priority > rules[j].Priority
+		}
+		return rules[i].Effect == DenyEffect && rules[j].Effect == AllowEffect
+	})
+	return rules, nil
+}
+
+func parseNetwork(ip string) (*net.IPNet, error) {
+	if strings.Contains(ip, "/") {
+		_, network, err := net.ParseCIDR(ip)
+		return network, err
+	}
+	address := net.ParseIP(ip)
+	if address == nil {
+		return nil, fmt.Errorf("invalid IP address %s", ip)
+	}
+	if address.To4() != nil {
+		return &net.IPNet{IP: address.To4(), Mask: net.CIDRMask(32, 32)}, nil
+	}
+	return &net.IPNet{IP: address, Mask: net.CIDRMask(128, 128)}, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
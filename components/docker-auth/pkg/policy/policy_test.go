@@ -0,0 +1,187 @@
+/*
+ * Copyright (c) 2019 WSO2 Inc. (http:www.wso2.org) All Rights Reserved.
+ *
+ * WSO2 Inc. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http:www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package policy
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const testExecId = "testExecId"
+
+const testPolicy = `
+rules:
+  - name: release-team-push
+    effect: allow
+    priority: 200
+    match:
+      accounts: ["alice", "robot$cellery+*"]
+      organizations: ["cellery"]
+      actions: ["push"]
+  - name: restrict-push
+    effect: deny
+    priority: 100
+    match:
+      organizations: ["cellery"]
+      actions: ["push"]
+  - name: office-pull
+    effect: allow
+    priority: 100
+    match:
+      organizations: ["cellery"]
+      images: ["base-*"]
+      ips: ["10.0.0.0/8", "192.168.1.10"]
+  - name: internal-anonymous-pull
+    effect: deny
+    phase: after
+    match:
+      organizations: ["internal"]
+      actions: ["pull"]
+      authenticated: false
+`
+
+func writePolicyFile(t *testing.T, dir string, content string) string {
+	file := filepath.Join(dir, "policy.yaml")
+	err := ioutil.WriteFile(file, []byte(content), 0600)
+	if err != nil {
+		t.Fatal("Error while writing the policy file :", err)
+	}
+	return file
+}
+
+func TestEvaluate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "policy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	logger := zap.NewExample().Sugar()
+	policy, err := NewPolicy(writePolicyFile(t, dir, testPolicy), logger)
+	if err != nil {
+		t.Fatal("Error while loading the policy :", err)
+	}
+
+	values := []struct {
+		phase   string
+		request Request
+		action  string
+		rule    string
+	}{
+		{BeforePhase, Request{Account: "alice", Organization: "cellery", Image: "hello", IsAuthenticated: true},
+			"push", "release-team-push"},
+		{BeforePhase, Request{Account: "robot$cellery+ci", Organization: "cellery", Image: "hello",
+			IsAuthenticated: true}, "push", "release-team-push"},
+		//	accounts never match unauthenticated requests, since the account is only claimed by the client
+		{BeforePhase, Request{Account: "alice", Organization: "cellery", Image: "hello"}, "push", "restrict-push"},
+		{BeforePhase, Request{Account: "bob", Organization: "cellery", Image: "hello"}, "push", "restrict-push"},
+		{BeforePhase, Request{Account: "bob", Organization: "is", Image: "hello"}, "push", ""},
+		{BeforePhase, Request{Account: "bob", Organization: "cellery", Image: "hello"}, "pull", ""},
+		//	ip and image glob matching
+		{BeforePhase, Request{Organization: "cellery", Image: "base-go", IP: net.ParseIP("10.1.2.3")}, "pull",
+			"office-pull"},
+		{BeforePhase, Request{Organization: "cellery", Image: "base-go", IP: net.ParseIP("192.168.1.10")},
+			"pull", "office-pull"},
+		{BeforePhase, Request{Organization: "cellery", Image: "base-go", IP: net.ParseIP("192.168.1.11")},
+			"pull", ""},
+		{BeforePhase, Request{Organization: "cellery", Image: "hello", IP: net.ParseIP("10.1.2.3")}, "pull", ""},
+		//	deny rules take precedence over allow rules of the same priority
+		{BeforePhase, Request{Organization: "cellery", Image: "base-go", IP: net.ParseIP("10.1.2.3")}, "push",
+			"restrict-push"},
+		//	authenticated label matching in the after phase
+		{AfterPhase, Request{Organization: "internal", Image: "hello"}, "pull", "internal-anonymous-pull"},
+		{AfterPhase, Request{Account: "alice", Organization: "internal", Image: "hello", IsAuthenticated: true},
+			"pull", ""},
+		{BeforePhase, Request{Organization: "internal", Image: "hello"}, "pull", ""},
+	}
+	for _, value := range values {
+		request := value.request
+		rule := policy.Evaluate(value.phase, &request, value.action, logger, testExecId)
+		ruleName := ""
+		if rule != nil {
+			ruleName = rule.Name
+		}
+		if ruleName != value.rule {
+			t.Error("Expected the rule", value.rule, "but found", ruleName, "for", value.action, "by",
+				value.request.Account, "on", value.request.Organization+"/"+value.request.Image)
+		}
+	}
+}
+
+func TestReloadPolicy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "policy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	logger := zap.NewExample().Sugar()
+	file := writePolicyFile(t, dir, testPolicy)
+	policy, err := NewPolicy(file, logger)
+	if err != nil {
+		t.Fatal("Error while loading the policy :", err)
+	}
+	request := &Request{Account: "bob", Organization: "is", Image: "hello"}
+	if rule := policy.Evaluate(BeforePhase, request, "push", logger, testExecId); rule != nil {
+		t.Fatal("Expected no rule to match but found", rule.Name)
+	}
+
+	writePolicyFile(t, dir, "rules:\n  - name: deny-all\n    effect: deny\n")
+	modTime := time.Now().Add(time.Minute)
+	err = os.Chtimes(file, modTime, modTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rule := policy.Evaluate(BeforePhase, request, "push", logger, testExecId); rule == nil ||
+		rule.Name != "deny-all" {
+		t.Error("Expected the reloaded deny-all rule to match")
+	}
+
+	// The previously loaded rules are used if the modified file is invalid
+	writePolicyFile(t, dir, "rules:\n  - name: invalid\n    effect: permit\n")
+	modTime = modTime.Add(time.Minute)
+	err = os.Chtimes(file, modTime, modTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rule := policy.Evaluate(BeforePhase, request, "push", logger, testExecId); rule == nil ||
+		rule.Name != "deny-all" {
+		t.Error("Expected the previously loaded deny-all rule to match")
+	}
+}
+
+func TestInvalidPolicy(t *testing.T) {
+	values := []string{
+		"rules:\n  - effect: permit\n",
+		"rules:\n  - effect: deny\n    phase: during\n",
+		"rules:\n  - effect: deny\n    match:\n      ips: [\"10.0.0.0/33\"]\n",
+		"rules:\n  - effect: deny\n    match:\n      images: [\"[\"]\n",
+		"rules:\n  - effect: deny\n    match:\n      account: [\"alice\"]\n",
+	}
+	for _, value := range values {
+		_, err := parseRules([]byte(value))
+		if err == nil {
+			t.Error("Expected an error for the policy", value)
+		}
+	}
+}
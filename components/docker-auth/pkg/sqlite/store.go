@@ -0,0 +1,65 @@
+/*
+ * Copyright (c) 2019 WSO2 Inc. (http:www.wso2.org) All Rights Reserved.
+ *
+ * WSO2 Inc. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http:www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+
+	_ "github.com/mattn/go-sqlite3"
+	"go.uber.org/zap"
+
+	"github.com/cellery-io/cellery-hub/components/docker-auth/pkg/extension"
+	"github.com/cellery-io/cellery-hub/components/docker-auth/pkg/migrations"
+)
+
+// Store retrieves the ACL data from an embedded SQLite database, hence the authorization plugin can be used in small
+// deployments without a database server. The store lives in its own package since it links the SQLite driver, which
+// is only built into the plugin with the sqlite build tag.
+type Store struct {
+	extension.ACLStore
+	db *sql.DB
+}
+
+// NewStore opens the SQLite database in the file and applies the pending schema migrations, which create the tables
+// of the ACL along with the default roles
+func NewStore(file string, logger *zap.SugaredLogger) (*Store, error) {
+	logger.Debugf("Opening the SQLite ACL store %s", file)
+	db, err := sql.Open(extension.SqliteDriver, fmt.Sprintf("file:%s?_foreign_keys=on&_busy_timeout=5000", file))
+	if err != nil {
+		return nil, fmt.Errorf("error while opening the SQLite database %s :%s", file, err)
+	}
+	_, err = migrations.Up(db, extension.SqliteDriver, logger)
+	if err != nil {
+		if closeErr := db.Close(); closeErr != nil {
+			logger.Errorf("Error while closing the SQLite database : %v", closeErr)
+		}
+		return nil, fmt.Errorf("error while migrating the SQLite database %s :%s", file, err)
+	}
+	return &Store{ACLStore: extension.NewSQLiteStore(db), db: db}, nil
+}
+
+// Close closes the SQLite database along with the statements prepared on it
+func (s *Store) Close() error {
+	releaseErr := extension.ReleasePreparedStatements(s.db)
+	if err := s.db.Close(); err != nil {
+		return err
+	}
+	return releaseErr
+}
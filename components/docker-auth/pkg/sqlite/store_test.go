@@ -0,0 +1,96 @@
+/*
+ * Copyright (c) 2019 WSO2 Inc. (http:www.wso2.org) All Rights Reserved.
+ *
+ * WSO2 Inc. licenses this file to you under the Apache License,
+ * Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ * http:www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package sqlite
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/cellery-io/cellery-hub/components/docker-auth/pkg/extension"
+	"github.com/cellery-io/cellery-hub/components/docker-auth/pkg/migrations"
+)
+
+const testUser = "testUser"
+
+func TestStoreSchema(t *testing.T) {
+	dir, err := ioutil.TempDir("", "acl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "acl.db")
+	logger := zap.NewExample().Sugar()
+	store, err := NewStore(file, logger)
+	if err != nil {
+		t.Fatal("Error while creating the SQLite store :", err)
+	}
+	err = migrations.CheckSchemaVersion(store.db, extension.SqliteDriver, logger)
+	if err != nil {
+		t.Error("Expected the migrations to be applied but found error :", err)
+	}
+	_, err = store.db.Exec("INSERT INTO REGISTRY_ORGANIZATION (ORG_NAME, DEFAULT_IMAGE_VISIBILITY, FIRST_AUTHOR) " +
+		"VALUES ('cellery', 'PRIVATE', 'wso2.com')")
+	if err != nil {
+		t.Fatal("Error while adding the organization :", err)
+	}
+	initialVersion, err := store.GetVersion(logger, testUser)
+	if err != nil {
+		t.Fatal("Error while retrieving the version :", err)
+	}
+	_, err = store.db.Exec("INSERT INTO REGISTRY_ORG_USER_MAPPING (USER_UUID, ORG_NAME, USER_ROLE) " +
+		"VALUES ('wso2.com', 'cellery', 'push')")
+	if err != nil {
+		t.Fatal("Error while adding the member :", err)
+	}
+	if err = store.Close(); err != nil {
+		t.Fatal("Error while closing the SQLite store :", err)
+	}
+
+	// The existing data is retained when the store is opened again
+	store, err = NewStore(file, logger)
+	if err != nil {
+		t.Fatal("Error while opening the SQLite store again :", err)
+	}
+	defer store.Close()
+	version, err := store.GetVersion(logger, testUser)
+	if err != nil || version != initialVersion+1 {
+		t.Error("Expected the version to be incremented only by the membership but found", version, err)
+	}
+	record, err := store.GetACLRecord("wso2.com", "cellery", "image", logger, testUser)
+	if err != nil {
+		t.Fatal("Error while retrieving the ACL record :", err)
+	}
+	if !record.IsOrganizationFound || record.Visibility != "PRIVATE" || len(record.OrganizationRoles) != 1 ||
+		record.OrganizationRoles[0] != "push" {
+		t.Error("Unexpected ACL record", record)
+	}
+	permissions, err := store.GetRolePermissions(logger, testUser)
+	if err != nil || len(permissions) != 3 {
+		t.Error("Expected the default roles but found", permissions, err)
+	}
+	_, err = store.db.Exec("INSERT INTO REGISTRY_ORG_USER_MAPPING (USER_UUID, ORG_NAME, USER_ROLE) " +
+		"VALUES ('user.com', 'unknown', 'push')")
+	if err == nil {
+		t.Error("Expected the foreign keys to be enforced")
+	}
+}
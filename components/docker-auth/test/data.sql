@@ -19,9 +19,32 @@
 USE CELLERY_HUB;
 INSERT INTO `REGISTRY_ORGANIZATION` (ORG_NAME, DESCRIPTION, WEBSITE_URL, DEFAULT_IMAGE_VISIBILITY, FIRST_AUTHOR, CREATED_DATE) VALUES ('cellery','ABC is my first org','abc.com','private','unknown','2019-05-27 14:58:47');
 INSERT INTO `REGISTRY_ORGANIZATION` (ORG_NAME, DESCRIPTION, WEBSITE_URL, DEFAULT_IMAGE_VISIBILITY, FIRST_AUTHOR, CREATED_DATE) VALUES ('is','ABC is my first org','pqr.com','private','unknown','2019-05-27 14:58:47');
+INSERT INTO `REGISTRY_ORGANIZATION` (ORG_NAME, DESCRIPTION, WEBSITE_URL, DEFAULT_IMAGE_VISIBILITY, FIRST_AUTHOR, CREATED_DATE) VALUES ('opensource','Open source images','opensource.com','public','unknown','2019-05-27 14:58:47');
+INSERT INTO `REGISTRY_ORGANIZATION` (ORG_NAME, DESCRIPTION, WEBSITE_URL, DEFAULT_IMAGE_VISIBILITY, FIRST_AUTHOR, CREATED_DATE) VALUES ('limited','Organization with quotas','limited.com','private','unknown','2019-05-27 14:58:47');
 INSERT INTO `REGISTRY_ORG_USER_MAPPING` (USER_UUID, ORG_NAME, USER_ROLE, CREATED_DATE) VALUES ('wso2.com','cellery','push','2019-04-06 00:00:00');
 INSERT INTO `REGISTRY_ORG_USER_MAPPING` (USER_UUID, ORG_NAME, USER_ROLE, CREATED_DATE) VALUES ('admin.com','cellery','admin','2019-04-06 00:00:00');
 INSERT INTO `REGISTRY_ORG_USER_MAPPING` (USER_UUID, ORG_NAME, USER_ROLE, CREATED_DATE) VALUES ('other.com','is','pull','2019-04-06 00:00:00');
+INSERT INTO `REGISTRY_ORG_USER_MAPPING` (USER_UUID, ORG_NAME, USER_ROLE, CREATED_DATE) VALUES ('maintainer.com','is','maintainer','2019-04-06 00:00:00');
+INSERT INTO `REGISTRY_ORG_USER_MAPPING` (USER_UUID, ORG_NAME, USER_ROLE, CREATED_DATE) VALUES ('unknown.com','is','unknown','2019-04-06 00:00:00');
+INSERT INTO `REGISTRY_ORG_USER_MAPPING` (USER_UUID, ORG_NAME, USER_ROLE, CREATED_DATE) VALUES ('limited.com','limited','push','2019-04-06 00:00:00');
+INSERT INTO `REGISTRY_ROLE_PERMISSION` (ROLE_NAME, ACTION) VALUES ('maintainer','pull');
+INSERT INTO `REGISTRY_ROLE_PERMISSION` (ROLE_NAME, ACTION) VALUES ('maintainer','delete');
+INSERT INTO `REGISTRY_TEAM` (ORG_NAME, TEAM_NAME, DESCRIPTION) VALUES ('is','readers','Read access to all the images');
+INSERT INTO `REGISTRY_TEAM` (ORG_NAME, TEAM_NAME, DESCRIPTION) VALUES ('is','releasers','Release the abc image');
+INSERT INTO `REGISTRY_TEAM_USER_MAPPING` (ORG_NAME, TEAM_NAME, USER_UUID) VALUES ('is','readers','team.com');
+INSERT INTO `REGISTRY_TEAM_USER_MAPPING` (ORG_NAME, TEAM_NAME, USER_UUID) VALUES ('is','releasers','team.com');
+INSERT INTO `REGISTRY_TEAM_USER_MAPPING` (ORG_NAME, TEAM_NAME, USER_UUID) VALUES ('is','releasers','maintainer.com');
+INSERT INTO `REGISTRY_TEAM_PERMISSION` (ORG_NAME, TEAM_NAME, ROLE_NAME) VALUES ('is','readers','pull');
+INSERT INTO `REGISTRY_IMAGE_COLLABORATOR` (ORG_NAME, IMAGE_NAME, COLLABORATOR_TYPE, COLLABORATOR_NAME, ROLE_NAME) VALUES ('is','abc','team','releasers','push');
+INSERT INTO `REGISTRY_IMAGE_COLLABORATOR` (ORG_NAME, IMAGE_NAME, COLLABORATOR_TYPE, COLLABORATOR_NAME, ROLE_NAME) VALUES ('cellery','newImage','user','contractor.com','push');
+INSERT INTO `REGISTRY_IMAGE_COLLABORATOR` (ORG_NAME, IMAGE_NAME, COLLABORATOR_TYPE, COLLABORATOR_NAME, ROLE_NAME) VALUES ('cellery','restricted','user','admin.com','pull');
 INSERT INTO `REGISTRY_ARTIFACT_IMAGE` (ARTIFACT_IMAGE_ID, ORG_NAME, IMAGE_NAME, DESCRIPTION, FIRST_AUTHOR, VISIBILITY) VALUES ('1','cellery','image','Sample','unkown','PUBLIC');
 INSERT INTO `REGISTRY_ARTIFACT_IMAGE` (ARTIFACT_IMAGE_ID, ORG_NAME, IMAGE_NAME, DESCRIPTION, FIRST_AUTHOR, VISIBILITY) VALUES ('2','cellery','newImage','Sample','www.dockehub.com','PRIVATE');
 INSERT INTO `REGISTRY_ARTIFACT_IMAGE` (ARTIFACT_IMAGE_ID, ORG_NAME, IMAGE_NAME, DESCRIPTION, FIRST_AUTHOR, VISIBILITY) VALUES ('3','is','pqr','Sample','www.dockehub.com','PRIVATE');
+INSERT INTO `REGISTRY_ARTIFACT_IMAGE` (ARTIFACT_IMAGE_ID, ORG_NAME, IMAGE_NAME, DESCRIPTION, FIRST_AUTHOR, VISIBILITY) VALUES ('4','limited','full','Sample','limited.com','PRIVATE');
+INSERT INTO `REGISTRY_ARTIFACT_IMAGE` (ARTIFACT_IMAGE_ID, ORG_NAME, IMAGE_NAME, DESCRIPTION, FIRST_AUTHOR, VISIBILITY) VALUES ('5','limited','fresh','Sample','limited.com','PRIVATE');
+INSERT INTO `REGISTRY_ORG_QUOTA` (ORG_NAME, MAX_IMAGES) VALUES ('limited',2);
+INSERT INTO `REGISTRY_ROBOT_ACCOUNT` (ORG_NAME, ROBOT_NAME, SECRET_HASH, ACTIONS, EXPIRES_AT) VALUES ('cellery','ci','$2a$10$MVum9v2XjQoiz4f4BbLs4.5Vz8P8H2agMeLpBJCOKSFt/YVKEnYRO','pull,push',NULL);
+INSERT INTO `REGISTRY_ROBOT_ACCOUNT` (ORG_NAME, ROBOT_NAME, SECRET_HASH, ACTIONS, EXPIRES_AT) VALUES ('cellery','reader','$argon2id$v=19$m=65536,t=3,p=2$zQQitDxYlsPeGe1L9NpYaQ$8fymmEpXEaTfZGYT2CfnFLbdhHLshs13U/ZasRU7YiY','pull',NULL);
+INSERT INTO `REGISTRY_ROBOT_ACCOUNT` (ORG_NAME, ROBOT_NAME, SECRET_HASH, ACTIONS, EXPIRES_AT) VALUES ('cellery','expired','$2a$10$MVum9v2XjQoiz4f4BbLs4.5Vz8P8H2agMeLpBJCOKSFt/YVKEnYRO','pull,push','2019-01-01 00:00:00');
+INSERT INTO `REGISTRY_PERSONAL_ACCESS_TOKEN` (TOKEN_HASH, USER_UUID, TOKEN_NAME, SCOPES, ORG_NAME, EXPIRES_AT) VALUES ('a8bd95dcb9cb7e0299099cb7f147406425897814857e6ac6ea586f150df42365','wso2.com','laptop','pull','cellery',NULL);
@@ -15,6 +15,10 @@
 # limitations under the License
 #
 # ------------------------------------------------------------------------
+#
+# This script is generated from the migrations of the pkg/migrations package by running
+# go test ./pkg/migrations -run TestScripts -update
+#
 
 DROP DATABASE IF EXISTS CELLERY_HUB;
 CREATE DATABASE CELLERY_HUB;
@@ -23,7 +27,7 @@ USE CELLERY_HUB;
 CREATE USER IF NOT EXISTS 'celleryhub'@'%' IDENTIFIED BY 'celleryhub';
 GRANT ALL ON CELLERY_HUB.* TO 'celleryhub'@'%';
 
-# This table is used for acquiring the MySQL write lock for a specific registry artifact
+# Migration 1 : Create the CELLERY_HUB tables
 CREATE TABLE IF NOT EXISTS REGISTRY_ARTIFACT_LOCK
 (
     ARTIFACT_NAME VARCHAR(255) NOT NULL,
@@ -32,21 +36,19 @@ CREATE TABLE IF NOT EXISTS REGISTRY_ARTIFACT_LOCK
 )
     ENGINE = InnoDB
     DEFAULT CHARSET = latin1;
-
 CREATE TABLE IF NOT EXISTS REGISTRY_ORGANIZATION
 (
     ORG_NAME                 VARCHAR(255)               NOT NULL,
     DESCRIPTION              BLOB,
-    SUMMARY                  VARCHAR(255)                        DEFAULT "",
-    WEBSITE_URL              VARCHAR(255)                        DEFAULT "",
-    DEFAULT_IMAGE_VISIBILITY ENUM ("PUBLIC", "PRIVATE") NOT NULL DEFAULT "PUBLIC",
+    SUMMARY                  VARCHAR(255)                        DEFAULT '',
+    WEBSITE_URL              VARCHAR(255)                        DEFAULT '',
+    DEFAULT_IMAGE_VISIBILITY ENUM ('PUBLIC', 'PRIVATE') NOT NULL DEFAULT 'PUBLIC',
     FIRST_AUTHOR             VARCHAR(255)               NOT NULL,
     CREATED_DATE             DATETIME                   NOT NULL DEFAULT CURRENT_TIMESTAMP,
     PRIMARY KEY (ORG_NAME)
 )
     ENGINE = InnoDB
     DEFAULT CHARSET = latin1;
-
 CREATE TABLE IF NOT EXISTS REGISTRY_ORG_USER_MAPPING
 (
     USER_UUID    VARCHAR(36)  NOT NULL,
@@ -59,16 +61,15 @@ CREATE TABLE IF NOT EXISTS REGISTRY_ORG_USER_MAPPING
 )
     ENGINE = InnoDB
     DEFAULT CHARSET = latin1;
-
 CREATE TABLE IF NOT EXISTS REGISTRY_ARTIFACT_IMAGE
 (
     ARTIFACT_IMAGE_ID VARCHAR(36)                NOT NULL,
     ORG_NAME          VARCHAR(255)               NOT NULL,
     IMAGE_NAME        VARCHAR(255)               NOT NULL,
-    SUMMARY           VARCHAR(255) DEFAULT "",
+    SUMMARY           VARCHAR(255) DEFAULT '',
     DESCRIPTION       BLOB,
     FIRST_AUTHOR      VARCHAR(255)               NOT NULL,
-    VISIBILITY        ENUM ("PUBLIC", "PRIVATE") NOT NULL,
+    VISIBILITY        ENUM ('PUBLIC', 'PRIVATE') NOT NULL,
     PRIMARY KEY (ARTIFACT_IMAGE_ID),
     CONSTRAINT UC_ARTIFACT_IMG UNIQUE (ORG_NAME, IMAGE_NAME),
     FOREIGN KEY (ORG_NAME) REFERENCES REGISTRY_ORGANIZATION (ORG_NAME)
@@ -76,7 +77,6 @@ CREATE TABLE IF NOT EXISTS REGISTRY_ARTIFACT_IMAGE
 )
     ENGINE = InnoDB
     DEFAULT CHARSET = latin1;
-
 CREATE TABLE IF NOT EXISTS IMAGE_KEYWORDS
 (
     ARTIFACT_IMAGE_ID VARCHAR(36) NOT NULL,
@@ -87,7 +87,6 @@ CREATE TABLE IF NOT EXISTS IMAGE_KEYWORDS
 )
     ENGINE = InnoDB
     DEFAULT CHARSET = latin1;
-
 CREATE TABLE IF NOT EXISTS REGISTRY_ARTIFACT
 (
     ARTIFACT_ID       VARCHAR(36)  NOT NULL,
@@ -110,7 +109,6 @@ CREATE TABLE IF NOT EXISTS REGISTRY_ARTIFACT
 )
     ENGINE = InnoDB
     DEFAULT CHARSET = latin1;
-
 CREATE TABLE IF NOT EXISTS REGISTRY_ARTIFACT_INGRESS
 (
     ARTIFACT_ID  VARCHAR(36) NOT NULL,
@@ -121,7 +119,6 @@ CREATE TABLE IF NOT EXISTS REGISTRY_ARTIFACT_INGRESS
 )
     ENGINE = InnoDB
     DEFAULT CHARSET = latin1;
-
 CREATE TABLE IF NOT EXISTS REGISTRY_ARTIFACT_LABEL
 (
     ARTIFACT_ID VARCHAR(36) NOT NULL,
@@ -134,4 +131,163 @@ CREATE TABLE IF NOT EXISTS REGISTRY_ARTIFACT_LABEL
     ENGINE = InnoDB
     DEFAULT CHARSET = latin1;
 
--- CELLERY HUB ENDS --
\ No newline at end of file
+# Migration 2 : Create the robot account table
+CREATE TABLE IF NOT EXISTS REGISTRY_ROBOT_ACCOUNT
+(
+    ORG_NAME     VARCHAR(255) NOT NULL,
+    ROBOT_NAME   VARCHAR(255) NOT NULL,
+    SECRET_HASH  VARCHAR(255) NOT NULL,
+    ACTIONS      VARCHAR(255) NOT NULL,
+    EXPIRES_AT   DATETIME,
+    CREATED_DATE DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ORG_NAME, ROBOT_NAME),
+    FOREIGN KEY (ORG_NAME) REFERENCES REGISTRY_ORGANIZATION (ORG_NAME)
+        ON DELETE CASCADE
+)
+    ENGINE = InnoDB
+    DEFAULT CHARSET = latin1;
+
+# Migration 3 : Create the personal access token table
+CREATE TABLE IF NOT EXISTS REGISTRY_PERSONAL_ACCESS_TOKEN
+(
+    TOKEN_HASH   CHAR(64)     NOT NULL,
+    USER_UUID    VARCHAR(36)  NOT NULL,
+    TOKEN_NAME   VARCHAR(255) NOT NULL,
+    SCOPES       VARCHAR(255) NOT NULL,
+    ORG_NAME     VARCHAR(255),
+    EXPIRES_AT   DATETIME,
+    CREATED_DATE DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (TOKEN_HASH),
+    CONSTRAINT UC_PERSONAL_ACCESS_TOKEN UNIQUE (USER_UUID, TOKEN_NAME),
+    FOREIGN KEY (ORG_NAME) REFERENCES REGISTRY_ORGANIZATION (ORG_NAME)
+        ON DELETE CASCADE
+)
+    ENGINE = InnoDB
+    DEFAULT CHARSET = latin1;
+
+# Migration 4 : Create the role permission table with the default roles
+CREATE TABLE IF NOT EXISTS REGISTRY_ROLE_PERMISSION
+(
+    ROLE_NAME    VARCHAR(255) NOT NULL,
+    ACTION       VARCHAR(255) NOT NULL,
+    CREATED_DATE DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ROLE_NAME, ACTION)
+)
+    ENGINE = InnoDB
+    DEFAULT CHARSET = latin1;
+INSERT IGNORE INTO REGISTRY_ROLE_PERMISSION (ROLE_NAME, ACTION) VALUES ('admin', 'pull'), ('admin', 'push'),
+    ('admin', 'delete'), ('push', 'pull'), ('push', 'push'), ('pull', 'pull');
+
+# Migration 5 : Create the team tables
+CREATE TABLE IF NOT EXISTS REGISTRY_TEAM
+(
+    ORG_NAME     VARCHAR(255) NOT NULL,
+    TEAM_NAME    VARCHAR(255) NOT NULL,
+    DESCRIPTION  VARCHAR(255),
+    CREATED_DATE DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ORG_NAME, TEAM_NAME),
+    FOREIGN KEY (ORG_NAME) REFERENCES REGISTRY_ORGANIZATION (ORG_NAME)
+        ON DELETE CASCADE
+)
+    ENGINE = InnoDB
+    DEFAULT CHARSET = latin1;
+CREATE TABLE IF NOT EXISTS REGISTRY_TEAM_USER_MAPPING
+(
+    ORG_NAME     VARCHAR(255) NOT NULL,
+    TEAM_NAME    VARCHAR(255) NOT NULL,
+    USER_UUID    VARCHAR(36)  NOT NULL,
+    CREATED_DATE DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ORG_NAME, TEAM_NAME, USER_UUID),
+    FOREIGN KEY (ORG_NAME, TEAM_NAME) REFERENCES REGISTRY_TEAM (ORG_NAME, TEAM_NAME)
+        ON DELETE CASCADE
+)
+    ENGINE = InnoDB
+    DEFAULT CHARSET = latin1;
+CREATE TABLE IF NOT EXISTS REGISTRY_TEAM_PERMISSION
+(
+    ORG_NAME     VARCHAR(255) NOT NULL,
+    TEAM_NAME    VARCHAR(255) NOT NULL,
+    ROLE_NAME    VARCHAR(255) NOT NULL,
+    CREATED_DATE DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ORG_NAME, TEAM_NAME, ROLE_NAME),
+    FOREIGN KEY (ORG_NAME, TEAM_NAME) REFERENCES REGISTRY_TEAM (ORG_NAME, TEAM_NAME)
+        ON DELETE CASCADE
+)
+    ENGINE = InnoDB
+    DEFAULT CHARSET = latin1;
+
+# Migration 6 : Create the image collaborator table
+CREATE TABLE IF NOT EXISTS REGISTRY_IMAGE_COLLABORATOR
+(
+    ORG_NAME          VARCHAR(255)          NOT NULL,
+    IMAGE_NAME        VARCHAR(255)          NOT NULL,
+    COLLABORATOR_TYPE ENUM ('user', 'team') NOT NULL,
+    COLLABORATOR_NAME VARCHAR(255)          NOT NULL,
+    ROLE_NAME         VARCHAR(255)          NOT NULL,
+    CREATED_DATE      DATETIME              NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ORG_NAME, IMAGE_NAME, COLLABORATOR_TYPE, COLLABORATOR_NAME),
+    FOREIGN KEY (ORG_NAME) REFERENCES REGISTRY_ORGANIZATION (ORG_NAME)
+        ON DELETE CASCADE
+)
+    ENGINE = InnoDB
+    DEFAULT CHARSET = latin1;
+
+# Migration 7 : Create the ACL version table and the triggers which increment it
+CREATE TABLE IF NOT EXISTS REGISTRY_ACL_VERSION
+(
+    ID           INT      NOT NULL,
+    VERSION      BIGINT   NOT NULL DEFAULT 0,
+    UPDATED_DATE DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (ID)
+)
+    ENGINE = InnoDB
+    DEFAULT CHARSET = latin1;
+INSERT IGNORE INTO REGISTRY_ACL_VERSION (ID, VERSION) VALUES (1, 0);
+CREATE TRIGGER ORGANIZATION_UPDATE_ACL_VERSION AFTER UPDATE ON REGISTRY_ORGANIZATION FOR EACH ROW UPDATE REGISTRY_ACL_VERSION SET VERSION = VERSION + 1 WHERE ID = 1 AND NEW.DEFAULT_IMAGE_VISIBILITY <> OLD.DEFAULT_IMAGE_VISIBILITY;
+CREATE TRIGGER ORGANIZATION_DELETE_ACL_VERSION AFTER DELETE ON REGISTRY_ORGANIZATION FOR EACH ROW UPDATE REGISTRY_ACL_VERSION SET VERSION = VERSION + 1 WHERE ID = 1;
+CREATE TRIGGER ORG_USER_MAPPING_INSERT_ACL_VERSION AFTER INSERT ON REGISTRY_ORG_USER_MAPPING FOR EACH ROW UPDATE REGISTRY_ACL_VERSION SET VERSION = VERSION + 1 WHERE ID = 1;
+CREATE TRIGGER ORG_USER_MAPPING_UPDATE_ACL_VERSION AFTER UPDATE ON REGISTRY_ORG_USER_MAPPING FOR EACH ROW UPDATE REGISTRY_ACL_VERSION SET VERSION = VERSION + 1 WHERE ID = 1;
+CREATE TRIGGER ORG_USER_MAPPING_DELETE_ACL_VERSION AFTER DELETE ON REGISTRY_ORG_USER_MAPPING FOR EACH ROW UPDATE REGISTRY_ACL_VERSION SET VERSION = VERSION + 1 WHERE ID = 1;
+CREATE TRIGGER ARTIFACT_IMAGE_INSERT_ACL_VERSION AFTER INSERT ON REGISTRY_ARTIFACT_IMAGE FOR EACH ROW UPDATE REGISTRY_ACL_VERSION SET VERSION = VERSION + 1 WHERE ID = 1;
+CREATE TRIGGER ARTIFACT_IMAGE_UPDATE_ACL_VERSION AFTER UPDATE ON REGISTRY_ARTIFACT_IMAGE FOR EACH ROW UPDATE REGISTRY_ACL_VERSION SET VERSION = VERSION + 1 WHERE ID = 1 AND NEW.VISIBILITY <> OLD.VISIBILITY;
+CREATE TRIGGER ARTIFACT_IMAGE_DELETE_ACL_VERSION AFTER DELETE ON REGISTRY_ARTIFACT_IMAGE FOR EACH ROW UPDATE REGISTRY_ACL_VERSION SET VERSION = VERSION + 1 WHERE ID = 1;
+CREATE TRIGGER ROLE_PERMISSION_INSERT_ACL_VERSION AFTER INSERT ON REGISTRY_ROLE_PERMISSION FOR EACH ROW UPDATE REGISTRY_ACL_VERSION SET VERSION = VERSION + 1 WHERE ID = 1;
+CREATE TRIGGER ROLE_PERMISSION_UPDATE_ACL_VERSION AFTER UPDATE ON REGISTRY_ROLE_PERMISSION FOR EACH ROW UPDATE REGISTRY_ACL_VERSION SET VERSION = VERSION + 1 WHERE ID = 1;
+CREATE TRIGGER ROLE_PERMISSION_DELETE_ACL_VERSION AFTER DELETE ON REGISTRY_ROLE_PERMISSION FOR EACH ROW UPDATE REGISTRY_ACL_VERSION SET VERSION = VERSION + 1 WHERE ID = 1;
+CREATE TRIGGER TEAM_DELETE_ACL_VERSION AFTER DELETE ON REGISTRY_TEAM FOR EACH ROW UPDATE REGISTRY_ACL_VERSION SET VERSION = VERSION + 1 WHERE ID = 1;
+CREATE TRIGGER TEAM_USER_MAPPING_INSERT_ACL_VERSION AFTER INSERT ON REGISTRY_TEAM_USER_MAPPING FOR EACH ROW UPDATE REGISTRY_ACL_VERSION SET VERSION = VERSION + 1 WHERE ID = 1;
+CREATE TRIGGER TEAM_USER_MAPPING_UPDATE_ACL_VERSION AFTER UPDATE ON REGISTRY_TEAM_USER_MAPPING FOR EACH ROW UPDATE REGISTRY_ACL_VERSION SET VERSION = VERSION + 1 WHERE ID = 1;
+CREATE TRIGGER TEAM_USER_MAPPING_DELETE_ACL_VERSION AFTER DELETE ON REGISTRY_TEAM_USER_MAPPING FOR EACH ROW UPDATE REGISTRY_ACL_VERSION SET VERSION = VERSION + 1 WHERE ID = 1;
+CREATE TRIGGER TEAM_PERMISSION_INSERT_ACL_VERSION AFTER INSERT ON REGISTRY_TEAM_PERMISSION FOR EACH ROW UPDATE REGISTRY_ACL_VERSION SET VERSION = VERSION + 1 WHERE ID = 1;
+CREATE TRIGGER TEAM_PERMISSION_UPDATE_ACL_VERSION AFTER UPDATE ON REGISTRY_TEAM_PERMISSION FOR EACH ROW UPDATE REGISTRY_ACL_VERSION SET VERSION = VERSION + 1 WHERE ID = 1;
+CREATE TRIGGER TEAM_PERMISSION_DELETE_ACL_VERSION AFTER DELETE ON REGISTRY_TEAM_PERMISSION FOR EACH ROW UPDATE REGISTRY_ACL_VERSION SET VERSION = VERSION + 1 WHERE ID = 1;
+CREATE TRIGGER IMAGE_COLLABORATOR_INSERT_ACL_VERSION AFTER INSERT ON REGISTRY_IMAGE_COLLABORATOR FOR EACH ROW UPDATE REGISTRY_ACL_VERSION SET VERSION = VERSION + 1 WHERE ID = 1;
+CREATE TRIGGER IMAGE_COLLABORATOR_UPDATE_ACL_VERSION AFTER UPDATE ON REGISTRY_IMAGE_COLLABORATOR FOR EACH ROW UPDATE REGISTRY_ACL_VERSION SET VERSION = VERSION + 1 WHERE ID = 1;
+CREATE TRIGGER IMAGE_COLLABORATOR_DELETE_ACL_VERSION AFTER DELETE ON REGISTRY_IMAGE_COLLABORATOR FOR EACH ROW UPDATE REGISTRY_ACL_VERSION SET VERSION = VERSION + 1 WHERE ID = 1;
+
+# Migration 8 : Create the organization quota table
+CREATE TABLE IF NOT EXISTS REGISTRY_ORG_QUOTA
+(
+    ORG_NAME     VARCHAR(255) NOT NULL,
+    MAX_IMAGES   INT UNSIGNED,
+    CREATED_DATE DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ORG_NAME),
+    FOREIGN KEY (ORG_NAME) REFERENCES REGISTRY_ORGANIZATION (ORG_NAME)
+        ON DELETE CASCADE
+)
+    ENGINE = InnoDB
+    DEFAULT CHARSET = latin1;
+CREATE TRIGGER ORG_QUOTA_INSERT_ACL_VERSION AFTER INSERT ON REGISTRY_ORG_QUOTA FOR EACH ROW UPDATE REGISTRY_ACL_VERSION SET VERSION = VERSION + 1 WHERE ID = 1;
+CREATE TRIGGER ORG_QUOTA_UPDATE_ACL_VERSION AFTER UPDATE ON REGISTRY_ORG_QUOTA FOR EACH ROW UPDATE REGISTRY_ACL_VERSION SET VERSION = VERSION + 1 WHERE ID = 1;
+CREATE TRIGGER ORG_QUOTA_DELETE_ACL_VERSION AFTER DELETE ON REGISTRY_ORG_QUOTA FOR EACH ROW UPDATE REGISTRY_ACL_VERSION SET VERSION = VERSION + 1 WHERE ID = 1;
+
+# The migrations applied by the script
+CREATE TABLE IF NOT EXISTS REGISTRY_SCHEMA_VERSION (VERSION INT NOT NULL, DESCRIPTION VARCHAR(255) NOT NULL, CHECKSUM CHAR(64) NOT NULL, APPLIED_DATE TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, PRIMARY KEY (VERSION));
+INSERT INTO REGISTRY_SCHEMA_VERSION (VERSION, DESCRIPTION, CHECKSUM) VALUES (1, 'Create the CELLERY_HUB tables', '804a08c6472cba63f1bc214b354c5e7cfc7ceddd2f28510204be13c10129a386');
+INSERT INTO REGISTRY_SCHEMA_VERSION (VERSION, DESCRIPTION, CHECKSUM) VALUES (2, 'Create the robot account table', '39b0ce41aab5eee3480d9e396da17282b11222efce9e9e1fcb0c1eba5eb62823');
+INSERT INTO REGISTRY_SCHEMA_VERSION (VERSION, DESCRIPTION, CHECKSUM) VALUES (3, 'Create the personal access token table', '297f04edcb7ed0c0cbed3b92372d2df60b50645fe709d911689812d9624793e9');
+INSERT INTO REGISTRY_SCHEMA_VERSION (VERSION, DESCRIPTION, CHECKSUM) VALUES (4, 'Create the role permission table with the default roles', 'f2dde538b33d7516402593e5e3cf2f834289790e57c7441c271119fd6bb2c024');
+INSERT INTO REGISTRY_SCHEMA_VERSION (VERSION, DESCRIPTION, CHECKSUM) VALUES (5, 'Create the team tables', '9b66cf1e98995c4566971b7590a16f2f8aca1eea14d8c53bc800cd424b32a521');
+INSERT INTO REGISTRY_SCHEMA_VERSION (VERSION, DESCRIPTION, CHECKSUM) VALUES (6, 'Create the image collaborator table', '74cc8037e89b6f19612b759cf554b695b9b785911571aec3ed31b8b9db06a0f8');
+INSERT INTO REGISTRY_SCHEMA_VERSION (VERSION, DESCRIPTION, CHECKSUM) VALUES (7, 'Create the ACL version table and the triggers which increment it', '9be35dade01397e0b8e6ba9947b06ac91fe31014b3044f35738657a07191847c');
+INSERT INTO REGISTRY_SCHEMA_VERSION (VERSION, DESCRIPTION, CHECKSUM) VALUES (8, 'Create the organization quota table', 'a61a8c327f614fc448f11e59e693ad2d8cca4d38391bc9eebf97571554917d03');
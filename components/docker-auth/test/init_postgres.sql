@@ -0,0 +1,262 @@
+-- ------------------------------------------------------------------------
+--
+-- Copyright 2019 WSO2, Inc. (http://wso2.com)
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+-- http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License
+--
+-- ------------------------------------------------------------------------
+--
+-- This script is generated from the migrations of the pkg/migrations package by running
+-- go test ./pkg/migrations -run TestScripts -update
+--
+
+-- The script should be executed against the cellery_hub database, which is selected by setting DB_DRIVER to postgres
+
+-- Migration 1 : Create the CELLERY_HUB tables
+CREATE TABLE IF NOT EXISTS REGISTRY_ARTIFACT_LOCK
+(
+    ARTIFACT_NAME VARCHAR(255) NOT NULL,
+    LOCK_COUNT    INTEGER DEFAULT 0,
+    PRIMARY KEY (ARTIFACT_NAME)
+);
+CREATE TABLE IF NOT EXISTS REGISTRY_ORGANIZATION
+(
+    ORG_NAME                 VARCHAR(255) NOT NULL,
+    DESCRIPTION              BYTEA,
+    SUMMARY                  VARCHAR(255)          DEFAULT '',
+    WEBSITE_URL              VARCHAR(255)          DEFAULT '',
+    DEFAULT_IMAGE_VISIBILITY VARCHAR(7)   NOT NULL DEFAULT 'PUBLIC'
+        CHECK (UPPER(DEFAULT_IMAGE_VISIBILITY) IN ('PUBLIC', 'PRIVATE')),
+    FIRST_AUTHOR             VARCHAR(255) NOT NULL,
+    CREATED_DATE             TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ORG_NAME)
+);
+CREATE TABLE IF NOT EXISTS REGISTRY_ORG_USER_MAPPING
+(
+    USER_UUID    VARCHAR(36)  NOT NULL,
+    ORG_NAME     VARCHAR(255) NOT NULL,
+    USER_ROLE    VARCHAR(255) NOT NULL,
+    CREATED_DATE TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (USER_UUID, ORG_NAME),
+    FOREIGN KEY (ORG_NAME) REFERENCES REGISTRY_ORGANIZATION (ORG_NAME)
+        ON DELETE CASCADE
+);
+CREATE TABLE IF NOT EXISTS REGISTRY_ARTIFACT_IMAGE
+(
+    ARTIFACT_IMAGE_ID VARCHAR(36)  NOT NULL,
+    ORG_NAME          VARCHAR(255) NOT NULL,
+    IMAGE_NAME        VARCHAR(255) NOT NULL,
+    SUMMARY           VARCHAR(255) DEFAULT '',
+    DESCRIPTION       BYTEA,
+    FIRST_AUTHOR      VARCHAR(255) NOT NULL,
+    VISIBILITY        VARCHAR(7)   NOT NULL CHECK (UPPER(VISIBILITY) IN ('PUBLIC', 'PRIVATE')),
+    PRIMARY KEY (ARTIFACT_IMAGE_ID),
+    CONSTRAINT UC_ARTIFACT_IMG UNIQUE (ORG_NAME, IMAGE_NAME),
+    FOREIGN KEY (ORG_NAME) REFERENCES REGISTRY_ORGANIZATION (ORG_NAME)
+        ON DELETE CASCADE
+);
+CREATE TABLE IF NOT EXISTS IMAGE_KEYWORDS
+(
+    ARTIFACT_IMAGE_ID VARCHAR(36) NOT NULL,
+    KEYWORD           VARCHAR(36) NOT NULL,
+    PRIMARY KEY (ARTIFACT_IMAGE_ID, KEYWORD),
+    FOREIGN KEY (ARTIFACT_IMAGE_ID) REFERENCES REGISTRY_ARTIFACT_IMAGE (ARTIFACT_IMAGE_ID)
+        ON DELETE CASCADE
+);
+CREATE TABLE IF NOT EXISTS REGISTRY_ARTIFACT
+(
+    ARTIFACT_ID       VARCHAR(36)  NOT NULL,
+    ARTIFACT_IMAGE_ID VARCHAR(36)  NOT NULL,
+    VERSION           VARCHAR(50)  NOT NULL,
+    DESCRIPTION       BYTEA,
+    PULL_COUNT        INTEGER               DEFAULT 0 CHECK (PULL_COUNT >= 0),
+    PUSH_COUNT        INTEGER               DEFAULT 0 CHECK (PUSH_COUNT >= 0),
+    LAST_AUTHOR       VARCHAR(255) NOT NULL,
+    FIRST_AUTHOR      VARCHAR(255) NOT NULL,
+    METADATA          BYTEA        NOT NULL,
+    VERIFIED          BOOLEAN               DEFAULT FALSE,
+    STATEFUL          BOOLEAN               DEFAULT FALSE,
+    CREATED_DATE      TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UPDATED_DATE      TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ARTIFACT_ID),
+    CONSTRAINT UC_ARTIFACT UNIQUE (ARTIFACT_IMAGE_ID, VERSION),
+    FOREIGN KEY (ARTIFACT_IMAGE_ID) REFERENCES REGISTRY_ARTIFACT_IMAGE (ARTIFACT_IMAGE_ID)
+        ON DELETE CASCADE
+);
+CREATE TABLE IF NOT EXISTS REGISTRY_ARTIFACT_INGRESS
+(
+    ARTIFACT_ID  VARCHAR(36) NOT NULL,
+    INGRESS_TYPE VARCHAR(36) NOT NULL,
+    PRIMARY KEY (ARTIFACT_ID, INGRESS_TYPE),
+    FOREIGN KEY (ARTIFACT_ID) REFERENCES REGISTRY_ARTIFACT (ARTIFACT_ID)
+        ON DELETE CASCADE
+);
+CREATE TABLE IF NOT EXISTS REGISTRY_ARTIFACT_LABEL
+(
+    ARTIFACT_ID VARCHAR(36) NOT NULL,
+    LABEL_KEY   VARCHAR(36) NOT NULL,
+    LABEL_VALUE VARCHAR(36) NOT NULL,
+    PRIMARY KEY (ARTIFACT_ID, LABEL_KEY),
+    FOREIGN KEY (ARTIFACT_ID) REFERENCES REGISTRY_ARTIFACT (ARTIFACT_ID)
+        ON DELETE CASCADE
+);
+
+-- Migration 2 : Create the robot account table
+CREATE TABLE IF NOT EXISTS REGISTRY_ROBOT_ACCOUNT
+(
+    ORG_NAME     VARCHAR(255) NOT NULL,
+    ROBOT_NAME   VARCHAR(255) NOT NULL,
+    SECRET_HASH  VARCHAR(255) NOT NULL,
+    ACTIONS      VARCHAR(255) NOT NULL,
+    EXPIRES_AT   TIMESTAMP,
+    CREATED_DATE TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ORG_NAME, ROBOT_NAME),
+    FOREIGN KEY (ORG_NAME) REFERENCES REGISTRY_ORGANIZATION (ORG_NAME)
+        ON DELETE CASCADE
+);
+
+-- Migration 3 : Create the personal access token table
+CREATE TABLE IF NOT EXISTS REGISTRY_PERSONAL_ACCESS_TOKEN
+(
+    TOKEN_HASH   CHAR(64)     NOT NULL,
+    USER_UUID    VARCHAR(36)  NOT NULL,
+    TOKEN_NAME   VARCHAR(255) NOT NULL,
+    SCOPES       VARCHAR(255) NOT NULL,
+    ORG_NAME     VARCHAR(255),
+    EXPIRES_AT   TIMESTAMP,
+    CREATED_DATE TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (TOKEN_HASH),
+    CONSTRAINT UC_PERSONAL_ACCESS_TOKEN UNIQUE (USER_UUID, TOKEN_NAME),
+    FOREIGN KEY (ORG_NAME) REFERENCES REGISTRY_ORGANIZATION (ORG_NAME)
+        ON DELETE CASCADE
+);
+
+-- Migration 4 : Create the role permission table with the default roles
+CREATE TABLE IF NOT EXISTS REGISTRY_ROLE_PERMISSION
+(
+    ROLE_NAME    VARCHAR(255) NOT NULL,
+    ACTION       VARCHAR(255) NOT NULL,
+    CREATED_DATE TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ROLE_NAME, ACTION)
+);
+INSERT INTO REGISTRY_ROLE_PERMISSION (ROLE_NAME, ACTION) VALUES ('admin', 'pull'), ('admin', 'push'),
+    ('admin', 'delete'), ('push', 'pull'), ('push', 'push'), ('pull', 'pull') ON CONFLICT DO NOTHING;
+
+-- Migration 5 : Create the team tables
+CREATE TABLE IF NOT EXISTS REGISTRY_TEAM
+(
+    ORG_NAME     VARCHAR(255) NOT NULL,
+    TEAM_NAME    VARCHAR(255) NOT NULL,
+    DESCRIPTION  VARCHAR(255),
+    CREATED_DATE TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ORG_NAME, TEAM_NAME),
+    FOREIGN KEY (ORG_NAME) REFERENCES REGISTRY_ORGANIZATION (ORG_NAME)
+        ON DELETE CASCADE
+);
+CREATE TABLE IF NOT EXISTS REGISTRY_TEAM_USER_MAPPING
+(
+    ORG_NAME     VARCHAR(255) NOT NULL,
+    TEAM_NAME    VARCHAR(255) NOT NULL,
+    USER_UUID    VARCHAR(36)  NOT NULL,
+    CREATED_DATE TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ORG_NAME, TEAM_NAME, USER_UUID),
+    FOREIGN KEY (ORG_NAME, TEAM_NAME) REFERENCES REGISTRY_TEAM (ORG_NAME, TEAM_NAME)
+        ON DELETE CASCADE
+);
+CREATE TABLE IF NOT EXISTS REGISTRY_TEAM_PERMISSION
+(
+    ORG_NAME     VARCHAR(255) NOT NULL,
+    TEAM_NAME    VARCHAR(255) NOT NULL,
+    ROLE_NAME    VARCHAR(255) NOT NULL,
+    CREATED_DATE TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ORG_NAME, TEAM_NAME, ROLE_NAME),
+    FOREIGN KEY (ORG_NAME, TEAM_NAME) REFERENCES REGISTRY_TEAM (ORG_NAME, TEAM_NAME)
+        ON DELETE CASCADE
+);
+
+-- Migration 6 : Create the image collaborator table
+CREATE TABLE IF NOT EXISTS REGISTRY_IMAGE_COLLABORATOR
+(
+    ORG_NAME          VARCHAR(255) NOT NULL,
+    IMAGE_NAME        VARCHAR(255) NOT NULL,
+    COLLABORATOR_TYPE VARCHAR(4)   NOT NULL CHECK (COLLABORATOR_TYPE IN ('user', 'team')),
+    COLLABORATOR_NAME VARCHAR(255) NOT NULL,
+    ROLE_NAME         VARCHAR(255) NOT NULL,
+    CREATED_DATE      TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ORG_NAME, IMAGE_NAME, COLLABORATOR_TYPE, COLLABORATOR_NAME),
+    FOREIGN KEY (ORG_NAME) REFERENCES REGISTRY_ORGANIZATION (ORG_NAME)
+        ON DELETE CASCADE
+);
+
+-- Migration 7 : Create the ACL version table and the triggers which increment it
+CREATE TABLE IF NOT EXISTS REGISTRY_ACL_VERSION
+(
+    ID           INTEGER   NOT NULL,
+    VERSION      BIGINT    NOT NULL DEFAULT 0,
+    UPDATED_DATE TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ID)
+);
+INSERT INTO REGISTRY_ACL_VERSION (ID, VERSION) VALUES (1, 0) ON CONFLICT DO NOTHING;
+CREATE OR REPLACE FUNCTION INCREMENT_ACL_VERSION() RETURNS TRIGGER AS
+$$
+BEGIN
+    UPDATE REGISTRY_ACL_VERSION SET VERSION = VERSION + 1, UPDATED_DATE = CURRENT_TIMESTAMP WHERE ID = 1;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+CREATE TRIGGER ORGANIZATION_UPDATE_ACL_VERSION AFTER UPDATE ON REGISTRY_ORGANIZATION FOR EACH ROW WHEN (NEW.DEFAULT_IMAGE_VISIBILITY <> OLD.DEFAULT_IMAGE_VISIBILITY) EXECUTE PROCEDURE INCREMENT_ACL_VERSION();
+CREATE TRIGGER ORGANIZATION_DELETE_ACL_VERSION AFTER DELETE ON REGISTRY_ORGANIZATION FOR EACH ROW EXECUTE PROCEDURE INCREMENT_ACL_VERSION();
+CREATE TRIGGER ORG_USER_MAPPING_INSERT_ACL_VERSION AFTER INSERT ON REGISTRY_ORG_USER_MAPPING FOR EACH ROW EXECUTE PROCEDURE INCREMENT_ACL_VERSION();
+CREATE TRIGGER ORG_USER_MAPPING_UPDATE_ACL_VERSION AFTER UPDATE ON REGISTRY_ORG_USER_MAPPING FOR EACH ROW EXECUTE PROCEDURE INCREMENT_ACL_VERSION();
+CREATE TRIGGER ORG_USER_MAPPING_DELETE_ACL_VERSION AFTER DELETE ON REGISTRY_ORG_USER_MAPPING FOR EACH ROW EXECUTE PROCEDURE INCREMENT_ACL_VERSION();
+CREATE TRIGGER ARTIFACT_IMAGE_INSERT_ACL_VERSION AFTER INSERT ON REGISTRY_ARTIFACT_IMAGE FOR EACH ROW EXECUTE PROCEDURE INCREMENT_ACL_VERSION();
+CREATE TRIGGER ARTIFACT_IMAGE_UPDATE_ACL_VERSION AFTER UPDATE ON REGISTRY_ARTIFACT_IMAGE FOR EACH ROW WHEN (NEW.VISIBILITY <> OLD.VISIBILITY) EXECUTE PROCEDURE INCREMENT_ACL_VERSION();
+CREATE TRIGGER ARTIFACT_IMAGE_DELETE_ACL_VERSION AFTER DELETE ON REGISTRY_ARTIFACT_IMAGE FOR EACH ROW EXECUTE PROCEDURE INCREMENT_ACL_VERSION();
+CREATE TRIGGER ROLE_PERMISSION_INSERT_ACL_VERSION AFTER INSERT ON REGISTRY_ROLE_PERMISSION FOR EACH ROW EXECUTE PROCEDURE INCREMENT_ACL_VERSION();
+CREATE TRIGGER ROLE_PERMISSION_UPDATE_ACL_VERSION AFTER UPDATE ON REGISTRY_ROLE_PERMISSION FOR EACH ROW EXECUTE PROCEDURE INCREMENT_ACL_VERSION();
+CREATE TRIGGER ROLE_PERMISSION_DELETE_ACL_VERSION AFTER DELETE ON REGISTRY_ROLE_PERMISSION FOR EACH ROW EXECUTE PROCEDURE INCREMENT_ACL_VERSION();
+CREATE TRIGGER TEAM_DELETE_ACL_VERSION AFTER DELETE ON REGISTRY_TEAM FOR EACH ROW EXECUTE PROCEDURE INCREMENT_ACL_VERSION();
+CREATE TRIGGER TEAM_USER_MAPPING_INSERT_ACL_VERSION AFTER INSERT ON REGISTRY_TEAM_USER_MAPPING FOR EACH ROW EXECUTE PROCEDURE INCREMENT_ACL_VERSION();
+CREATE TRIGGER TEAM_USER_MAPPING_UPDATE_ACL_VERSION AFTER UPDATE ON REGISTRY_TEAM_USER_MAPPING FOR EACH ROW EXECUTE PROCEDURE INCREMENT_ACL_VERSION();
+CREATE TRIGGER TEAM_USER_MAPPING_DELETE_ACL_VERSION AFTER DELETE ON REGISTRY_TEAM_USER_MAPPING FOR EACH ROW EXECUTE PROCEDURE INCREMENT_ACL_VERSION();
+CREATE TRIGGER TEAM_PERMISSION_INSERT_ACL_VERSION AFTER INSERT ON REGISTRY_TEAM_PERMISSION FOR EACH ROW EXECUTE PROCEDURE INCREMENT_ACL_VERSION();
+CREATE TRIGGER TEAM_PERMISSION_UPDATE_ACL_VERSION AFTER UPDATE ON REGISTRY_TEAM_PERMISSION FOR EACH ROW EXECUTE PROCEDURE INCREMENT_ACL_VERSION();
+CREATE TRIGGER TEAM_PERMISSION_DELETE_ACL_VERSION AFTER DELETE ON REGISTRY_TEAM_PERMISSION FOR EACH ROW EXECUTE PROCEDURE INCREMENT_ACL_VERSION();
+CREATE TRIGGER IMAGE_COLLABORATOR_INSERT_ACL_VERSION AFTER INSERT ON REGISTRY_IMAGE_COLLABORATOR FOR EACH ROW EXECUTE PROCEDURE INCREMENT_ACL_VERSION();
+CREATE TRIGGER IMAGE_COLLABORATOR_UPDATE_ACL_VERSION AFTER UPDATE ON REGISTRY_IMAGE_COLLABORATOR FOR EACH ROW EXECUTE PROCEDURE INCREMENT_ACL_VERSION();
+CREATE TRIGGER IMAGE_COLLABORATOR_DELETE_ACL_VERSION AFTER DELETE ON REGISTRY_IMAGE_COLLABORATOR FOR EACH ROW EXECUTE PROCEDURE INCREMENT_ACL_VERSION();
+
+-- Migration 8 : Create the organization quota table
+CREATE TABLE IF NOT EXISTS REGISTRY_ORG_QUOTA
+(
+    ORG_NAME     VARCHAR(255) NOT NULL,
+    MAX_IMAGES   INTEGER CHECK (MAX_IMAGES >= 0),
+    CREATED_DATE TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ORG_NAME),
+    FOREIGN KEY (ORG_NAME) REFERENCES REGISTRY_ORGANIZATION (ORG_NAME)
+        ON DELETE CASCADE
+);
+CREATE TRIGGER ORG_QUOTA_INSERT_ACL_VERSION AFTER INSERT ON REGISTRY_ORG_QUOTA FOR EACH ROW EXECUTE PROCEDURE INCREMENT_ACL_VERSION();
+CREATE TRIGGER ORG_QUOTA_UPDATE_ACL_VERSION AFTER UPDATE ON REGISTRY_ORG_QUOTA FOR EACH ROW EXECUTE PROCEDURE INCREMENT_ACL_VERSION();
+CREATE TRIGGER ORG_QUOTA_DELETE_ACL_VERSION AFTER DELETE ON REGISTRY_ORG_QUOTA FOR EACH ROW EXECUTE PROCEDURE INCREMENT_ACL_VERSION();
+
+-- The migrations applied by the script
+CREATE TABLE IF NOT EXISTS REGISTRY_SCHEMA_VERSION (VERSION INT NOT NULL, DESCRIPTION VARCHAR(255) NOT NULL, CHECKSUM CHAR(64) NOT NULL, APPLIED_DATE TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, PRIMARY KEY (VERSION));
+INSERT INTO REGISTRY_SCHEMA_VERSION (VERSION, DESCRIPTION, CHECKSUM) VALUES (1, 'Create the CELLERY_HUB tables', '92f77a65f3a57febd2d83293a422581b0b2cc2ee1b8771136f55411c223e07ca');
+INSERT INTO REGISTRY_SCHEMA_VERSION (VERSION, DESCRIPTION, CHECKSUM) VALUES (2, 'Create the robot account table', '4e965d0b796ee6ddb2cbd565704ee674e71af4a92d2ab1a6895c2cef36ae3a66');
+INSERT INTO REGISTRY_SCHEMA_VERSION (VERSION, DESCRIPTION, CHECKSUM) VALUES (3, 'Create the personal access token table', '0023eee413bb36002a4f05dfe4f904a820890373c25952c56853d04b3b5de494');
+INSERT INTO REGISTRY_SCHEMA_VERSION (VERSION, DESCRIPTION, CHECKSUM) VALUES (4, 'Create the role permission table with the default roles', '998432ff85e44978f598defc9a895e188ddc71da7ddb7013c0d5f2e2bb801315');
+INSERT INTO REGISTRY_SCHEMA_VERSION (VERSION, DESCRIPTION, CHECKSUM) VALUES (5, 'Create the team tables', 'c26931c47ea469a4c0d4644295000ba88f884c15750e163fb8167b4dcb31ae2c');
+INSERT INTO REGISTRY_SCHEMA_VERSION (VERSION, DESCRIPTION, CHECKSUM) VALUES (6, 'Create the image collaborator table', '1bfc5582b86c7eb2f73532ea1d22a3589fe40bc2c44c9673c936fbe4fe972bbd');
+INSERT INTO REGISTRY_SCHEMA_VERSION (VERSION, DESCRIPTION, CHECKSUM) VALUES (7, 'Create the ACL version table and the triggers which increment it', '5cd5c9fc50c3a9b1e2a0656eed4cfd7804ddfe681bbb736797c5924431b03406');
+INSERT INTO REGISTRY_SCHEMA_VERSION (VERSION, DESCRIPTION, CHECKSUM) VALUES (8, 'Create the organization quota table', '66bd4d493dbf292a0dc764c6ce0255cbe0146fe56e97699fa8578a95bf13e80e');
@@ -0,0 +1,253 @@
+-- ------------------------------------------------------------------------
+--
+-- Copyright 2019 WSO2, Inc. (http://wso2.com)
+--
+-- Licensed under the Apache License, Version 2.0 (the "License");
+-- you may not use this file except in compliance with the License.
+-- You may obtain a copy of the License at
+--
+-- http://www.apache.org/licenses/LICENSE-2.0
+--
+-- Unless required by applicable law or agreed to in writing, software
+-- distributed under the License is distributed on an "AS IS" BASIS,
+-- WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+-- See the License for the specific language governing permissions and
+-- limitations under the License
+--
+-- ------------------------------------------------------------------------
+--
+-- This script is generated from the migrations of the pkg/migrations package by running
+-- go test ./pkg/migrations -run TestScripts -update
+--
+
+-- Migration 1 : Create the CELLERY_HUB tables
+CREATE TABLE IF NOT EXISTS REGISTRY_ARTIFACT_LOCK
+(
+    ARTIFACT_NAME VARCHAR(255) NOT NULL,
+    LOCK_COUNT    INTEGER DEFAULT 0,
+    PRIMARY KEY (ARTIFACT_NAME)
+);
+CREATE TABLE IF NOT EXISTS REGISTRY_ORGANIZATION
+(
+    ORG_NAME                 VARCHAR(255) NOT NULL,
+    DESCRIPTION              BLOB,
+    SUMMARY                  VARCHAR(255)          DEFAULT '',
+    WEBSITE_URL              VARCHAR(255)          DEFAULT '',
+    DEFAULT_IMAGE_VISIBILITY VARCHAR(7)   NOT NULL DEFAULT 'PUBLIC'
+        CHECK (UPPER(DEFAULT_IMAGE_VISIBILITY) IN ('PUBLIC', 'PRIVATE')),
+    FIRST_AUTHOR             VARCHAR(255) NOT NULL,
+    CREATED_DATE             DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ORG_NAME)
+);
+CREATE TABLE IF NOT EXISTS REGISTRY_ORG_USER_MAPPING
+(
+    USER_UUID    VARCHAR(36)  NOT NULL,
+    ORG_NAME     VARCHAR(255) NOT NULL,
+    USER_ROLE    VARCHAR(255) NOT NULL,
+    CREATED_DATE DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (USER_UUID, ORG_NAME),
+    FOREIGN KEY (ORG_NAME) REFERENCES REGISTRY_ORGANIZATION (ORG_NAME)
+        ON DELETE CASCADE
+);
+CREATE TABLE IF NOT EXISTS REGISTRY_ARTIFACT_IMAGE
+(
+    ARTIFACT_IMAGE_ID VARCHAR(36)  NOT NULL,
+    ORG_NAME          VARCHAR(255) NOT NULL,
+    IMAGE_NAME        VARCHAR(255) NOT NULL,
+    SUMMARY           VARCHAR(255) DEFAULT '',
+    DESCRIPTION       BLOB,
+    FIRST_AUTHOR      VARCHAR(255) NOT NULL,
+    VISIBILITY        VARCHAR(7)   NOT NULL CHECK (UPPER(VISIBILITY) IN ('PUBLIC', 'PRIVATE')),
+    PRIMARY KEY (ARTIFACT_IMAGE_ID),
+    CONSTRAINT UC_ARTIFACT_IMG UNIQUE (ORG_NAME, IMAGE_NAME),
+    FOREIGN KEY (ORG_NAME) REFERENCES REGISTRY_ORGANIZATION (ORG_NAME)
+        ON DELETE CASCADE
+);
+CREATE TABLE IF NOT EXISTS IMAGE_KEYWORDS
+(
+    ARTIFACT_IMAGE_ID VARCHAR(36) NOT NULL,
+    KEYWORD           VARCHAR(36) NOT NULL,
+    PRIMARY KEY (ARTIFACT_IMAGE_ID, KEYWORD),
+    FOREIGN KEY (ARTIFACT_IMAGE_ID) REFERENCES REGISTRY_ARTIFACT_IMAGE (ARTIFACT_IMAGE_ID)
+        ON DELETE CASCADE
+);
+CREATE TABLE IF NOT EXISTS REGISTRY_ARTIFACT
+(
+    ARTIFACT_ID       VARCHAR(36)  NOT NULL,
+    ARTIFACT_IMAGE_ID VARCHAR(36)  NOT NULL,
+    VERSION           VARCHAR(50)  NOT NULL,
+    DESCRIPTION       BLOB,
+    PULL_COUNT        INTEGER               DEFAULT 0,
+    PUSH_COUNT        INTEGER               DEFAULT 0,
+    LAST_AUTHOR       VARCHAR(255) NOT NULL,
+    FIRST_AUTHOR      VARCHAR(255) NOT NULL,
+    METADATA          BLOB         NOT NULL,
+    VERIFIED          BOOLEAN               DEFAULT FALSE,
+    STATEFUL          BOOLEAN               DEFAULT FALSE,
+    CREATED_DATE      DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UPDATED_DATE      DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ARTIFACT_ID),
+    CONSTRAINT UC_ARTIFACT UNIQUE (ARTIFACT_IMAGE_ID, VERSION),
+    FOREIGN KEY (ARTIFACT_IMAGE_ID) REFERENCES REGISTRY_ARTIFACT_IMAGE (ARTIFACT_IMAGE_ID)
+        ON DELETE CASCADE
+);
+CREATE TABLE IF NOT EXISTS REGISTRY_ARTIFACT_INGRESS
+(
+    ARTIFACT_ID  VARCHAR(36) NOT NULL,
+    INGRESS_TYPE VARCHAR(36) NOT NULL,
+    PRIMARY KEY (ARTIFACT_ID, INGRESS_TYPE),
+    FOREIGN KEY (ARTIFACT_ID) REFERENCES REGISTRY_ARTIFACT (ARTIFACT_ID)
+        ON DELETE CASCADE
+);
+CREATE TABLE IF NOT EXISTS REGISTRY_ARTIFACT_LABEL
+(
+    ARTIFACT_ID VARCHAR(36) NOT NULL,
+    LABEL_KEY   VARCHAR(36) NOT NULL,
+    LABEL_VALUE VARCHAR(36) NOT NULL,
+    PRIMARY KEY (ARTIFACT_ID, LABEL_KEY),
+    FOREIGN KEY (ARTIFACT_ID) REFERENCES REGISTRY_ARTIFACT (ARTIFACT_ID)
+        ON DELETE CASCADE
+);
+
+-- Migration 2 : Create the robot account table
+CREATE TABLE IF NOT EXISTS REGISTRY_ROBOT_ACCOUNT
+(
+    ORG_NAME     VARCHAR(255) NOT NULL,
+    ROBOT_NAME   VARCHAR(255) NOT NULL,
+    SECRET_HASH  VARCHAR(255) NOT NULL,
+    ACTIONS      VARCHAR(255) NOT NULL,
+    EXPIRES_AT   DATETIME,
+    CREATED_DATE DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ORG_NAME, ROBOT_NAME),
+    FOREIGN KEY (ORG_NAME) REFERENCES REGISTRY_ORGANIZATION (ORG_NAME)
+        ON DELETE CASCADE
+);
+
+-- Migration 3 : Create the personal access token table
+CREATE TABLE IF NOT EXISTS REGISTRY_PERSONAL_ACCESS_TOKEN
+(
+    TOKEN_HASH   CHAR(64)     NOT NULL,
+    USER_UUID    VARCHAR(36)  NOT NULL,
+    TOKEN_NAME   VARCHAR(255) NOT NULL,
+    SCOPES       VARCHAR(255) NOT NULL,
+    ORG_NAME     VARCHAR(255),
+    EXPIRES_AT   DATETIME,
+    CREATED_DATE DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (TOKEN_HASH),
+    CONSTRAINT UC_PERSONAL_ACCESS_TOKEN UNIQUE (USER_UUID, TOKEN_NAME),
+    FOREIGN KEY (ORG_NAME) REFERENCES REGISTRY_ORGANIZATION (ORG_NAME)
+        ON DELETE CASCADE
+);
+
+-- Migration 4 : Create the role permission table with the default roles
+CREATE TABLE IF NOT EXISTS REGISTRY_ROLE_PERMISSION
+(
+    ROLE_NAME    VARCHAR(255) NOT NULL,
+    ACTION       VARCHAR(255) NOT NULL,
+    CREATED_DATE DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ROLE_NAME, ACTION)
+);
+INSERT OR IGNORE INTO REGISTRY_ROLE_PERMISSION (ROLE_NAME, ACTION) VALUES ('admin', 'pull'), ('admin', 'push'),
+    ('admin', 'delete'), ('push', 'pull'), ('push', 'push'), ('pull', 'pull');
+
+-- Migration 5 : Create the team tables
+CREATE TABLE IF NOT EXISTS REGISTRY_TEAM
+(
+    ORG_NAME     VARCHAR(255) NOT NULL,
+    TEAM_NAME    VARCHAR(255) NOT NULL,
+    DESCRIPTION  VARCHAR(255),
+    CREATED_DATE DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ORG_NAME, TEAM_NAME),
+    FOREIGN KEY (ORG_NAME) REFERENCES REGISTRY_ORGANIZATION (ORG_NAME)
+        ON DELETE CASCADE
+);
+CREATE TABLE IF NOT EXISTS REGISTRY_TEAM_USER_MAPPING
+(
+    ORG_NAME     VARCHAR(255) NOT NULL,
+    TEAM_NAME    VARCHAR(255) NOT NULL,
+    USER_UUID    VARCHAR(36)  NOT NULL,
+    CREATED_DATE DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ORG_NAME, TEAM_NAME, USER_UUID),
+    FOREIGN KEY (ORG_NAME, TEAM_NAME) REFERENCES REGISTRY_TEAM (ORG_NAME, TEAM_NAME)
+        ON DELETE CASCADE
+);
+CREATE TABLE IF NOT EXISTS REGISTRY_TEAM_PERMISSION
+(
+    ORG_NAME     VARCHAR(255) NOT NULL,
+    TEAM_NAME    VARCHAR(255) NOT NULL,
+    ROLE_NAME    VARCHAR(255) NOT NULL,
+    CREATED_DATE DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ORG_NAME, TEAM_NAME, ROLE_NAME),
+    FOREIGN KEY (ORG_NAME, TEAM_NAME) REFERENCES REGISTRY_TEAM (ORG_NAME, TEAM_NAME)
+        ON DELETE CASCADE
+);
+
+-- Migration 6 : Create the image collaborator table
+CREATE TABLE IF NOT EXISTS REGISTRY_IMAGE_COLLABORATOR
+(
+    ORG_NAME          VARCHAR(255) NOT NULL,
+    IMAGE_NAME        VARCHAR(255) NOT NULL,
+    COLLABORATOR_TYPE VARCHAR(4)   NOT NULL CHECK (COLLABORATOR_TYPE IN ('user', 'team')),
+    COLLABORATOR_NAME VARCHAR(255) NOT NULL,
+    ROLE_NAME         VARCHAR(255) NOT NULL,
+    CREATED_DATE      DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ORG_NAME, IMAGE_NAME, COLLABORATOR_TYPE, COLLABORATOR_NAME),
+    FOREIGN KEY (ORG_NAME) REFERENCES REGISTRY_ORGANIZATION (ORG_NAME)
+        ON DELETE CASCADE
+);
+
+-- Migration 7 : Create the ACL version table and the triggers which increment it
+CREATE TABLE IF NOT EXISTS REGISTRY_ACL_VERSION
+(
+    ID           INTEGER  NOT NULL,
+    VERSION      BIGINT   NOT NULL DEFAULT 0,
+    UPDATED_DATE DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ID)
+);
+INSERT OR IGNORE INTO REGISTRY_ACL_VERSION (ID, VERSION) VALUES (1, 0);
+CREATE TRIGGER ORGANIZATION_UPDATE_ACL_VERSION AFTER UPDATE ON REGISTRY_ORGANIZATION FOR EACH ROW WHEN NEW.DEFAULT_IMAGE_VISIBILITY <> OLD.DEFAULT_IMAGE_VISIBILITY BEGIN UPDATE REGISTRY_ACL_VERSION SET VERSION = VERSION + 1 WHERE ID = 1; END;
+CREATE TRIGGER ORGANIZATION_DELETE_ACL_VERSION AFTER DELETE ON REGISTRY_ORGANIZATION FOR EACH ROW BEGIN UPDATE REGISTRY_ACL_VERSION SET VERSION = VERSION + 1 WHERE ID = 1; END;
+CREATE TRIGGER ORG_USER_MAPPING_INSERT_ACL_VERSION AFTER INSERT ON REGISTRY_ORG_USER_MAPPING FOR EACH ROW BEGIN UPDATE REGISTRY_ACL_VERSION SET VERSION = VERSION + 1 WHERE ID = 1; END;
+CREATE TRIGGER ORG_USER_MAPPING_UPDATE_ACL_VERSION AFTER UPDATE ON REGISTRY_ORG_USER_MAPPING FOR EACH ROW BEGIN UPDATE REGISTRY_ACL_VERSION SET VERSION = VERSION + 1 WHERE ID = 1; END;
+CREATE TRIGGER ORG_USER_MAPPING_DELETE_ACL_VERSION AFTER DELETE ON REGISTRY_ORG_USER_MAPPING FOR EACH ROW BEGIN UPDATE REGISTRY_ACL_VERSION SET VERSION = VERSION + 1 WHERE ID = 1; END;
+CREATE TRIGGER ARTIFACT_IMAGE_INSERT_ACL_VERSION AFTER INSERT ON REGISTRY_ARTIFACT_IMAGE FOR EACH ROW BEGIN UPDATE REGISTRY_ACL_VERSION SET VERSION = VERSION + 1 WHERE ID = 1; END;
+CREATE TRIGGER ARTIFACT_IMAGE_UPDATE_ACL_VERSION AFTER UPDATE ON REGISTRY_ARTIFACT_IMAGE FOR EACH ROW WHEN NEW.VISIBILITY <> OLD.VISIBILITY BEGIN UPDATE REGISTRY_ACL_VERSION SET VERSION = VERSION + 1 WHERE ID = 1; END;
+CREATE TRIGGER ARTIFACT_IMAGE_DELETE_ACL_VERSION AFTER DELETE ON REGISTRY_ARTIFACT_IMAGE FOR EACH ROW BEGIN UPDATE REGISTRY_ACL_VERSION SET VERSION = VERSION + 1 WHERE ID = 1; END;
+CREATE TRIGGER ROLE_PERMISSION_INSERT_ACL_VERSION AFTER INSERT ON REGISTRY_ROLE_PERMISSION FOR EACH ROW BEGIN UPDATE REGISTRY_ACL_VERSION SET VERSION = VERSION + 1 WHERE ID = 1; END;
+CREATE TRIGGER ROLE_PERMISSION_UPDATE_ACL_VERSION AFTER UPDATE ON REGISTRY_ROLE_PERMISSION FOR EACH ROW BEGIN UPDATE REGISTRY_ACL_VERSION SET VERSION = VERSION + 1 WHERE ID = 1; END;
+CREATE TRIGGER ROLE_PERMISSION_DELETE_ACL_VERSION AFTER DELETE ON REGISTRY_ROLE_PERMISSION FOR EACH ROW BEGIN UPDATE REGISTRY_ACL_VERSION SET VERSION = VERSION + 1 WHERE ID = 1; END;
+CREATE TRIGGER TEAM_DELETE_ACL_VERSION AFTER DELETE ON REGISTRY_TEAM FOR EACH ROW BEGIN UPDATE REGISTRY_ACL_VERSION SET VERSION = VERSION + 1 WHERE ID = 1; END;
+CREATE TRIGGER TEAM_USER_MAPPING_INSERT_ACL_VERSION AFTER INSERT ON REGISTRY_TEAM_USER_MAPPING FOR EACH ROW BEGIN UPDATE REGISTRY_ACL_VERSION SET VERSION = VERSION + 1 WHERE ID = 1; END;
+CREATE TRIGGER TEAM_USER_MAPPING_UPDATE_ACL_VERSION AFTER UPDATE ON REGISTRY_TEAM_USER_MAPPING FOR EACH ROW BEGIN UPDATE REGISTRY_ACL_VERSION SET VERSION = VERSION + 1 WHERE ID = 1; END;
+CREATE TRIGGER TEAM_USER_MAPPING_DELETE_ACL_VERSION AFTER DELETE ON REGISTRY_TEAM_USER_MAPPING FOR EACH ROW BEGIN UPDATE REGISTRY_ACL_VERSION SET VERSION = VERSION + 1 WHERE ID = 1; END;
+CREATE TRIGGER TEAM_PERMISSION_INSERT_ACL_VERSION AFTER INSERT ON REGISTRY_TEAM_PERMISSION FOR EACH ROW BEGIN UPDATE REGISTRY_ACL_VERSION SET VERSION = VERSION + 1 WHERE ID = 1; END;
+CREATE TRIGGER TEAM_PERMISSION_UPDATE_ACL_VERSION AFTER UPDATE ON REGISTRY_TEAM_PERMISSION FOR EACH ROW BEGIN UPDATE REGISTRY_ACL_VERSION SET VERSION = VERSION + 1 WHERE ID = 1; END;
+CREATE TRIGGER TEAM_PERMISSION_DELETE_ACL_VERSION AFTER DELETE ON REGISTRY_TEAM_PERMISSION FOR EACH ROW BEGIN UPDATE REGISTRY_ACL_VERSION SET VERSION = VERSION + 1 WHERE ID = 1; END;
+CREATE TRIGGER IMAGE_COLLABORATOR_INSERT_ACL_VERSION AFTER INSERT ON REGISTRY_IMAGE_COLLABORATOR FOR EACH ROW BEGIN UPDATE REGISTRY_ACL_VERSION SET VERSION = VERSION + 1 WHERE ID = 1; END;
+CREATE TRIGGER IMAGE_COLLABORATOR_UPDATE_ACL_VERSION AFTER UPDATE ON REGISTRY_IMAGE_COLLABORATOR FOR EACH ROW BEGIN UPDATE REGISTRY_ACL_VERSION SET VERSION = VERSION + 1 WHERE ID = 1; END;
+CREATE TRIGGER IMAGE_COLLABORATOR_DELETE_ACL_VERSION AFTER DELETE ON REGISTRY_IMAGE_COLLABORATOR FOR EACH ROW BEGIN UPDATE REGISTRY_ACL_VERSION SET VERSION = VERSION + 1 WHERE ID = 1; END;
+
+-- Migration 8 : Create the organization quota table
+CREATE TABLE IF NOT EXISTS REGISTRY_ORG_QUOTA
+(
+    ORG_NAME     VARCHAR(255) NOT NULL,
+    MAX_IMAGES   INTEGER CHECK (MAX_IMAGES >= 0),
+    CREATED_DATE DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ORG_NAME),
+    FOREIGN KEY (ORG_NAME) REFERENCES REGISTRY_ORGANIZATION (ORG_NAME)
+        ON DELETE CASCADE
+);
+CREATE TRIGGER ORG_QUOTA_INSERT_ACL_VERSION AFTER INSERT ON REGISTRY_ORG_QUOTA FOR EACH ROW BEGIN UPDATE REGISTRY_ACL_VERSION SET VERSION = VERSION + 1 WHERE ID = 1; END;
+CREATE TRIGGER ORG_QUOTA_UPDATE_ACL_VERSION AFTER UPDATE ON REGISTRY_ORG_QUOTA FOR EACH ROW BEGIN UPDATE REGISTRY_ACL_VERSION SET VERSION = VERSION + 1 WHERE ID = 1; END;
+CREATE TRIGGER ORG_QUOTA_DELETE_ACL_VERSION AFTER DELETE ON REGISTRY_ORG_QUOTA FOR EACH ROW BEGIN UPDATE REGISTRY_ACL_VERSION SET VERSION = VERSION + 1 WHERE ID = 1; END;
+
+-- The migrations applied by the script
+CREATE TABLE IF NOT EXISTS REGISTRY_SCHEMA_VERSION (VERSION INT NOT NULL, DESCRIPTION VARCHAR(255) NOT NULL, CHECKSUM CHAR(64) NOT NULL, APPLIED_DATE TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, PRIMARY KEY (VERSION));
+INSERT INTO REGISTRY_SCHEMA_VERSION (VERSION, DESCRIPTION, CHECKSUM) VALUES (1, 'Create the CELLERY_HUB tables', '75c62d8441c38cf213bd280f27f4e9dcb012b70363040046dde57627a02aac70');
+INSERT INTO REGISTRY_SCHEMA_VERSION (VERSION, DESCRIPTION, CHECKSUM) VALUES (2, 'Create the robot account table', '4cf33f0f3630106c007d2b7f7ece60d5c29c60c5703960fbe2868a3886c9935b');
+INSERT INTO REGISTRY_SCHEMA_VERSION (VERSION, DESCRIPTION, CHECKSUM) VALUES (3, 'Create the personal access token table', 'ed4fe713f62f5633f5d83b72c2f9fdd05590944b9c9fd26000d1f159fc79b026');
+INSERT INTO REGISTRY_SCHEMA_VERSION (VERSION, DESCRIPTION, CHECKSUM) VALUES (4, 'Create the role permission table with the default roles', 'ed695d32a675d76133d6538cad975d978e47a2da53422b75969ec8a2cd4f5b8f');
+INSERT INTO REGISTRY_SCHEMA_VERSION (VERSION, DESCRIPTION, CHECKSUM) VALUES (5, 'Create the team tables', '809552db11cf85b3c6be38b82de063ded6fdb7efb968304734161264e01f00bc');
+INSERT INTO REGISTRY_SCHEMA_VERSION (VERSION, DESCRIPTION, CHECKSUM) VALUES (6, 'Create the image collaborator table', '6d95ee4a7e8d23035bf86b73eb800aa711a4513ec9c4f0f3de3827d0e282b4f4');
+INSERT INTO REGISTRY_SCHEMA_VERSION (VERSION, DESCRIPTION, CHECKSUM) VALUES (7, 'Create the ACL version table and the triggers which increment it', '40e41f2afc47fbdce4d44e010cf22f86ec52a8b6bd69f548a623979118c73e10');
+INSERT INTO REGISTRY_SCHEMA_VERSION (VERSION, DESCRIPTION, CHECKSUM) VALUES (8, 'Create the organization quota table', '2d3014f9a92c798b3ed7a909e07071c11e50c54c10e9f320a682860fd2bfa270');